@@ -10,6 +10,7 @@ import (
 
 	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
 	v1 "github.com/connerdouglass/livestream-api/v1"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -49,20 +50,89 @@ func main() {
 	// Migrate the schema
 	db.AutoMigrate(
 		&models.Account{},
+		&models.AccountIdentity{},
+		&models.ApiKey{},
+		&models.AuditEvent{},
 		&models.BrowserNotifySub{},
 		&models.BrowserNotifyTarget{},
+		&models.CreatorProfileInvite{},
+		&models.CreatorProfileLink{},
 		&models.CreatorProfileMember{},
 		&models.CreatorProfile{},
+		&models.CreatorUsernameRedirect{},
+		&models.EmailVerificationToken{},
+		&models.Image{},
+		&models.ImageVariant{},
+		&models.IngestSettings{},
+		&models.IngestNode{},
+		&models.LoginAttempt{},
+		&models.OidcLoginState{},
+		&models.PasswordResetToken{},
+		&models.Session{},
 		&models.SiteConfig{},
 		&models.Stream{},
+		&models.StreamAnalytics{},
+		&models.StreamStatusChange{},
+		&models.StreamViewerReport{},
+		&models.StreamViewerSample{},
 		&models.TelegramNotifySub{},
 		&models.TelegramNotifyTarget{},
+		&models.TotpRecoveryCode{},
+		&models.ViewerSession{},
 	)
 
 	//================================================================================
 	// Create all the service instances
 	//================================================================================
 
+	// Create the mailer. Emails are delivered over SMTP when a host is configured, and are
+	// otherwise just logged (and optionally written to MAIL_LOG_DIR) for local development
+	var mailer services.Mailer = &services.LogMailer{
+		Dir: os.Getenv("MAIL_LOG_DIR"),
+	}
+	if smtpHost := os.Getenv("SMTP_HOST"); len(smtpHost) > 0 {
+		smtpPort := os.Getenv("SMTP_PORT")
+		if len(smtpPort) == 0 {
+			smtpPort = "587"
+		}
+		mailer = &services.SmtpMailer{
+			Host:     smtpHost,
+			Port:     smtpPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+	}
+
+	// Create the blob storage for uploaded images. Files go to an S3-compatible bucket when one is
+	// configured, and are otherwise kept in a local directory and served by this server
+	var blobStorage services.BlobStorage
+	localStorageDir := os.Getenv("STORAGE_DIR")
+	if len(localStorageDir) == 0 {
+		localStorageDir = "uploads"
+	}
+	if bucket := os.Getenv("S3_BUCKET"); len(bucket) > 0 {
+		blobStorage = &services.S3BlobStorage{
+			Endpoint: os.Getenv("S3_ENDPOINT"),
+			Region:   os.Getenv("S3_REGION"),
+			Bucket:   bucket,
+			Credentials: utils.AwsCredentials{
+				AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			},
+			PublicUrl: os.Getenv("STORAGE_PUBLIC_URL"),
+		}
+	} else {
+		baseUrl := os.Getenv("STORAGE_PUBLIC_URL")
+		if len(baseUrl) == 0 {
+			baseUrl = "/uploads"
+		}
+		blobStorage = &services.LocalBlobStorage{
+			Dir:     localStorageDir,
+			BaseUrl: baseUrl,
+		}
+	}
+
 	// Create the rest of the services
 	siteConfigService := &services.SiteConfigService{DB: db}
 	telegramService := &services.TelegramService{
@@ -71,16 +141,78 @@ func main() {
 		BotUsername: os.Getenv("TELEGRAM_BOT_USERNAME"),
 	}
 	accountsService := &services.AccountsService{DB: db}
+	loginThrottleService := &services.LoginThrottleService{DB: db}
+	emailVerificationService := &services.EmailVerificationService{
+		DB:              db,
+		Mailer:          mailer,
+		SiteUrl:         os.Getenv("SITE_URL"),
+		AccountsService: accountsService,
+	}
 	authTokensService := &services.AuthTokensService{
 		DB:            db,
 		SigningPepper: os.Getenv("AUTH_TOKEN_SIGNING_PEPPER"),
 	}
+	sessionsService := &services.SessionsService{
+		DB:                db,
+		AuthTokensService: authTokensService,
+	}
+	totpService := &services.TotpService{
+		DB:     db,
+		Issuer: os.Getenv("PLATFORM_TITLE"),
+	}
+	passwordResetService := &services.PasswordResetService{
+		DB:              db,
+		Mailer:          mailer,
+		SiteUrl:         os.Getenv("SITE_URL"),
+		AccountsService: accountsService,
+		SessionsService: sessionsService,
+	}
+	oidcService := &services.OidcService{
+		DB:              db,
+		Issuer:          os.Getenv("OIDC_ISSUER"),
+		ClientID:        os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:    os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectUrl:     os.Getenv("OIDC_REDIRECT_URL"),
+		AccountsService: accountsService,
+	}
+	auditService := &services.AuditService{DB: db}
 	creatorsService := &services.CreatorsService{DB: db}
+	imagesService := &services.ImagesService{
+		DB:      db,
+		Storage: blobStorage,
+	}
+	apiKeysService := &services.ApiKeysService{DB: db}
 	rtmpAuthService := &services.RtmpAuthService{
+		DB:                 db,
 		RtmpServerPasscode: os.Getenv("RTMP_SERVER_PASSCODE"),
 	}
-	streamsService := &services.StreamsService{DB: db}
+	playbackService := &services.PlaybackService{
+		DB:             db,
+		HlsUrlTemplate: os.Getenv("PLAYBACK_HLS_URL_TEMPLATE"),
+		TokenSecret:    os.Getenv("PLAYBACK_TOKEN_SECRET"),
+	}
+	streamsService := &services.StreamsService{
+		DB:              db,
+		PlaybackService: playbackService,
+	}
+	ingestSettingsService := &services.IngestSettingsService{DB: db}
+	viewerAnalyticsService := &services.ViewerAnalyticsService{DB: db}
+	viewerCountsService := &services.ViewerCountsService{
+		DB:             db,
+		StreamsService: streamsService,
+	}
+	ingestNodesService := &services.IngestNodesService{
+		DB:             db,
+		StreamsService: streamsService,
+	}
 	membershipService := &services.MembershipService{DB: db}
+	invitesService := &services.InvitesService{
+		DB:                db,
+		Mailer:            mailer,
+		SiteUrl:           os.Getenv("SITE_URL"),
+		AccountsService:   accountsService,
+		MembershipService: membershipService,
+	}
 
 	// Create the notifiers
 	browserNotifier := &services.BrowserNotifier{
@@ -96,6 +228,27 @@ func main() {
 		telegramNotifier,
 	)
 
+	// Accounts that predate email verification are considered verified
+	siteConfig, err := siteConfigService.GetSiteConfig()
+	if err != nil {
+		log.Fatalln("Failed to load site config: ", err)
+	}
+	if err := accountsService.MarkLegacyAccountsVerified(siteConfig); err != nil {
+		log.Fatalln("Failed to migrate legacy accounts: ", err)
+	}
+
+	// Memberships that predate roles are given one
+	if err := membershipService.MigrateRoles(); err != nil {
+		log.Fatalln("Failed to migrate member roles: ", err)
+	}
+
+	// Promote the accounts listed in ADMIN_EMAILS to platform admins
+	if adminEmails := os.Getenv("ADMIN_EMAILS"); len(adminEmails) > 0 {
+		if err := accountsService.PromoteAdmins(strings.Split(adminEmails, ",")); err != nil {
+			log.Fatalln("Failed to promote admin accounts: ", err)
+		}
+	}
+
 	//================================================================================
 	// Listen on the Telegram bot channel
 	//================================================================================
@@ -106,6 +259,48 @@ func main() {
 		}
 	}()
 
+	//================================================================================
+	// Disconnect streams on ingest nodes that went offline, and end streams whose
+	// encoder didn't reconnect in time
+	//================================================================================
+
+	go func() {
+		for range time.Tick(time.Second * 10) {
+			if _, err := ingestNodesService.DisconnectStaleNodes(); err != nil {
+				fmt.Println("Error disconnecting streams on stale ingest nodes: ", err.Error())
+			}
+			if _, err := streamsService.EndDisconnectedStreams(); err != nil {
+				fmt.Println("Error ending disconnected streams: ", err.Error())
+			}
+		}
+	}()
+
+	//================================================================================
+	// Expire stale viewer counts and sessions, sample the viewer counts of live
+	// streams, and compute the analytics of ended streams
+	//================================================================================
+
+	go func() {
+		for range time.Tick(time.Second * 10) {
+			if err := viewerCountsService.ExpireViewerReports(); err != nil {
+				fmt.Println("Error expiring viewer reports: ", err.Error())
+			}
+			if err := viewerAnalyticsService.CloseStaleSessions(); err != nil {
+				fmt.Println("Error closing stale viewer sessions: ", err.Error())
+			}
+		}
+	}()
+	go func() {
+		for range time.Tick(time.Minute) {
+			if err := viewerCountsService.RecordViewerSamples(); err != nil {
+				fmt.Println("Error recording viewer samples: ", err.Error())
+			}
+			if _, err := viewerAnalyticsService.ComputeEndedStreams(); err != nil {
+				fmt.Println("Error computing stream analytics: ", err.Error())
+			}
+		}
+	}()
+
 	//================================================================================
 	// Setup the Gin HTTP router
 	//================================================================================
@@ -122,24 +317,45 @@ func main() {
 
 	// Create the API instance
 	api := &v1.Server{
-		PlatformTitle:       os.Getenv("PLATFORM_TITLE"),
-		MainCreatorUsername: os.Getenv("MAIN_CREATOR_USERNAME"),
-		SiteConfigService:   siteConfigService,
-		AccountsService:     accountsService,
-		AuthTokensService:   authTokensService,
-		CreatorsService:     creatorsService,
-		MembershipService:   membershipService,
-		RtmpAuthService:     rtmpAuthService,
-		StreamsService:      streamsService,
-		TelegramService:     telegramService,
-		Notifier:            notifiers,
-		BrowserNotifier:     browserNotifier,
-		TelegramNotifier:    telegramNotifier,
+		PlatformTitle:            os.Getenv("PLATFORM_TITLE"),
+		MainCreatorUsername:      os.Getenv("MAIN_CREATOR_USERNAME"),
+		PublicUrl:                os.Getenv("PUBLIC_URL"),
+		SiteConfigService:        siteConfigService,
+		AccountsService:          accountsService,
+		EmailVerificationService: emailVerificationService,
+		PasswordResetService:     passwordResetService,
+		AuthTokensService:        authTokensService,
+		SessionsService:          sessionsService,
+		LoginThrottleService:     loginThrottleService,
+		OidcService:              oidcService,
+		TotpService:              totpService,
+		CreatorsService:          creatorsService,
+		ApiKeysService:           apiKeysService,
+		MembershipService:        membershipService,
+		InvitesService:           invitesService,
+		ImagesService:            imagesService,
+		RtmpAuthService:          rtmpAuthService,
+		IngestNodesService:       ingestNodesService,
+		IngestSettingsService:    ingestSettingsService,
+		ViewerCountsService:      viewerCountsService,
+		ViewerAnalyticsService:   viewerAnalyticsService,
+		PlaybackService:          playbackService,
+		StreamsService:           streamsService,
+		TelegramService:          telegramService,
+		Notifier:                 notifiers,
+		BrowserNotifier:          browserNotifier,
+		TelegramNotifier:         telegramNotifier,
+		AuditService:             auditService,
 	}
 
 	// Mount the API routes
 	api.Setup(r.Group("v1"))
 
+	// Serve uploaded files, if they're kept locally
+	if _, ok := blobStorage.(*services.LocalBlobStorage); ok {
+		r.Static("/uploads", localStorageDir)
+	}
+
 	// Create a mux to serve both the HTTP and Socket.IO servers
 	mux := http.NewServeMux()
 	mux.Handle("/", r)
@@ -9,22 +9,78 @@ import (
 
 // Account is a creator account that has a profile on the platform
 type Account struct {
-	ID           uint64 `gorm:"primaryKey"`
-	Email        string
-	PasswordSalt string
-	PasswordHash string
-	CreatedDate  time.Time
-	DeletedDate  sql.NullTime
+	ID                uint64 `gorm:"primaryKey"`
+	Email             string
+	PasswordSalt      string
+	PasswordHash      string
+	EmailVerifiedDate sql.NullTime
+	TotpSecret        sql.NullString
+	TotpEnabledDate   sql.NullTime
+	TotpLastStep      sql.NullInt64
+	IsAdmin           bool `gorm:"not null;default:false"`
+	DisabledDate      sql.NullTime
+	CreatedDate       time.Time
+	DeletedDate       sql.NullTime
 }
 
-// VerifyPassword verifies a password on the account
+// VerifyPassword verifies a password on the account. Legacy salted SHA-256 hashes are still accepted
+// until they are upgraded with RehashPassword
 func (a *Account) VerifyPassword(password string) bool {
-	passwordHash := utils.Sha256Hex(a.PasswordSalt + password)
-	return passwordHash == a.PasswordHash
+	if utils.IsLegacyPasswordHash(a.PasswordHash) {
+		return utils.VerifyLegacyPasswordHash(password, a.PasswordSalt, a.PasswordHash)
+	}
+	ok, err := utils.VerifyPasswordHash(password, a.PasswordHash)
+	return err == nil && ok
 }
 
-// SetPassword sets a new password for the account
-func (a *Account) SetPassword(password string) {
-	a.PasswordSalt = utils.RandHexStrInt64()
-	a.PasswordHash = utils.Sha256Hex(a.PasswordSalt + password)
+// SetPassword sets a new password for the account. This also rotates the account's salt, which is
+// part of the key used to sign the account's auth tokens, so existing tokens become invalid
+func (a *Account) SetPassword(password string) error {
+	salt, err := utils.SecureRandHexStr(32)
+	if err != nil {
+		return err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	a.PasswordSalt = salt
+	a.PasswordHash = hash
+	return nil
+}
+
+// PasswordNeedsRehash checks if the account's password hash uses an outdated algorithm or parameters
+func (a *Account) PasswordNeedsRehash() bool {
+	return utils.PasswordHashNeedsUpgrade(a.PasswordHash)
+}
+
+// RehashPassword replaces the account's password hash with a fresh one for the same password. Unlike
+// SetPassword, the salt is left alone so that existing auth tokens remain valid
+func (a *Account) RehashPassword(password string) error {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	a.PasswordHash = hash
+	return nil
+}
+
+// HasPassword checks if the account has a password. Accounts created through external login don't
+func (a *Account) HasPassword() bool {
+	return len(a.PasswordHash) > 0
+}
+
+// IsEmailVerified checks if the account's email address has been verified
+func (a *Account) IsEmailVerified() bool {
+	return a.EmailVerifiedDate.Valid
+}
+
+// IsDisabled checks if the account was disabled by a platform admin, which blocks it from logging in
+func (a *Account) IsDisabled() bool {
+	return a.DisabledDate.Valid
+}
+
+// IsTotpEnabled checks if the account requires a TOTP code to log in
+func (a *Account) IsTotpEnabled() bool {
+	return a.TotpEnabledDate.Valid && a.TotpSecret.Valid
 }
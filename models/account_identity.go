@@ -0,0 +1,19 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// AccountIdentity links an account to a user at an external identity provider, so the account can
+// be logged in to through that provider
+type AccountIdentity struct {
+	ID          uint64 `gorm:"primaryKey"`
+	AccountID   uint64
+	Account     *Account
+	Issuer      string
+	Subject     string
+	Email       string
+	CreatedDate time.Time
+	DeletedDate sql.NullTime
+}
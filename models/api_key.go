@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// ApiKeyScopes is the list of all scopes that can be granted to an API key. Scopes are the member
+// permissions that are safe to automate
+var ApiKeyScopes = []string{
+	Permission_StreamsRead,
+	Permission_StreamsWrite,
+	Permission_StreamsKeys,
+	Permission_MembersManage,
+	Permission_NotifySend,
+	Permission_AnalyticsRead,
+}
+
+// ApiKey is a key used by scripts to automate a creator profile, in place of logging in
+type ApiKey struct {
+	ID                 uint64 `gorm:"primaryKey"`
+	CreatorProfileID   uint64
+	CreatorProfile     *CreatorProfile
+	CreatedByAccountID uint64
+	CreatedByAccount   *Account
+	Name               string
+	Prefix             string `gorm:"index"`
+	KeyHash            string
+	Scopes             string
+	LastUsedDate       sql.NullTime
+	ExpiresDate        sql.NullTime
+	RevokedDate        sql.NullTime
+	CreatedDate        time.Time
+}
+
+// GetScopes gets the scopes granted to the key
+func (k *ApiKey) GetScopes() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope checks if the key was granted the scope
+func (k *ApiKey) HasScope(scope string) bool {
+	for _, s := range k.GetScopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
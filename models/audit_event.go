@@ -0,0 +1,74 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	AuditAction_AdminAccountDisable           = "admin.account.disable"
+	AuditAction_AdminAccountEnable            = "admin.account.enable"
+	AuditAction_AdminImpersonate              = "admin.account.impersonate"
+	AuditAction_AdminCreatorCreate            = "admin.creator.create"
+	AuditAction_AdminStreamForceEnd           = "admin.stream.force_end"
+	AuditAction_AdminVapidKeysRotate          = "admin.vapid_keys.rotate"
+	AuditAction_AdminIngestNodeCreate         = "admin.ingest_node.create"
+	AuditAction_AdminIngestNodeRevoke         = "admin.ingest_node.revoke"
+	AuditAction_AdminIngestNodeSetPlaybackUrl = "admin.ingest_node.set_playback_url"
+
+	AuditAction_AccountSessionRevoke           = "account.session.revoke"
+	AuditAction_AccountTotpEnable              = "account.totp.enable"
+	AuditAction_AccountTotpDisable             = "account.totp.disable"
+	AuditAction_AccountRecoveryCodesRegenerate = "account.recovery_codes.regenerate"
+
+	AuditAction_ApiKeyCreate = "api_key.create"
+	AuditAction_ApiKeyRevoke = "api_key.revoke"
+
+	AuditAction_CreatorCreate      = "creator.create"
+	AuditAction_CreatorUpdate      = "creator.update"
+	AuditAction_CreatorUploadImage = "creator.upload_image"
+	AuditAction_CreatorDelete      = "creator.delete"
+
+	AuditAction_CreatorIngestSettingsSet = "creator.ingest_settings.set"
+
+	AuditAction_CreatorStreamKeyReveal = "creator.stream_key.reveal"
+	AuditAction_CreatorStreamKeyRotate = "creator.stream_key.rotate"
+	AuditAction_CreatorStreamKeyRevoke = "creator.stream_key.revoke"
+
+	AuditAction_MemberInvite            = "member.invite"
+	AuditAction_MemberInviteRevoke      = "member.invite.revoke"
+	AuditAction_MemberInviteAccept      = "member.invite.accept"
+	AuditAction_MemberRemove            = "member.remove"
+	AuditAction_MemberSetRole           = "member.set_role"
+	AuditAction_MemberTransferOwnership = "member.transfer_ownership"
+
+	AuditAction_NotificationSend = "notification.send"
+
+	AuditAction_StreamCreate          = "stream.create"
+	AuditAction_StreamUpdate          = "stream.update"
+	AuditAction_StreamSetStatus       = "stream.set_status"
+	AuditAction_StreamSetStreaming    = "stream.set_streaming"
+	AuditAction_StreamUploadThumbnail = "stream.upload_thumbnail"
+	AuditAction_StreamKeyReveal       = "stream.key.reveal"
+	AuditAction_StreamKeyRotate       = "stream.key.rotate"
+
+	AuditAction_StreamIngestSettingsSet = "stream.ingest_settings.set"
+)
+
+// AuditEvent records an action taken on the platform, and who took it. Events are append-only, and are
+// never updated or deleted once recorded
+type AuditEvent struct {
+	ID                    uint64 `gorm:"primaryKey"`
+	ActorAccountID        sql.NullInt64
+	ImpersonatorAccountID sql.NullInt64
+	ApiKeyID              sql.NullInt64
+	IngestNodeID          sql.NullInt64
+	CreatorProfileID      sql.NullInt64 `gorm:"index"`
+	Action                string        `gorm:"index"`
+	TargetType            string
+	TargetID              string
+	Changes               string
+	Details               string
+	IPAddress             string
+	CreatedDate           time.Time
+}
@@ -10,7 +10,10 @@ type CreatorProfile struct {
 	ID          uint64 `gorm:"primaryKey"`
 	Username    string
 	Name        string
+	Bio         string `gorm:"not null;default:''"`
 	Image       string
+	Links       []*CreatorProfileLink
+	StreamKey   sql.NullString `gorm:"index"`
 	CreatedDate time.Time
 	DeletedDate sql.NullTime
 }
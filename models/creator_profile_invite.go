@@ -0,0 +1,27 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CreatorProfileInvite is an invitation sent to an email address to join a creator profile as a member
+type CreatorProfileInvite struct {
+	ID                 uint64 `gorm:"primaryKey"`
+	CreatorProfileID   uint64
+	CreatorProfile     *CreatorProfile
+	InvitedByAccountID uint64
+	InvitedByAccount   *Account
+	Email              string
+	Role               string
+	TokenHash          string `gorm:"index"`
+	ExpiresDate        time.Time
+	AcceptedDate       sql.NullTime
+	RevokedDate        sql.NullTime
+	CreatedDate        time.Time
+}
+
+// IsPending checks if the invite can still be accepted
+func (i *CreatorProfileInvite) IsPending(now time.Time) bool {
+	return !i.AcceptedDate.Valid && !i.RevokedDate.Valid && now.Before(i.ExpiresDate)
+}
@@ -0,0 +1,10 @@
+package models
+
+// CreatorProfileLink is a link to somewhere else on the web, shown on a creator profile
+type CreatorProfileLink struct {
+	ID               uint64 `gorm:"primaryKey"`
+	CreatorProfileID uint64
+	Label            string
+	Url              string
+	SortOrder        int
+}
@@ -5,6 +5,44 @@ import (
 	"time"
 )
 
+const (
+	MemberRole_Owner           = "owner"
+	MemberRole_Admin           = "admin"
+	MemberRole_Producer        = "producer"
+	MemberRole_Moderator       = "moderator"
+	MemberRole_ViewerAnalytics = "viewer_analytics"
+)
+
+// MemberRoles is the list of all member roles, from the most to the least privileged
+var MemberRoles = []string{
+	MemberRole_Owner,
+	MemberRole_Admin,
+	MemberRole_Producer,
+	MemberRole_Moderator,
+	MemberRole_ViewerAnalytics,
+}
+
+// IsMemberRole checks if the string is a valid member role
+func IsMemberRole(role string) bool {
+	return memberRoleRank(role) >= 0
+}
+
+// MemberRoleOutranks checks if the first role is strictly more privileged than the second
+func MemberRoleOutranks(role, other string) bool {
+	rank := memberRoleRank(role)
+	return rank >= 0 && rank < memberRoleRank(other)
+}
+
+// memberRoleRank gets the position of the role in MemberRoles, or -1 if it isn't a role
+func memberRoleRank(role string) int {
+	for i, r := range MemberRoles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
 // CreatorProfileMember is a member with access to manage a profile
 type CreatorProfileMember struct {
 	ID               uint64 `gorm:"primaryKey"`
@@ -12,6 +50,12 @@ type CreatorProfileMember struct {
 	Account          *Account
 	CreatorProfileID uint64
 	CreatorProfile   *CreatorProfile
+	Role             string `gorm:"not null;default:''"`
 	CreatedDate      time.Time
 	DeletedDate      sql.NullTime
 }
+
+// HasPermission checks if the member's role grants the permission
+func (m *CreatorProfileMember) HasPermission(permission string) bool {
+	return RoleHasPermission(m.Role, permission)
+}
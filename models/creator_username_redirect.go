@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// CreatorUsernameRedirect is a username previously used by a creator profile, so that links using the
+// old username still lead to the profile
+type CreatorUsernameRedirect struct {
+	ID               uint64 `gorm:"primaryKey"`
+	CreatorProfileID uint64
+	CreatorProfile   *CreatorProfile
+	Username         string `gorm:"index"`
+	CreatedDate      time.Time
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	ImagePurpose_CreatorImage    = "creator_image"
+	ImagePurpose_StreamThumbnail = "stream_thumbnail"
+)
+
+// Image is an uploaded image, stored as one or more resized variants
+type Image struct {
+	ID                  uint64 `gorm:"primaryKey"`
+	CreatorProfileID    uint64
+	StreamID            sql.NullInt64
+	UploadedByAccountID uint64
+	Purpose             string
+	Variants            []*ImageVariant
+	CreatedDate         time.Time
+	DeletedDate         sql.NullTime
+}
+
+// ImageVariant is a single stored size of an uploaded image
+type ImageVariant struct {
+	ID          uint64 `gorm:"primaryKey"`
+	ImageID     uint64
+	Name        string
+	StorageKey  string
+	Url         string
+	ContentType string
+	Width       int
+	Height      int
+	Size        int
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// IngestNodeHeartbeatTimeout is how long an ingest node may go without a heartbeat before it's considered
+// offline, and the streams on it are marked as disconnected
+const IngestNodeHeartbeatTimeout = 30 * time.Second
+
+// IngestNodeConnectGrace is how long after a stream connects to an ingest node it may still be missing
+// from the node's heartbeats, because the heartbeat was built before the stream connected
+const IngestNodeConnectGrace = 10 * time.Second
+
+// IngestNode is an RTMP ingest server that is allowed to call into the API. Each node signs its
+// requests with its own secret, so a single node can be revoked without affecting the others.
+// Nodes heartbeat their capacity and load, which is used to pick where encoders should connect.
+// Streams on a node with a playback URL template are played from it, instead of the default
+type IngestNode struct {
+	ID                  uint64 `gorm:"primaryKey"`
+	Name                string
+	Identifier          string `gorm:"uniqueIndex"`
+	Secret              string
+	AllowedCidrs        string
+	IngestUrl           string `gorm:"not null;default:''"`
+	PlaybackUrlTemplate string `gorm:"not null;default:''"`
+	Capacity            int    `gorm:"not null;default:0"`
+	ActiveStreams       int    `gorm:"not null;default:0"`
+	LastHeartbeatDate   sql.NullTime
+	LastSeenDate        sql.NullTime
+	RevokedDate         sql.NullTime
+	CreatedDate         time.Time
+}
+
+// GetAllowedCidrs gets the CIDR ranges the node may call from. An empty list allows any address
+func (n *IngestNode) GetAllowedCidrs() []string {
+	return strings.Fields(n.AllowedCidrs)
+}
+
+// IsOnline checks if the node has sent a heartbeat recently
+func (n *IngestNode) IsOnline(now time.Time) bool {
+	return n.LastHeartbeatDate.Valid &&
+		now.Sub(n.LastHeartbeatDate.Time) < IngestNodeHeartbeatTimeout &&
+		!n.RevokedDate.Valid
+}
+
+// HasCapacity checks if the node can take another stream
+func (n *IngestNode) HasCapacity() bool {
+	return n.ActiveStreams < n.Capacity
+}
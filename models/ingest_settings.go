@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// IngestRendition is one rung of the transcoding ladder the RTMP server produces from a stream
+type IngestRendition struct {
+	Name        string `json:"name"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	BitrateKbps int    `json:"bitrate_kbps"`
+	Framerate   int    `json:"framerate"`
+}
+
+// IngestSettings are the limits and behavior the RTMP server applies to a stream. Settings belong to
+// either a creator profile, where they're the defaults for all of its streams, or to a single stream.
+// Null fields aren't set at that level, and are inherited instead
+type IngestSettings struct {
+	ID                 uint64        `gorm:"primaryKey"`
+	CreatorProfileID   sql.NullInt64 `gorm:"uniqueIndex"`
+	StreamID           sql.NullInt64 `gorm:"uniqueIndex"`
+	MaxBitrateKbps     sql.NullInt32
+	MaxWidth           sql.NullInt32
+	MaxHeight          sql.NullInt32
+	MaxDurationSeconds sql.NullInt32
+	Record             sql.NullBool
+	AllowedVideoCodecs sql.NullString
+	AllowedAudioCodecs sql.NullString
+	Renditions         sql.NullString
+	UpdatedDate        time.Time
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	LoginAttemptResult_Success      = "success"
+	LoginAttemptResult_BadPassword  = "bad_password"
+	LoginAttemptResult_BadTotp      = "bad_totp"
+	LoginAttemptResult_Unverified   = "unverified"
+	LoginAttemptResult_Disabled     = "disabled"
+	LoginAttemptResult_Throttled    = "throttled"
+	LoginAttemptResult_UnknownEmail = "unknown_email"
+)
+
+// LoginAttemptFailureResults are the results that count as failed guesses at the credentials
+var LoginAttemptFailureResults = []string{
+	LoginAttemptResult_BadPassword,
+	LoginAttemptResult_BadTotp,
+	LoginAttemptResult_UnknownEmail,
+}
+
+// LoginAttempt is a record of an attempt to log in, used to throttle brute-force attacks and to show
+// account owners suspicious activity. Failed guesses at a stream's password are recorded against the stream
+type LoginAttempt struct {
+	ID          uint64 `gorm:"primaryKey"`
+	AccountID   sql.NullInt64
+	StreamID    sql.NullInt64 `gorm:"index"`
+	Email       string
+	IPAddress   string `gorm:"index"`
+	UserAgent   string
+	Result      string
+	CreatedDate time.Time `gorm:"index"`
+}
+
+// IsFailure checks if the attempt counts as a failed guess at the credentials
+func (a *LoginAttempt) IsFailure() bool {
+	for _, result := range LoginAttemptFailureResults {
+		if a.Result == result {
+			return true
+		}
+	}
+	return false
+}
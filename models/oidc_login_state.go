@@ -0,0 +1,18 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OidcLoginState tracks an external identity provider login between redirecting the user to the
+// provider and the provider redirecting them back
+type OidcLoginState struct {
+	ID           uint64 `gorm:"primaryKey"`
+	StateHash    string `gorm:"index"`
+	Nonce        string
+	CodeVerifier string
+	ExpiresDate  time.Time
+	UsedDate     sql.NullTime
+	CreatedDate  time.Time
+}
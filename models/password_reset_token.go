@@ -0,0 +1,17 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PasswordResetToken is a single-use token sent to an account's email address to reset its password
+type PasswordResetToken struct {
+	ID          uint64 `gorm:"primaryKey"`
+	AccountID   uint64
+	Account     *Account
+	TokenHash   string
+	ExpiresDate time.Time
+	UsedDate    sql.NullTime
+	CreatedDate time.Time
+}
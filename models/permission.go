@@ -0,0 +1,77 @@
+package models
+
+const (
+	Permission_StreamsRead       = "streams:read"
+	Permission_StreamsWrite      = "streams:write"
+	Permission_StreamsKeys       = "streams:keys"
+	Permission_MembersManage     = "members:manage"
+	Permission_NotifySend        = "notify:send"
+	Permission_AnalyticsRead     = "analytics:read"
+	Permission_SecurityRead      = "security:read"
+	Permission_AuditRead         = "audit:read"
+	Permission_ApiKeysManage     = "api_keys:manage"
+	Permission_ProfileManage     = "profile:manage"
+	Permission_ProfileDelete     = "profile:delete"
+	Permission_OwnershipTransfer = "ownership:transfer"
+)
+
+// rolePermissions is the set of permissions granted to each member role
+var rolePermissions = map[string][]string{
+	MemberRole_Owner: {
+		Permission_StreamsRead,
+		Permission_StreamsWrite,
+		Permission_StreamsKeys,
+		Permission_MembersManage,
+		Permission_NotifySend,
+		Permission_AnalyticsRead,
+		Permission_SecurityRead,
+		Permission_AuditRead,
+		Permission_ApiKeysManage,
+		Permission_ProfileManage,
+		Permission_ProfileDelete,
+		Permission_OwnershipTransfer,
+	},
+	MemberRole_Admin: {
+		Permission_StreamsRead,
+		Permission_StreamsWrite,
+		Permission_StreamsKeys,
+		Permission_MembersManage,
+		Permission_NotifySend,
+		Permission_AnalyticsRead,
+		Permission_SecurityRead,
+		Permission_AuditRead,
+		Permission_ApiKeysManage,
+		Permission_ProfileManage,
+	},
+	MemberRole_Producer: {
+		Permission_StreamsRead,
+		Permission_StreamsWrite,
+		Permission_StreamsKeys,
+		Permission_NotifySend,
+		Permission_AnalyticsRead,
+	},
+	MemberRole_Moderator: {
+		Permission_StreamsRead,
+		Permission_NotifySend,
+		Permission_AnalyticsRead,
+	},
+	MemberRole_ViewerAnalytics: {
+		Permission_StreamsRead,
+		Permission_AnalyticsRead,
+	},
+}
+
+// GetRolePermissions gets the permissions granted to a member role
+func GetRolePermissions(role string) []string {
+	return rolePermissions[role]
+}
+
+// RoleHasPermission checks if a member role is granted the permission
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Session is a login on a single device. Access tokens are issued against a session, and stop working
+// as soon as the session is revoked. The refresh token that was rotated out most recently is kept, so
+// that presenting it again can be caught
+type Session struct {
+	ID                       uint64 `gorm:"primaryKey"`
+	AccountID                uint64
+	Account                  *Account
+	RefreshTokenHash         string `gorm:"index"`
+	PreviousRefreshTokenHash string `gorm:"index"`
+	Device                   string
+	IPAddress                string
+	UserAgent                string
+	ImpersonatorAccountID    sql.NullInt64
+	LastSeenDate             time.Time
+	ExpiresDate              time.Time
+	RevokedDate              sql.NullTime
+	CreatedDate              time.Time
+}
+
+// IsImpersonation checks if the session was started by a platform admin to act as the account
+func (s *Session) IsImpersonation() bool {
+	return s.ImpersonatorAccountID.Valid
+}
+
+// IsActive checks if the session can still be used at the given time
+func (s *Session) IsActive(now time.Time) bool {
+	return !s.RevokedDate.Valid && s.ExpiresDate.After(now)
+}
@@ -9,4 +9,7 @@ type SiteConfig struct {
 	ID              uint64 `gorm:"primaryKey"`
 	VapidPublicKey  sql.NullString
 	VapidPrivateKey sql.NullString
+	// LegacyAccountsVerifiedDate is when accounts that predate email verification were marked as
+	// verified. The migration only ever runs once
+	LegacyAccountsVerifiedDate sql.NullTime
 }
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"time"
+
+	"github.com/connerdouglass/livestream-api/utils"
 )
 
 const (
@@ -12,20 +14,136 @@ const (
 	StreamStatus_Cancelled = "cancelled"
 )
 
+const (
+	StreamIngestPolicy_Manual      = "manual"
+	StreamIngestPolicy_AutoLive    = "auto_live"
+	StreamIngestPolicy_AutoLiveEnd = "auto_live_end"
+	StreamIngestPolicy_AutoEnd     = "auto_end"
+)
+
+const (
+	StreamVisibility_Public   = "public"
+	StreamVisibility_Unlisted = "unlisted"
+	StreamVisibility_Members  = "members"
+	StreamVisibility_Password = "password"
+)
+
+// streamStatusTransitions is the set of statuses each stream status can move to. Ended and cancelled
+// streams are finished, and can't change status again
+var streamStatusTransitions = map[string][]string{
+	StreamStatus_Upcoming: {
+		StreamStatus_Live,
+		StreamStatus_Cancelled,
+	},
+	StreamStatus_Live: {
+		StreamStatus_Ended,
+	},
+	StreamStatus_Ended:     {},
+	StreamStatus_Cancelled: {},
+}
+
+// IsStreamStatus checks if a string is one of the stream statuses
+func IsStreamStatus(status string) bool {
+	_, ok := streamStatusTransitions[status]
+	return ok
+}
+
+// IsStreamIngestPolicy checks if a string is one of the stream ingest policies
+func IsStreamIngestPolicy(policy string) bool {
+	return policy == StreamIngestPolicy_Manual ||
+		policy == StreamIngestPolicy_AutoLive ||
+		policy == StreamIngestPolicy_AutoLiveEnd ||
+		policy == StreamIngestPolicy_AutoEnd
+}
+
+// IsStreamVisibility checks if a string is one of the stream visibilities
+func IsStreamVisibility(visibility string) bool {
+	return visibility == StreamVisibility_Public ||
+		visibility == StreamVisibility_Unlisted ||
+		visibility == StreamVisibility_Members ||
+		visibility == StreamVisibility_Password
+}
+
+// CanTransitionStreamStatus checks if a stream may move from one status to another
+func CanTransitionStreamStatus(from, to string) bool {
+	for _, status := range streamStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
 // Stream represents a scheduled or currently-live stream
 type Stream struct {
-	ID                 uint64 `gorm:"primaryKey"`
-	CreatorProfileID   uint64
-	CreatorProfile     *CreatorProfile
-	Identifier         string
-	Title              string
-	StreamKey          string
-	Status             string
-	Streaming          bool
-	ScheduledStartDate time.Time
-	ChatRoomUrl        sql.NullString
-	CurrentViewers     int
-	EndedDate          sql.NullTime
-	CreatedDate        time.Time
-	DeletedDate        sql.NullTime
+	ID                     uint64 `gorm:"primaryKey"`
+	CreatorProfileID       uint64
+	CreatorProfile         *CreatorProfile
+	Identifier             string
+	Title                  string
+	StreamKey              string `gorm:"index"`
+	Status                 string
+	Streaming              bool
+	IngestPolicy           string `gorm:"not null;default:'manual'"`
+	DisconnectGraceSeconds int    `gorm:"not null;default:60"`
+	Visibility             string `gorm:"not null;default:'public'"`
+	PasswordHash           string `gorm:"not null;default:''"`
+	ConnectedDate          sql.NullTime
+	DisconnectedDate       sql.NullTime
+	IngestNodeID           sql.NullInt64 `gorm:"index"`
+	IngestNode             *IngestNode
+	ScheduledStartDate     time.Time
+	ChatRoomUrl            sql.NullString
+	ThumbnailUrl           sql.NullString
+	CurrentViewers         int
+	StartedDate            sql.NullTime
+	EndedDate              sql.NullTime
+	CreatedDate            time.Time
+	DeletedDate            sql.NullTime
+}
+
+// IsFinished checks if the stream has ended or was cancelled
+func (s *Stream) IsFinished() bool {
+	return s.Status == StreamStatus_Ended || s.Status == StreamStatus_Cancelled
+}
+
+// GoesLiveOnIngest checks if the stream goes live by itself when its encoder connects
+func (s *Stream) GoesLiveOnIngest() bool {
+	return s.IngestPolicy == StreamIngestPolicy_AutoLive || s.IngestPolicy == StreamIngestPolicy_AutoLiveEnd
+}
+
+// EndsOnDisconnect checks if the stream ends by itself once its encoder has been disconnected for
+// longer than the grace period
+func (s *Stream) EndsOnDisconnect() bool {
+	return s.IngestPolicy == StreamIngestPolicy_AutoLiveEnd || s.IngestPolicy == StreamIngestPolicy_AutoEnd
+}
+
+// DisconnectGracePeriod gets how long the encoder may be disconnected before the stream ends
+func (s *Stream) DisconnectGracePeriod() time.Duration {
+	return time.Duration(s.DisconnectGraceSeconds) * time.Second
+}
+
+// IsPublic checks if the stream is listed on its creator's page, and its subscribers are notified when
+// it goes live. Unlisted streams can be watched by anyone with the link, but are otherwise hidden
+func (s *Stream) IsPublic() bool {
+	return s.Visibility == StreamVisibility_Public
+}
+
+// RequiresPassword checks if viewers must enter the stream's password to watch it
+func (s *Stream) RequiresPassword() bool {
+	return s.Visibility == StreamVisibility_Password
+}
+
+// MembersOnly checks if only members of the stream's creator profile may watch it
+func (s *Stream) MembersOnly() bool {
+	return s.Visibility == StreamVisibility_Members
+}
+
+// VerifyPassword checks a viewer's password against the stream's password
+func (s *Stream) VerifyPassword(password string) bool {
+	if len(s.PasswordHash) == 0 {
+		return false
+	}
+	ok, err := utils.VerifyPasswordHash(password, s.PasswordHash)
+	return err == nil && ok
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// StreamAnalytics is the final summary of how many viewers watched a stream, computed once it ended
+type StreamAnalytics struct {
+	ID             uint64 `gorm:"primaryKey"`
+	StreamID       uint64 `gorm:"uniqueIndex"`
+	PeakViewers    int
+	AverageViewers float64
+	UniqueViewers  int
+	WatchMinutes   float64
+	ComputedDate   time.Time
+}
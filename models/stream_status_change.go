@@ -0,0 +1,25 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	StreamStatusSource_Studio = "studio"
+	StreamStatusSource_Rtmp   = "rtmp"
+	StreamStatusSource_Admin  = "admin"
+)
+
+// StreamStatusChange records a stream moving from one status to another, and what triggered it
+type StreamStatusChange struct {
+	ID             uint64 `gorm:"primaryKey"`
+	StreamID       uint64 `gorm:"index"`
+	FromStatus     string
+	ToStatus       string
+	Source         string
+	ActorAccountID sql.NullInt64
+	ApiKeyID       sql.NullInt64
+	IngestNodeID   sql.NullInt64
+	CreatedDate    time.Time
+}
@@ -0,0 +1,58 @@
+package models
+
+import "testing"
+
+func TestCanTransitionStreamStatus(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		expected bool
+	}{
+		{StreamStatus_Upcoming, StreamStatus_Live, true},
+		{StreamStatus_Upcoming, StreamStatus_Cancelled, true},
+		{StreamStatus_Upcoming, StreamStatus_Ended, false},
+		{StreamStatus_Upcoming, StreamStatus_Upcoming, false},
+		{StreamStatus_Live, StreamStatus_Ended, true},
+		{StreamStatus_Live, StreamStatus_Upcoming, false},
+		{StreamStatus_Live, StreamStatus_Cancelled, false},
+		{StreamStatus_Live, StreamStatus_Live, false},
+		{StreamStatus_Ended, StreamStatus_Live, false},
+		{StreamStatus_Ended, StreamStatus_Upcoming, false},
+		{StreamStatus_Cancelled, StreamStatus_Live, false},
+		{StreamStatus_Cancelled, StreamStatus_Upcoming, false},
+		{"unknown", StreamStatus_Live, false},
+		{StreamStatus_Upcoming, "unknown", false},
+	}
+	for _, test := range tests {
+		if actual := CanTransitionStreamStatus(test.from, test.to); actual != test.expected {
+			t.Errorf("%s -> %s: expected %t, got %t", test.from, test.to, test.expected, actual)
+		}
+	}
+}
+
+func TestStreamIngestPolicies(t *testing.T) {
+	tests := []struct {
+		policy       string
+		goesLive     bool
+		endsOnDrop   bool
+		isRecognized bool
+	}{
+		{StreamIngestPolicy_Manual, false, false, true},
+		{StreamIngestPolicy_AutoLive, true, false, true},
+		{StreamIngestPolicy_AutoEnd, false, true, true},
+		{StreamIngestPolicy_AutoLiveEnd, true, true, true},
+		{"unknown", false, false, false},
+	}
+	for _, test := range tests {
+		stream := Stream{IngestPolicy: test.policy}
+		if stream.GoesLiveOnIngest() != test.goesLive {
+			t.Errorf("%s: expected goes live on ingest to be %t", test.policy, test.goesLive)
+		}
+		if stream.EndsOnDisconnect() != test.endsOnDrop {
+			t.Errorf("%s: expected ends on disconnect to be %t", test.policy, test.endsOnDrop)
+		}
+		if IsStreamIngestPolicy(test.policy) != test.isRecognized {
+			t.Errorf("%s: expected recognized to be %t", test.policy, test.isRecognized)
+		}
+	}
+}
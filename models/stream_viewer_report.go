@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// StreamViewerReport is the latest number of viewers an edge node reported watching a stream. A stream's
+// viewer count is the sum of the recent reports from every node
+type StreamViewerReport struct {
+	ID           uint64 `gorm:"primaryKey"`
+	StreamID     uint64 `gorm:"uniqueIndex:idx_stream_viewer_report_node"`
+	IngestNodeID uint64 `gorm:"uniqueIndex:idx_stream_viewer_report_node"`
+	Viewers      int
+	ReportedDate time.Time `gorm:"index"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// StreamViewerSample records the viewer count of a stream at a point in time
+type StreamViewerSample struct {
+	ID          uint64 `gorm:"primaryKey"`
+	StreamID    uint64 `gorm:"index"`
+	Viewers     int
+	CreatedDate time.Time
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TotpRecoveryCode is a single-use code that can stand in for a TOTP code, in case the account owner
+// loses their authenticator device
+type TotpRecoveryCode struct {
+	ID          uint64 `gorm:"primaryKey"`
+	AccountID   uint64
+	Account     *Account
+	CodeHash    string
+	UsedDate    sql.NullTime
+	CreatedDate time.Time
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ViewerSession is a span of time an anonymous viewer spent watching a stream. Viewers heartbeat while
+// they watch, and the session ends when they leave or stop heartbeating
+type ViewerSession struct {
+	ID           uint64 `gorm:"primaryKey"`
+	StreamID     uint64 `gorm:"index"`
+	ViewerID     string `gorm:"index"`
+	StartedDate  time.Time
+	LastSeenDate time.Time
+	EndedDate    sql.NullTime
+}
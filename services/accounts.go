@@ -1,12 +1,23 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
 	"gorm.io/gorm"
 )
 
+// accountsSearchMaxLimit is the most accounts returned by a single search
+const accountsSearchMaxLimit = 100
+
+// ErrAccountDisabled is returned when a disabled account tries to start a session
+var ErrAccountDisabled = errors.New("this account has been disabled")
+
 // AccountsService manages the accounts on the platform
 type AccountsService struct {
 	DB *gorm.DB
@@ -22,20 +33,41 @@ func (s *AccountsService) FindByLogin(email, password string) (*models.Account,
 	}
 
 	// Verify the password
-	if !account.VerifyPassword(password) {
+	if account == nil || !account.VerifyPassword(password) {
 		return nil, nil
 	}
 
+	// Upgrade the password hash if it was made with an outdated algorithm. A failure here shouldn't
+	// prevent the login, since the old hash is still valid
+	if account.PasswordNeedsRehash() {
+		if err := s.rehashPassword(account, password); err != nil {
+			fmt.Println("Error upgrading password hash: ", err.Error())
+		}
+	}
+
 	// Return the account
 	return account, nil
 
 }
 
-// GetByEmail gets the account with the provided email address
+// rehashPassword replaces the password hash on the account with one using the current algorithm
+func (s *AccountsService) rehashPassword(account *models.Account, password string) error {
+	if err := account.RehashPassword(password); err != nil {
+		return err
+	}
+	return s.DB.
+		Model(account).
+		Update("password_hash", account.PasswordHash).
+		Error
+}
+
+// GetByEmail gets the account with the provided email address. Email addresses are matched exactly,
+// ignoring case
 func (s *AccountsService) GetByEmail(email string) (*models.Account, error) {
 	var account models.Account
 	err := s.DB.
-		Where("email LIKE ?", email).
+		Where("deleted_date IS NULL").
+		Where("LOWER(email) = ?", utils.NormalizeEmail(email)).
 		First(&account).
 		Error
 	if err != nil {
@@ -46,3 +78,285 @@ func (s *AccountsService) GetByEmail(email string) (*models.Account, error) {
 	}
 	return &account, nil
 }
+
+// Register creates a new account with the provided credentials. The account starts out with an
+// unverified email address, and cannot log in until it has been verified.
+func (s *AccountsService) Register(email, password string) (*models.Account, error) {
+
+	// Validate the email address
+	email = utils.NormalizeEmail(email)
+	if err := utils.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+
+	// Validate the password
+	if err := utils.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	// Make sure the email address isn't already taken
+	existing, err := s.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("an account with that email address already exists")
+	}
+
+	// Create the account
+	account := models.Account{
+		Email:       email,
+		CreatedDate: time.Now(),
+	}
+	if err := account.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Create(&account).Error; err != nil {
+		return nil, err
+	}
+
+	// Return the account
+	return &account, nil
+
+}
+
+// RegisterExternal creates a new account for a user who logged in through an external identity
+// provider, which must have already verified their email address. The account has no password until
+// one is set through a password reset
+func (s *AccountsService) RegisterExternal(email string) (*models.Account, error) {
+
+	// Validate the email address
+	email = utils.NormalizeEmail(email)
+	if err := utils.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+
+	// Make sure the email address isn't already taken
+	existing, err := s.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("an account with that email address already exists")
+	}
+
+	// The salt is still needed to sign auth tokens, even without a password
+	salt, err := utils.SecureRandHexStr(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the account
+	account := models.Account{
+		Email:             email,
+		PasswordSalt:      salt,
+		EmailVerifiedDate: sql.NullTime{Valid: true, Time: time.Now()},
+		CreatedDate:       time.Now(),
+	}
+	if err := s.DB.Create(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+
+}
+
+// UpdatePassword validates and sets a new password on the account. Setting a password rotates the
+// account's salt, which invalidates every auth token previously issued for the account.
+func (s *AccountsService) UpdatePassword(account *models.Account, password string) error {
+
+	// Validate the password
+	if err := utils.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	// Set the password and save the account
+	if err := account.SetPassword(password); err != nil {
+		return err
+	}
+	return s.DB.Save(account).Error
+
+}
+
+// MarkEmailVerified marks the email address on the account as verified
+func (s *AccountsService) MarkEmailVerified(account *models.Account) error {
+	account.EmailVerifiedDate = sql.NullTime{
+		Valid: true,
+		Time:  time.Now(),
+	}
+	return s.DB.Save(account).Error
+}
+
+// ClaimUnverifiedAccount verifies an account whose email address was proven through an external identity
+// provider, but that was never verified by its own link. Anyone could have registered the address, so
+// the password they chose is removed and their sessions are revoked before the account is handed over.
+// The salt is rotated too, which invalidates any auth tokens issued for it
+func (s *AccountsService) ClaimUnverifiedAccount(account *models.Account) error {
+
+	// The salt is still needed to sign auth tokens, even without a password
+	salt, err := utils.SecureRandHexStr(32)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	// Clear the password, revoke the sessions, and verify the account together
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&models.Session{}).
+			Where("account_id = ?", account.ID).
+			Where("revoked_date IS NULL").
+			Update("revoked_date", sql.NullTime{Valid: true, Time: now}).
+			Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Model(account).
+			Updates(map[string]interface{}{
+				"password_hash":       "",
+				"password_salt":       salt,
+				"email_verified_date": sql.NullTime{Valid: true, Time: now},
+			}).
+			Error
+	})
+	if err != nil {
+		return err
+	}
+	account.PasswordHash = ""
+	account.PasswordSalt = salt
+	account.EmailVerifiedDate = sql.NullTime{Valid: true, Time: now}
+	return nil
+
+}
+
+// MarkLegacyAccountsVerified marks accounts created before email verification existed as verified,
+// so they aren't locked out. Every account created through registration has a verification token,
+// so any unverified account without one must predate registration. This is a one-time migration,
+// recorded on the site config, so that later accounts are never verified without a link
+func (s *AccountsService) MarkLegacyAccountsVerified(config *models.SiteConfig) error {
+
+	// Only migrate once
+	if config.LegacyAccountsVerifiedDate.Valid {
+		return nil
+	}
+
+	// Verify the legacy accounts, and record that the migration ran
+	now := sql.NullTime{Valid: true, Time: time.Now()}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&models.Account{}).
+			Where("email_verified_date IS NULL").
+			Where(
+				"id NOT IN (?)",
+				tx.
+					Select("account_id").
+					Model(&models.EmailVerificationToken{}),
+			).
+			Update("email_verified_date", gorm.Expr("created_date")).
+			Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Model(config).
+			Update("legacy_accounts_verified_date", now).
+			Error
+	})
+	if err != nil {
+		return err
+	}
+	config.LegacyAccountsVerifiedDate = now
+	return nil
+
+}
+
+// GetByID gets the account with the identifier
+func (s *AccountsService) GetByID(accountID uint64) (*models.Account, error) {
+	var account models.Account
+	err := s.DB.
+		Where("deleted_date IS NULL").
+		Where("id = ?", accountID).
+		First(&account).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+// SearchAccounts finds the accounts with an email address containing the query, newest first, along with
+// the total number of matches
+func (s *AccountsService) SearchAccounts(query string, offset, limit int) ([]*models.Account, int64, error) {
+
+	// Build the query
+	db := s.DB.
+		Model(&models.Account{}).
+		Where("deleted_date IS NULL")
+	if query = strings.TrimSpace(query); len(query) > 0 {
+		db = db.Where("LOWER(email) LIKE ?", "%"+strings.ToLower(query)+"%")
+	}
+
+	// Count all of the matches
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get the page of matches
+	if limit <= 0 || limit > accountsSearchMaxLimit {
+		limit = accountsSearchMaxLimit
+	}
+	var accounts []*models.Account
+	err := db.
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&accounts).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return accounts, total, nil
+
+}
+
+// SetDisabled disables or re-enables an account. Disabled accounts can't log in, and their existing
+// access tokens stop working
+func (s *AccountsService) SetDisabled(account *models.Account, disabled bool) error {
+	account.DisabledDate = sql.NullTime{}
+	if disabled {
+		account.DisabledDate = sql.NullTime{Valid: true, Time: time.Now()}
+	}
+	return s.DB.
+		Model(account).
+		Update("disabled_date", account.DisabledDate).
+		Error
+}
+
+// PromoteAdmins makes the accounts with the email addresses into platform admins. This is how the first
+// admins are created, since only admins can manage the platform
+func (s *AccountsService) PromoteAdmins(emails []string) error {
+
+	// Normalize the email addresses, skipping blanks
+	var normalized []string
+	for _, email := range emails {
+		if email = utils.NormalizeEmail(email); len(email) > 0 {
+			normalized = append(normalized, email)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+
+	// Promote the accounts
+	return s.DB.
+		Model(&models.Account{}).
+		Where("deleted_date IS NULL").
+		Where("LOWER(email) IN ?", normalized).
+		Update("is_admin", true).
+		Error
+
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// ApiKeyPrefix is the prefix of every API key, which tells them apart from auth tokens
+	ApiKeyPrefix = "lsk_"
+
+	// apiKeyLastUsedInterval limits how often the last-used date of a key is written
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// ApiKeysService manages the API keys on creator profiles
+type ApiKeysService struct {
+	DB *gorm.DB
+}
+
+// CreateKey creates a new API key on a creator profile. The full key is only returned here, and
+// only its hash is stored
+func (s *ApiKeysService) CreateKey(
+	creatorID uint64,
+	accountID uint64,
+	name string,
+	scopes []string,
+	expires *time.Time,
+) (*models.ApiKey, string, error) {
+
+	// Validate the name
+	name = strings.TrimSpace(name)
+	if len(name) == 0 || len(name) > 64 {
+		return nil, "", errors.New("API key name must be between 1 and 64 characters")
+	}
+
+	// Validate the scopes
+	if len(scopes) == 0 {
+		return nil, "", errors.New("API key must have at least one scope")
+	}
+	for _, scope := range scopes {
+		if !isApiKeyScope(scope) {
+			return nil, "", fmt.Errorf("unsupported API key scope: \"%s\"", scope)
+		}
+	}
+
+	// Generate the key. The prefix is used to find the key, and the rest is the secret
+	prefix, err := utils.SecureRandHexStr(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := utils.SecureRandHexStr(40)
+	if err != nil {
+		return nil, "", err
+	}
+	key := fmt.Sprintf("%s%s_%s", ApiKeyPrefix, prefix, secret)
+
+	// Create the key record
+	apiKey := models.ApiKey{
+		CreatorProfileID:   creatorID,
+		CreatedByAccountID: accountID,
+		Name:               name,
+		Prefix:             prefix,
+		KeyHash:            utils.Sha256Hex(key),
+		Scopes:             strings.Join(scopes, " "),
+		CreatedDate:        time.Now(),
+	}
+	if expires != nil {
+		apiKey.ExpiresDate = sql.NullTime{Valid: true, Time: *expires}
+	}
+	if err := s.DB.Create(&apiKey).Error; err != nil {
+		return nil, "", err
+	}
+	return &apiKey, key, nil
+
+}
+
+// isApiKeyScope checks if the scope is one that can be granted to API keys
+func isApiKeyScope(scope string) bool {
+	for _, s := range models.ApiKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate gets the active API key matching the provided key string. Keys stop working when the
+// account that created them is disabled or deleted
+func (s *ApiKeysService) Authenticate(key string) (*models.ApiKey, error) {
+
+	// Parse out the prefix of the key
+	parts := strings.SplitN(strings.TrimPrefix(key, ApiKeyPrefix), "_", 2)
+	if !strings.HasPrefix(key, ApiKeyPrefix) || len(parts) != 2 {
+		return nil, errors.New("malformed API key")
+	}
+
+	// Find the key with the prefix
+	var apiKey models.ApiKey
+	err := s.DB.
+		Where("prefix = ?", parts[0]).
+		Where("revoked_date IS NULL").
+		Preload("CreatorProfile").
+		Preload("CreatedByAccount").
+		First(&apiKey).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid API key")
+		}
+		return nil, err
+	}
+
+	// Compare the hashes in constant time
+	if subtle.ConstantTimeCompare([]byte(utils.Sha256Hex(key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, errors.New("invalid API key")
+	}
+
+	// Make sure the key hasn't expired, and its profile and the account that created it still exist
+	now := time.Now()
+	if apiKey.ExpiresDate.Valid && apiKey.ExpiresDate.Time.Before(now) {
+		return nil, errors.New("API key has expired")
+	}
+	if apiKey.CreatorProfile == nil || apiKey.CreatorProfile.DeletedDate.Valid {
+		return nil, errors.New("invalid API key")
+	}
+	if apiKey.CreatedByAccount == nil ||
+		apiKey.CreatedByAccount.DeletedDate.Valid ||
+		apiKey.CreatedByAccount.IsDisabled() {
+		return nil, errors.New("invalid API key")
+	}
+
+	// Record that the key was used
+	if !apiKey.LastUsedDate.Valid || now.Sub(apiKey.LastUsedDate.Time) >= apiKeyLastUsedInterval {
+		apiKey.LastUsedDate = sql.NullTime{Valid: true, Time: now}
+		err := s.DB.
+			Model(&apiKey).
+			Update("last_used_date", apiKey.LastUsedDate).
+			Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &apiKey, nil
+
+}
+
+// GetKeysForCreator gets the API keys on a creator profile that haven't been revoked
+func (s *ApiKeysService) GetKeysForCreator(creatorID uint64) ([]*models.ApiKey, error) {
+	var keys []*models.ApiKey
+	err := s.DB.
+		Where("creator_profile_id = ?", creatorID).
+		Where("revoked_date IS NULL").
+		Preload("CreatedByAccount").
+		Order("created_date DESC").
+		Find(&keys).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeKey revokes an API key on a creator profile, so it can no longer be used
+func (s *ApiKeysService) RevokeKey(creatorID, keyID uint64) error {
+	result := s.DB.
+		Model(&models.ApiKey{}).
+		Where("id = ?", keyID).
+		Where("creator_profile_id = ?", creatorID).
+		Where("revoked_date IS NULL").
+		Update("revoked_date", sql.NullTime{Valid: true, Time: time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("API key not found")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+)
+
+func TestAuthenticateRejectsKeysOfDisabledAccounts(t *testing.T) {
+	s := ApiKeysService{DB: openTestDB(t, &models.ApiKey{}, &models.CreatorProfile{}, &models.Account{})}
+	account := &models.Account{Email: "a@example.com"}
+	s.DB.Create(account)
+	s.DB.Create(&models.CreatorProfile{ID: 1})
+	_, key, err := s.CreateKey(1, account.ID, "Deploys", []string{models.Permission_StreamsRead}, nil)
+	if err != nil {
+		t.Fatalf("error creating key: %s", err.Error())
+	}
+	if _, err := s.Authenticate(key); err != nil {
+		t.Fatalf("error authenticating key: %s", err.Error())
+	}
+
+	// Once the account that created the key is disabled, the key stops working
+	s.DB.Model(account).Update("disabled_date", sql.NullTime{Valid: true, Time: time.Now()})
+	if _, err := s.Authenticate(key); err == nil {
+		t.Errorf("key of a disabled account was accepted")
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+// auditEventsMaxLimit is the most audit events returned at once
+const auditEventsMaxLimit = 200
+
+// AuditActor identifies who took an audited action. Zero values mean the field doesn't apply
+type AuditActor struct {
+	AccountID             uint64
+	ImpersonatorAccountID uint64
+	ApiKeyID              uint64
+	IngestNodeID          uint64
+	IPAddress             string
+}
+
+// AuditEntry describes an audited action. Before and After are snapshots of the target, which are
+// stored as the list of fields that changed. Either may be nil when the target was created or deleted
+type AuditEntry struct {
+	CreatorProfileID uint64
+	Action           string
+	TargetType       string
+	TargetID         string
+	Before           interface{}
+	After            interface{}
+	Details          interface{}
+}
+
+// AuditFilter narrows down the audit events to list. Zero values match everything
+type AuditFilter struct {
+	CreatorProfileID uint64
+	ActorAccountID   uint64
+	Action           string
+	BeforeID         uint64
+	Limit            int
+}
+
+// AuditService records and lists the audit trail of actions taken on the platform
+type AuditService struct {
+	DB *gorm.DB
+}
+
+// Record saves an audit event for an action
+func (s *AuditService) Record(actor *AuditActor, entry *AuditEntry) error {
+
+	// Encode the changes to the target
+	var changes string
+	if entry.Before != nil || entry.After != nil {
+		diff, err := utils.JsonDiff(entry.Before, entry.After)
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		changes = string(encoded)
+	}
+
+	// Encode the details
+	var details string
+	if entry.Details != nil {
+		encoded, err := json.Marshal(entry.Details)
+		if err != nil {
+			return err
+		}
+		details = string(encoded)
+	}
+
+	// Save the event
+	event := models.AuditEvent{
+		ActorAccountID:        nullInt64(actor.AccountID),
+		ImpersonatorAccountID: nullInt64(actor.ImpersonatorAccountID),
+		ApiKeyID:              nullInt64(actor.ApiKeyID),
+		IngestNodeID:          nullInt64(actor.IngestNodeID),
+		CreatorProfileID:      nullInt64(entry.CreatorProfileID),
+		Action:                entry.Action,
+		TargetType:            entry.TargetType,
+		TargetID:              entry.TargetID,
+		Changes:               changes,
+		Details:               details,
+		IPAddress:             actor.IPAddress,
+		CreatedDate:           time.Now(),
+	}
+	return s.DB.Create(&event).Error
+
+}
+
+// GetEvents gets the audit events matching the filter, newest first. If there are more events after this
+// page, the cursor to pass as BeforeID for the next page is returned too, and is otherwise zero
+func (s *AuditService) GetEvents(filter *AuditFilter) ([]*models.AuditEvent, uint64, error) {
+
+	// Apply the filter
+	query := s.DB.Model(&models.AuditEvent{})
+	if filter.CreatorProfileID > 0 {
+		query = query.Where("creator_profile_id = ?", filter.CreatorProfileID)
+	}
+	if filter.ActorAccountID > 0 {
+		query = query.Where("actor_account_id = ?", filter.ActorAccountID)
+	}
+	if len(filter.Action) > 0 {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	// Limit the page size
+	limit := filter.Limit
+	if limit <= 0 || limit > auditEventsMaxLimit {
+		limit = auditEventsMaxLimit
+	}
+
+	// Get the events, plus one more to tell if there's another page
+	var events []*models.AuditEvent
+	err := query.
+		Order("id DESC").
+		Limit(limit + 1).
+		Find(&events).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		return events, events[limit-1].ID, nil
+	}
+	return events, 0, nil
+
+}
+
+// nullInt64 converts an identifier to a nullable column value, where zero is null
+func nullInt64(id uint64) sql.NullInt64 {
+	if id == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Valid: true, Int64: int64(id)}
+}
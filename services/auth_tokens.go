@@ -29,9 +29,10 @@ func (s *AuthTokensService) getSigningSecretKey(secret string) []byte {
 	return []byte(utils.Sha256Hex(secret + s.SigningPepper))
 }
 
-// CreateToken creates an auth token for an account
+// CreateToken creates an auth token for an account, tied to one of its sessions
 func (s *AuthTokensService) CreateToken(
 	account *models.Account,
+	session *models.Session,
 	created time.Time,
 	expire time.Time,
 ) (string, error) {
@@ -39,6 +40,7 @@ func (s *AuthTokensService) CreateToken(
 	// Create the claims for the token
 	claims := jwt.MapClaims{
 		"uid": account.ID,
+		"sid": session.ID,
 		"cre": created.UTC().Unix(),
 		"exp": expire.UTC().Unix(),
 	}
@@ -51,8 +53,9 @@ func (s *AuthTokensService) CreateToken(
 
 }
 
-// GetAccountForToken gets the account of the provided token string
-func (s *AuthTokensService) GetAccountForToken(token string) (*models.Account, error) {
+// GetAccountForToken gets the account and session of the provided token string. Tokens whose session
+// has been revoked or has expired are rejected
+func (s *AuthTokensService) GetAccountForToken(token string) (*models.Account, *models.Session, error) {
 
 	// Decode the token
 	tokenObj, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
@@ -72,11 +75,27 @@ func (s *AuthTokensService) GetAccountForToken(token string) (*models.Account, e
 
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Get the object from the token
-	return s.getAccountFromTokenObj(tokenObj)
+	account, err := s.getAccountFromTokenObj(tokenObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	if account == nil {
+		return nil, nil, errors.New("no account matches token")
+	}
+	if account.IsDisabled() {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	// Get the session the token was issued for
+	session, err := s.getSessionFromTokenObj(tokenObj, account)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, session, nil
 
 }
 
@@ -103,6 +122,11 @@ func (s *AuthTokensService) validateTokenClaims(token *jwt.Token) (jwt.MapClaims
 		return nil, errors.New("token claims missing \"uid\" field")
 	}
 
+	// Get the session ID value. Tokens issued before sessions existed don't have one
+	if _, ok = claims["sid"]; !ok {
+		return nil, errors.New("token claims missing \"sid\" field")
+	}
+
 	// Return no issues
 	return claims, nil
 
@@ -132,3 +156,95 @@ func (s *AuthTokensService) getAccountFromTokenObj(token *jwt.Token) (*models.Ac
 	return &account, nil
 
 }
+
+// getSessionFromTokenObj gets the active session of the provided token object
+func (s *AuthTokensService) getSessionFromTokenObj(token *jwt.Token, account *models.Account) (*models.Session, error) {
+
+	// Validate the claims
+	claims, err := s.validateTokenClaims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Search the database for the session
+	var session models.Session
+	err = s.DB.
+		Where("id = ?", claims["sid"]).
+		Where("account_id = ?", account.ID).
+		First(&session).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no session matches token")
+		}
+		return nil, err
+	}
+
+	// Make sure the session can still be used
+	if !session.IsActive(time.Now()) {
+		return nil, errors.New("session has been revoked or has expired")
+	}
+	return &session, nil
+
+}
+
+// totpChallengeTokenType is the type claim on challenge tokens issued between the password and TOTP
+// steps of logging in
+const totpChallengeTokenType = "totp_challenge"
+
+// CreateChallengeToken creates a short-lived token proving that the account's password was already
+// checked, to be exchanged for a session once the second factor is verified. Challenge tokens have no
+// session, so they are never accepted as auth tokens
+func (s *AuthTokensService) CreateChallengeToken(account *models.Account, expire time.Time) (string, error) {
+
+	// Create the claims for the token
+	claims := jwt.MapClaims{
+		"uid": account.ID,
+		"typ": totpChallengeTokenType,
+		"exp": expire.UTC().Unix(),
+	}
+
+	// Sign the token to a string
+	token := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claims)
+	return token.SignedString(s.getSigningSecretKey(account.PasswordSalt))
+
+}
+
+// GetAccountForChallengeToken gets the account of the provided challenge token string
+func (s *AuthTokensService) GetAccountForChallengeToken(token string) (*models.Account, error) {
+
+	// Decode the token, looking up the account to find the signing key
+	var account *models.Account
+	_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+
+		// Make sure it's a challenge token that hasn't expired
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok || claims["typ"] != totpChallengeTokenType {
+			return nil, errors.New("not a challenge token")
+		}
+		exp, ok := claims["exp"].(float64)
+		if !ok || int64(exp) < time.Now().UTC().Unix() {
+			return nil, errors.New("challenge token has expired")
+		}
+
+		// Find the account
+		var found models.Account
+		err := s.DB.
+			Where("id = ?", claims["uid"]).
+			First(&found).
+			Error
+		if err != nil {
+			return nil, err
+		}
+		account = &found
+
+		// Get the signing secret for the account
+		return s.getSigningSecretKey(account.PasswordSalt), nil
+
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+
+}
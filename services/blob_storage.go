@@ -0,0 +1,9 @@
+package services
+
+// BlobStorage stores uploaded files, such as images, and serves them at public URLs. Implementations
+// may write to the local filesystem or to an S3-compatible object store.
+type BlobStorage interface {
+	PutBlob(key string, contentType string, data []byte) error
+	DeleteBlob(key string) error
+	BlobUrl(key string) string
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBlobStorage stores blobs as files in Dir. The files must be served at BaseUrl, which the server
+// does itself when this storage is used.
+type LocalBlobStorage struct {
+	Dir     string
+	BaseUrl string
+}
+
+func (s *LocalBlobStorage) PutBlob(key string, contentType string, data []byte) error {
+
+	// Get the path for the key
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the directory exists, and write the file
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+
+}
+
+func (s *LocalBlobStorage) DeleteBlob(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStorage) BlobUrl(key string) string {
+	return strings.TrimRight(s.BaseUrl, "/") + "/" + key
+}
+
+// path gets the file path for a key, making sure it can't point outside of the directory
+func (s *LocalBlobStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/utils"
+)
+
+// s3DefaultHttpClient calls the object store when no other client is configured
+var s3DefaultHttpClient = &http.Client{Timeout: time.Second * 30}
+
+// S3BlobStorage stores blobs in a bucket on S3 or an S3-compatible object store, such as MinIO. Objects
+// are addressed with path-style URLs under Endpoint, and served from PublicUrl, which defaults to the
+// bucket's URL at the endpoint.
+type S3BlobStorage struct {
+	Endpoint    string
+	Region      string
+	Bucket      string
+	Credentials utils.AwsCredentials
+	PublicUrl   string
+	HttpClient  *http.Client
+}
+
+func (s *S3BlobStorage) PutBlob(key string, contentType string, data []byte) error {
+	req, err := http.NewRequest(http.MethodPut, s.objectUrl(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	return s.do(req, data)
+}
+
+func (s *S3BlobStorage) DeleteBlob(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.objectUrl(key), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil)
+}
+
+func (s *S3BlobStorage) BlobUrl(key string) string {
+	if len(s.PublicUrl) > 0 {
+		return strings.TrimRight(s.PublicUrl, "/") + "/" + key
+	}
+	return s.objectUrl(key)
+}
+
+// objectUrl gets the path-style URL of the object with the key
+func (s *S3BlobStorage) objectUrl(key string) string {
+	return strings.TrimRight(s.Endpoint, "/") + "/" + s.Bucket + "/" + key
+}
+
+// do signs and sends a request to the object store
+func (s *S3BlobStorage) do(req *http.Request, body []byte) error {
+
+	// Sign the request
+	payloadHash := utils.Sha256Hex(string(body))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	utils.SignAwsV4(req, payloadHash, s.Credentials, s.Region, "s3", time.Now())
+
+	// Send it
+	client := s.HttpClient
+	if client == nil {
+		client = s3DefaultHttpClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Any 2xx status is a success
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("object store returned status %d: %s", resp.StatusCode, string(message))
+	}
+	return nil
+
+}
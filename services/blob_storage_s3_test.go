@@ -0,0 +1,79 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/utils"
+)
+
+func TestS3BlobStorage(t *testing.T) {
+
+	// Start a stand-in object store that checks request signatures
+	credentials := utils.AwsCredentials{AccessKeyID: "minio", SecretAccessKey: "minio-secret"}
+	objects := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// Sign a copy of the request the same way, and compare the signatures
+		body, _ := io.ReadAll(r.Body)
+		signTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+		if err != nil || r.Header.Get("X-Amz-Content-Sha256") != utils.Sha256Hex(string(body)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		check := r.Clone(r.Context())
+		check.Header.Del("Authorization")
+		utils.SignAwsV4(check, utils.Sha256Hex(string(body)), credentials, "us-east-1", "s3", signTime)
+		if check.Header.Get("Authorization") != r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "SignatureDoesNotMatch")
+			return
+		}
+
+		// Store or delete the object
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path] = body
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	}))
+	defer server.Close()
+
+	// Store an object
+	storage := &S3BlobStorage{
+		Endpoint:    server.URL,
+		Region:      "us-east-1",
+		Bucket:      "media",
+		Credentials: credentials,
+	}
+	if err := storage.PutBlob("images/abc/large.png", "image/png", []byte("png data")); err != nil {
+		t.Fatalf("error storing object: %s", err.Error())
+	}
+	if string(objects["/media/images/abc/large.png"]) != "png data" {
+		t.Errorf("object was not stored at the expected path")
+	}
+	if url := storage.BlobUrl("images/abc/large.png"); url != server.URL+"/media/images/abc/large.png" {
+		t.Errorf("unexpected object URL: %s", url)
+	}
+
+	// Delete it
+	if err := storage.DeleteBlob("images/abc/large.png"); err != nil {
+		t.Fatalf("error deleting object: %s", err.Error())
+	}
+	if _, ok := objects["/media/images/abc/large.png"]; ok {
+		t.Errorf("object was not deleted")
+	}
+
+	// Requests signed with the wrong secret should fail
+	storage.Credentials.SecretAccessKey = "wrong"
+	if err := storage.PutBlob("images/abc/large.png", "image/png", []byte("png data")); err == nil {
+		t.Errorf("object stored with the wrong credentials")
+	}
+
+}
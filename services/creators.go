@@ -1,20 +1,34 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/connerdouglass/livestream-api/models"
 	"gorm.io/gorm"
 )
 
+const (
+	creatorUsernameMinLength = 3
+	creatorUsernameMaxLength = 32
+	creatorNameMaxLength     = 64
+	creatorBioMaxLength      = 1000
+	creatorMaxLinks          = 10
+	creatorsSearchMaxLimit   = 100
+)
+
 // CreatorsService manages the creators on the platform
 type CreatorsService struct {
 	DB *gorm.DB
 }
 
-// GetCreatorByUsername gets the creator with the provided username
+// GetCreatorByUsername gets the creator with the provided username. If no profile has the username, but
+// a profile used to, that profile is returned instead
 func (s *CreatorsService) GetCreatorByUsername(username string) (*models.CreatorProfile, error) {
 
 	// Trim the username
@@ -29,7 +43,39 @@ func (s *CreatorsService) GetCreatorByUsername(username string) (*models.Creator
 	var creator models.CreatorProfile
 	err := s.DB.
 		Where("deleted_date IS NULL").
-		Where("username LIKE ?", username).
+		Where("LOWER(username) = ?", strings.ToLower(username)).
+		Preload("Links", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC")
+		}).
+		First(&creator).
+		Error
+	if err == nil {
+		return &creator, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Follow the most recent redirect from the username, if there is one
+	var redirect models.CreatorUsernameRedirect
+	err = s.DB.
+		Where("LOWER(username) = ?", strings.ToLower(username)).
+		Order("created_date DESC").
+		First(&redirect).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	creator = models.CreatorProfile{}
+	err = s.DB.
+		Where("deleted_date IS NULL").
+		Where("id = ?", redirect.CreatorProfileID).
+		Preload("Links", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC")
+		}).
 		First(&creator).
 		Error
 	if err != nil {
@@ -61,6 +107,9 @@ func (s *CreatorsService) GetCreatorByID(creatorID uint64) (*models.CreatorProfi
 	err := s.DB.
 		Where("deleted_date IS NULL").
 		Where("id = ?", creatorID).
+		Preload("Links", func(db *gorm.DB) *gorm.DB {
+			return db.Order("sort_order ASC")
+		}).
 		First(&creator).
 		Error
 	if err != nil {
@@ -72,8 +121,342 @@ func (s *CreatorsService) GetCreatorByID(creatorID uint64) (*models.CreatorProfi
 	return &creator, nil
 }
 
+// SearchCreators finds the creator profiles with a username or name containing the query, newest first,
+// along with the total number of matches
+func (s *CreatorsService) SearchCreators(query string, offset, limit int) ([]*models.CreatorProfile, int64, error) {
+
+	// Build the query
+	db := s.DB.
+		Model(&models.CreatorProfile{}).
+		Where("deleted_date IS NULL")
+	if query = strings.TrimSpace(query); len(query) > 0 {
+		pattern := "%" + strings.ToLower(query) + "%"
+		db = db.Where("LOWER(username) LIKE ? OR LOWER(name) LIKE ?", pattern, pattern)
+	}
+
+	// Count all of the matches
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Get the page of matches
+	if limit <= 0 || limit > creatorsSearchMaxLimit {
+		limit = creatorsSearchMaxLimit
+	}
+	var creators []*models.CreatorProfile
+	err := db.
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&creators).
+		Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return creators, total, nil
+
+}
+
 // ValidateUsernae checks if the provided username is valid
 func (s *CreatorsService) ValidateUsername(username string) bool {
 	pattern := regexp.MustCompile(`^\w+$`)
 	return pattern.MatchString(username)
 }
+
+// validateNewUsername checks if a username may be given to a profile. The profile with the identifier
+// is ignored when checking if the username is taken, so a profile can keep its own username
+func (s *CreatorsService) validateNewUsername(username string, creatorID uint64) error {
+
+	// Check the format of the username
+	if !s.ValidateUsername(username) {
+		return errors.New("username may only contain letters, numbers, and underscores")
+	}
+	if len(username) < creatorUsernameMinLength || len(username) > creatorUsernameMaxLength {
+		return fmt.Errorf(
+			"username must be between %d and %d characters",
+			creatorUsernameMinLength,
+			creatorUsernameMaxLength,
+		)
+	}
+
+	// Make sure no other profile has the username. Old usernames of other profiles are not reserved,
+	// so taking one replaces its redirect
+	var count int64
+	err := s.DB.
+		Model(&models.CreatorProfile{}).
+		Where("deleted_date IS NULL").
+		Where("LOWER(username) = ?", strings.ToLower(username)).
+		Where("id <> ?", creatorID).
+		Count(&count).
+		Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("that username is already taken")
+	}
+	return nil
+
+}
+
+type CreateCreatorOptions struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Bio      string `json:"bio"`
+}
+
+// CreateCreator creates a new creator profile, with the account as its owner
+func (s *CreatorsService) CreateCreator(accountID uint64, options *CreateCreatorOptions) (*models.CreatorProfile, error) {
+
+	// Validate the fields
+	username := strings.TrimSpace(options.Username)
+	if err := s.validateNewUsername(username, 0); err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(options.Name)
+	if err := validateCreatorName(name); err != nil {
+		return nil, err
+	}
+	bio := strings.TrimSpace(options.Bio)
+	if len(bio) > creatorBioMaxLength {
+		return nil, fmt.Errorf("bio must be at most %d characters", creatorBioMaxLength)
+	}
+
+	// Create the profile and its owner together
+	creator := models.CreatorProfile{
+		Username:    username,
+		Name:        name,
+		Bio:         bio,
+		CreatedDate: time.Now(),
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&creator).Error; err != nil {
+			return err
+		}
+		if err := s.deleteRedirects(tx, username); err != nil {
+			return err
+		}
+		return tx.Create(&models.CreatorProfileMember{
+			CreatorProfileID: creator.ID,
+			AccountID:        accountID,
+			Role:             models.MemberRole_Owner,
+			CreatedDate:      time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &creator, nil
+
+}
+
+type CreatorLink struct {
+	Label string `json:"label"`
+	Url   string `json:"url"`
+}
+
+type CreatorUpdates struct {
+	Username *string        `json:"username"`
+	Name     *string        `json:"name"`
+	Bio      *string        `json:"bio"`
+	Links    *[]CreatorLink `json:"links"`
+}
+
+// UpdateCreator commits a series of updates to the provided creator. When the username changes, the old
+// username redirects to the profile
+func (s *CreatorsService) UpdateCreator(creator *models.CreatorProfile, updates *CreatorUpdates) error {
+
+	// Collect the changed fields
+	fields := map[string]interface{}{}
+	var oldUsername, newUsername string
+	if updates.Username != nil {
+		username := strings.TrimSpace(*updates.Username)
+		if username != creator.Username {
+			if err := s.validateNewUsername(username, creator.ID); err != nil {
+				return err
+			}
+			oldUsername = creator.Username
+			newUsername = username
+			fields["username"] = username
+		}
+	}
+	if updates.Name != nil {
+		name := strings.TrimSpace(*updates.Name)
+		if err := validateCreatorName(name); err != nil {
+			return err
+		}
+		fields["name"] = name
+	}
+	if updates.Bio != nil {
+		bio := strings.TrimSpace(*updates.Bio)
+		if len(bio) > creatorBioMaxLength {
+			return fmt.Errorf("bio must be at most %d characters", creatorBioMaxLength)
+		}
+		fields["bio"] = bio
+	}
+
+	// Validate the links
+	var links []*models.CreatorProfileLink
+	if updates.Links != nil {
+		if len(*updates.Links) > creatorMaxLinks {
+			return fmt.Errorf("a profile can have at most %d links", creatorMaxLinks)
+		}
+		for i, link := range *updates.Links {
+			label := strings.TrimSpace(link.Label)
+			if len(label) == 0 || len(label) > creatorNameMaxLength {
+				return fmt.Errorf("link label must be between 1 and %d characters", creatorNameMaxLength)
+			}
+			if err := validateCreatorLinkUrl(link.Url); err != nil {
+				return err
+			}
+			links = append(links, &models.CreatorProfileLink{
+				CreatorProfileID: creator.ID,
+				Label:            label,
+				Url:              link.Url,
+				SortOrder:        i,
+			})
+		}
+	}
+
+	// Save everything together
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+
+		// Update the profile fields
+		if len(fields) > 0 {
+			if err := tx.Model(creator).Updates(fields).Error; err != nil {
+				return err
+			}
+		}
+
+		// Point the old username at the profile, and drop any redirect from the new one
+		if len(newUsername) > 0 {
+			if err := s.deleteRedirects(tx, newUsername); err != nil {
+				return err
+			}
+			redirect := models.CreatorUsernameRedirect{
+				CreatorProfileID: creator.ID,
+				Username:         oldUsername,
+				CreatedDate:      time.Now(),
+			}
+			if err := tx.Create(&redirect).Error; err != nil {
+				return err
+			}
+		}
+
+		// Replace the links
+		if updates.Links != nil {
+			err := tx.
+				Where("creator_profile_id = ?", creator.ID).
+				Delete(&models.CreatorProfileLink{}).
+				Error
+			if err != nil {
+				return err
+			}
+			if len(links) > 0 {
+				if err := tx.Create(&links).Error; err != nil {
+					return err
+				}
+			}
+			creator.Links = links
+		}
+		return nil
+
+	})
+	return err
+
+}
+
+// DeleteCreator soft-deletes a creator profile, along with its streams, memberships, notification
+// subscriptions, API keys, and pending invites
+func (s *CreatorsService) DeleteCreator(creator *models.CreatorProfile) error {
+
+	// A profile can't be deleted in the middle of a stream
+	var liveCount int64
+	err := s.DB.
+		Model(&models.Stream{}).
+		Where("deleted_date IS NULL").
+		Where("creator_profile_id = ?", creator.ID).
+		Where("status = ? OR streaming = ?", models.StreamStatus_Live, true).
+		Count(&liveCount).
+		Error
+	if err != nil {
+		return err
+	}
+	if liveCount > 0 {
+		return errors.New("end the live stream before deleting the profile")
+	}
+
+	// Delete everything together
+	now := sql.NullTime{Valid: true, Time: time.Now()}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+
+		// Soft-delete the profile and everything hanging off of it
+		softDeletes := []struct {
+			model  interface{}
+			column string
+		}{
+			{&models.CreatorProfile{}, "id"},
+			{&models.Stream{}, "creator_profile_id"},
+			{&models.CreatorProfileMember{}, "creator_profile_id"},
+			{&models.BrowserNotifySub{}, "creator_profile_id"},
+			{&models.TelegramNotifySub{}, "creator_profile_id"},
+		}
+		for _, softDelete := range softDeletes {
+			err := tx.
+				Model(softDelete.model).
+				Where(softDelete.column+" = ?", creator.ID).
+				Where("deleted_date IS NULL").
+				Update("deleted_date", now).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Revoke the API keys and pending invites
+		err := tx.
+			Model(&models.ApiKey{}).
+			Where("creator_profile_id = ?", creator.ID).
+			Where("revoked_date IS NULL").
+			Update("revoked_date", now).
+			Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Model(&models.CreatorProfileInvite{}).
+			Where("creator_profile_id = ?", creator.ID).
+			Where("accepted_date IS NULL").
+			Where("revoked_date IS NULL").
+			Update("revoked_date", now).
+			Error
+
+	})
+}
+
+// deleteRedirects removes any redirects from the username, once a profile has taken it
+func (s *CreatorsService) deleteRedirects(tx *gorm.DB, username string) error {
+	return tx.
+		Where("LOWER(username) = ?", strings.ToLower(username)).
+		Delete(&models.CreatorUsernameRedirect{}).
+		Error
+}
+
+// validateCreatorName checks if a display name may be given to a profile
+func validateCreatorName(name string) error {
+	if len(name) == 0 || len(name) > creatorNameMaxLength {
+		return fmt.Errorf("name must be between 1 and %d characters", creatorNameMaxLength)
+	}
+	return nil
+}
+
+// validateCreatorLinkUrl checks if a URL may be linked from a profile. Only web links are allowed, so
+// that a profile can't link to scripts or other schemes
+func validateCreatorLinkUrl(link string) error {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return fmt.Errorf("invalid link URL: \"%s\"", link)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens an in-memory database for the test, with the tables for the models
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("error opening database: %s", err.Error())
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("error migrating database: %s", err.Error())
+	}
+	return db
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+// emailVerificationTokenLifetime is how long an email verification link remains valid
+const emailVerificationTokenLifetime = time.Hour * 48
+
+// EmailVerificationService issues and redeems the tokens used to verify account email addresses
+type EmailVerificationService struct {
+	DB              *gorm.DB
+	Mailer          Mailer
+	SiteUrl         string
+	AccountsService *AccountsService
+}
+
+// SendVerification issues a new verification token for the account and emails it to the account's address
+func (s *EmailVerificationService) SendVerification(account *models.Account) error {
+	token, err := issueVerificationToken(s.DB, account)
+	if err != nil {
+		return err
+	}
+	return s.sendVerificationEmail(account, token)
+}
+
+// RegisterAccount creates a new account along with its first verification token, in one transaction so
+// that an account never exists without a way to verify it, and then emails the token. The account
+// exists even if the email can't be sent, and the user can request another
+func (s *EmailVerificationService) RegisterAccount(email, password string) (*models.Account, error) {
+
+	// Create the account and its token together
+	var account *models.Account
+	var token string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		accountsService := AccountsService{DB: tx}
+		account, err = accountsService.Register(email, password)
+		if err != nil {
+			return err
+		}
+		token, err = issueVerificationToken(tx, account)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the verification email
+	if err := s.sendVerificationEmail(account, token); err != nil {
+		fmt.Println("Error sending verification email: ", err.Error())
+	}
+	return account, nil
+
+}
+
+// issueVerificationToken creates a new verification token for the account. Only the hash is stored in
+// the database, and the token itself is returned
+func issueVerificationToken(db *gorm.DB, account *models.Account) (string, error) {
+
+	// Generate the token
+	token, err := utils.SecureRandHexStr(48)
+	if err != nil {
+		return "", err
+	}
+
+	// Save the token
+	record := models.EmailVerificationToken{
+		AccountID:   account.ID,
+		TokenHash:   utils.Sha256Hex(token),
+		ExpiresDate: time.Now().Add(emailVerificationTokenLifetime),
+		CreatedDate: time.Now(),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+
+}
+
+// sendVerificationEmail emails the verification link with the token to the account's address
+func (s *EmailVerificationService) sendVerificationEmail(account *models.Account, token string) error {
+	return s.Mailer.SendMail(&Email{
+		To:      account.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Welcome! Verify your email address by opening the link below:\n\n%s/verify-email?token=%s\n\nThis link expires in 48 hours.",
+			s.SiteUrl,
+			token,
+		),
+	})
+}
+
+// Verify redeems a verification token, marking the email address on its account as verified. Every other
+// outstanding token for the account is used up with it, and tokens for verified accounts are rejected
+func (s *EmailVerificationService) Verify(token string) (*models.Account, error) {
+
+	// Find the unused token with the hash
+	var record models.EmailVerificationToken
+	err := s.DB.
+		Where("token_hash = ?", utils.Sha256Hex(token)).
+		Where("used_date IS NULL").
+		Where("expires_date > ?", time.Now()).
+		Preload("Account").
+		First(&record).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("verification link is invalid or has expired")
+		}
+		return nil, err
+	}
+	if record.Account == nil || record.Account.DeletedDate.Valid {
+		return nil, errors.New("verification link is invalid or has expired")
+	}
+	if record.Account.IsEmailVerified() {
+		return nil, errors.New("email address is already verified")
+	}
+
+	// Mark the token as used, unless a concurrent request already did
+	result := s.DB.
+		Model(&record).
+		Where("used_date IS NULL").
+		Update("used_date", sql.NullTime{Valid: true, Time: time.Now()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("verification link is invalid or has expired")
+	}
+	if err := spendVerificationTokens(s.DB, record.AccountID); err != nil {
+		return nil, err
+	}
+
+	// Mark the account as verified
+	if err := s.AccountsService.MarkEmailVerified(record.Account); err != nil {
+		return nil, err
+	}
+	return record.Account, nil
+
+}
+
+// spendVerificationTokens uses up every outstanding verification token for the account, so that old
+// links can't be redeemed once the account is verified or its password changes
+func spendVerificationTokens(db *gorm.DB, accountID uint64) error {
+	return db.
+		Model(&models.EmailVerificationToken{}).
+		Where("account_id = ?", accountID).
+		Where("used_date IS NULL").
+		Update("used_date", sql.NullTime{Valid: true, Time: time.Now()}).
+		Error
+}
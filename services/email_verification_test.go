@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+)
+
+func TestVerifySpendsEveryToken(t *testing.T) {
+	db := openTestDB(t, &models.Account{}, &models.EmailVerificationToken{})
+	s := EmailVerificationService{DB: db, AccountsService: &AccountsService{DB: db}}
+	account := &models.Account{Email: "a@example.com"}
+	db.Create(account)
+
+	// Two links were sent, and the first one verifies the account
+	first, _ := issueVerificationToken(db, account)
+	second, _ := issueVerificationToken(db, account)
+	if _, err := s.Verify(first); err != nil {
+		t.Fatalf("error verifying: %s", err.Error())
+	}
+
+	// The other link was used up with it, and new links aren't accepted once the account is verified
+	if _, err := s.Verify(second); err == nil {
+		t.Errorf("second verification link was accepted")
+	}
+	third, _ := issueVerificationToken(db, account)
+	if _, err := s.Verify(third); err == nil {
+		t.Errorf("verification link for a verified account was accepted")
+	}
+}
+
+func TestResetPasswordSpendsVerificationTokens(t *testing.T) {
+	db := openTestDB(t,
+		&models.Account{},
+		&models.EmailVerificationToken{},
+		&models.PasswordResetToken{},
+		&models.Session{},
+	)
+	accountsService := &AccountsService{DB: db}
+	s := PasswordResetService{
+		DB:              db,
+		AccountsService: accountsService,
+		SessionsService: &SessionsService{DB: db},
+	}
+	account := &models.Account{Email: "a@example.com"}
+	db.Create(account)
+	verification, _ := issueVerificationToken(db, account)
+	db.Create(&models.PasswordResetToken{
+		AccountID:   account.ID,
+		TokenHash:   utils.Sha256Hex("reset"),
+		ExpiresDate: time.Now().Add(time.Hour),
+		CreatedDate: time.Now(),
+	})
+
+	// Resetting the password verifies the account, and the old link can't log in anymore
+	if _, err := s.ResetPassword("reset", "correct horse battery"); err != nil {
+		t.Fatalf("error resetting password: %s", err.Error())
+	}
+	db.Model(account).Update("email_verified_date", nil)
+	verifier := EmailVerificationService{DB: db, AccountsService: accountsService}
+	if _, err := verifier.Verify(verification); err == nil {
+		t.Errorf("verification link sent before the password reset was accepted")
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+// ImageMaxUploadSize is the largest image file that can be uploaded, in bytes
+const ImageMaxUploadSize = 10 << 20
+
+// imageVariantSize is a size an uploaded image is resized to fit within
+type imageVariantSize struct {
+	name   string
+	width  int
+	height int
+}
+
+// creatorImageSizes are the variants of a creator profile image, which is cropped square. The first is
+// the one shown by default
+var creatorImageSizes = []imageVariantSize{
+	{"large", 512, 512},
+	{"small", 128, 128},
+}
+
+// streamThumbnailSizes are the variants of a stream thumbnail, which is cropped to 16:9. The first is
+// the one shown by default
+var streamThumbnailSizes = []imageVariantSize{
+	{"large", 1280, 720},
+	{"medium", 640, 360},
+	{"small", 320, 180},
+}
+
+// ImagesService processes uploaded images and keeps them in blob storage
+type ImagesService struct {
+	DB      *gorm.DB
+	Storage BlobStorage
+}
+
+// UploadCreatorImage replaces the image of a creator profile with an uploaded one
+func (s *ImagesService) UploadCreatorImage(
+	creator *models.CreatorProfile,
+	uploadedByAccountID uint64,
+	data []byte,
+) (*models.Image, error) {
+
+	// Process and store the image
+	record := models.Image{
+		CreatorProfileID:    creator.ID,
+		UploadedByAccountID: uploadedByAccountID,
+		Purpose:             models.ImagePurpose_CreatorImage,
+		CreatedDate:         time.Now(),
+	}
+	if err := s.storeImage(&record, data, 1, 1, creatorImageSizes); err != nil {
+		return nil, err
+	}
+
+	// Point the profile at the new image
+	creator.Image = record.Variants[0].Url
+	err := s.DB.
+		Model(creator).
+		Update("image", creator.Image).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean up the images it replaced
+	s.deleteReplacedImages(s.DB.
+		Where("creator_profile_id = ?", creator.ID).
+		Where("purpose = ?", models.ImagePurpose_CreatorImage).
+		Where("id <> ?", record.ID),
+	)
+	return &record, nil
+
+}
+
+// UploadStreamThumbnail replaces the thumbnail of a stream with an uploaded one
+func (s *ImagesService) UploadStreamThumbnail(
+	stream *models.Stream,
+	uploadedByAccountID uint64,
+	data []byte,
+) (*models.Image, error) {
+
+	// Process and store the image
+	record := models.Image{
+		CreatorProfileID:    stream.CreatorProfileID,
+		StreamID:            sql.NullInt64{Valid: true, Int64: int64(stream.ID)},
+		UploadedByAccountID: uploadedByAccountID,
+		Purpose:             models.ImagePurpose_StreamThumbnail,
+		CreatedDate:         time.Now(),
+	}
+	if err := s.storeImage(&record, data, 16, 9, streamThumbnailSizes); err != nil {
+		return nil, err
+	}
+
+	// Point the stream at the new thumbnail
+	stream.ThumbnailUrl = sql.NullString{Valid: true, String: record.Variants[0].Url}
+	err := s.DB.
+		Model(stream).
+		Update("thumbnail_url", stream.ThumbnailUrl).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean up the thumbnails it replaced
+	s.deleteReplacedImages(s.DB.
+		Where("stream_id = ?", stream.ID).
+		Where("purpose = ?", models.ImagePurpose_StreamThumbnail).
+		Where("id <> ?", record.ID),
+	)
+	return &record, nil
+
+}
+
+// storeImage decodes an uploaded image, crops it to the aspect ratio, and stores a variant for each size.
+// The image record is saved with its variants once they're all stored
+func (s *ImagesService) storeImage(
+	record *models.Image,
+	data []byte,
+	aspectWidth, aspectHeight int,
+	sizes []imageVariantSize,
+) error {
+
+	// Validate and decode the upload
+	if len(data) > ImageMaxUploadSize {
+		return fmt.Errorf("image must be at most %d MB", ImageMaxUploadSize>>20)
+	}
+	img, _, err := utils.DecodeImage(data)
+	if err != nil {
+		return err
+	}
+	img = utils.CropImageToAspect(img, aspectWidth, aspectHeight)
+	transparent := !utils.ImageIsOpaque(img)
+
+	// Convert the crop once, rather than for every variant
+	img = utils.ImageToRGBA(img)
+
+	// Generate a unique prefix for the stored files
+	prefix, err := utils.SecureRandHexStr(32)
+	if err != nil {
+		return err
+	}
+
+	// Resize, encode, and store each variant
+	bounds := img.Bounds()
+	for _, size := range sizes {
+		width, height := utils.FitImageSize(bounds.Dx(), bounds.Dy(), size.width, size.height)
+		encoded, contentType, err := utils.EncodeImage(utils.ResizeImage(img, width, height), transparent)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("images/%s/%s.%s", prefix, size.name, strings.TrimPrefix(contentType, "image/"))
+		if err := s.Storage.PutBlob(key, contentType, encoded); err != nil {
+			s.deleteVariantBlobs(record.Variants)
+			return err
+		}
+		record.Variants = append(record.Variants, &models.ImageVariant{
+			Name:        size.name,
+			StorageKey:  key,
+			Url:         s.Storage.BlobUrl(key),
+			ContentType: contentType,
+			Width:       width,
+			Height:      height,
+			Size:        len(encoded),
+		})
+	}
+
+	// Save the image and its variants
+	if err := s.DB.Create(record).Error; err != nil {
+		s.deleteVariantBlobs(record.Variants)
+		return err
+	}
+	return nil
+
+}
+
+// deleteReplacedImages deletes the images matching the query, and their stored files. Failures are only
+// logged, since the new image is already in place
+func (s *ImagesService) deleteReplacedImages(query *gorm.DB) {
+	var images []*models.Image
+	err := query.
+		Where("deleted_date IS NULL").
+		Preload("Variants").
+		Find(&images).
+		Error
+	if err != nil {
+		fmt.Println("Error finding replaced images: ", err.Error())
+		return
+	}
+	for _, image := range images {
+		s.deleteVariantBlobs(image.Variants)
+		err := s.DB.
+			Model(image).
+			Update("deleted_date", sql.NullTime{Valid: true, Time: time.Now()}).
+			Error
+		if err != nil {
+			fmt.Println("Error deleting replaced image: ", err.Error())
+		}
+	}
+}
+
+// deleteVariantBlobs deletes the stored files of image variants
+func (s *ImagesService) deleteVariantBlobs(variants []*models.ImageVariant) {
+	for _, variant := range variants {
+		if err := s.Storage.DeleteBlob(variant.StorageKey); err != nil {
+			fmt.Println("Error deleting image file: ", err.Error())
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"gorm.io/gorm"
+)
+
+// IngestHeartbeat is the state an ingest node reports about itself
+type IngestHeartbeat struct {
+	Capacity        int      `json:"capacity"`
+	ActiveStreamIDs []string `json:"active_stream_ids"`
+}
+
+// IngestNodesService tracks the ingest nodes that are online, the streams on them, and which node
+// encoders should connect to
+type IngestNodesService struct {
+	DB             *gorm.DB
+	StreamsService *StreamsService
+}
+
+// ValidateIngestUrl checks that a URL is one an encoder can stream to
+func ValidateIngestUrl(ingestUrl string) error {
+	parsed, err := url.Parse(ingestUrl)
+	if err != nil || (parsed.Scheme != "rtmp" && parsed.Scheme != "rtmps") || len(parsed.Host) == 0 {
+		return fmt.Errorf("invalid ingest URL: \"%s\"", ingestUrl)
+	}
+	return nil
+}
+
+// Heartbeat records the capacity and load reported by an ingest node. Streams the node previously
+// reported as connected that are no longer active on it are marked as disconnected, unless they
+// connected too recently to be in the heartbeat. It returns the number of streams that were disconnected
+func (s *IngestNodesService) Heartbeat(node *models.IngestNode, heartbeat *IngestHeartbeat) (int, error) {
+
+	// Validate the heartbeat
+	if heartbeat.Capacity < 0 {
+		return 0, errors.New("capacity cannot be negative")
+	}
+
+	// Update the node
+	node.Capacity = heartbeat.Capacity
+	node.ActiveStreams = len(heartbeat.ActiveStreamIDs)
+	now := time.Now()
+	node.LastHeartbeatDate = sql.NullTime{Valid: true, Time: now}
+	err := s.DB.
+		Model(node).
+		Updates(map[string]interface{}{
+			"capacity":            node.Capacity,
+			"active_streams":      node.ActiveStreams,
+			"last_heartbeat_date": node.LastHeartbeatDate,
+		}).
+		Error
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the streams the node is supposed to have
+	streams, err := s.getStreamingOnNodes([]uint64{node.ID})
+	if err != nil {
+		return 0, err
+	}
+
+	// Disconnect the ones it no longer has. Streams that connected after the heartbeat was built are
+	// left for the next one
+	active := make(map[string]bool, len(heartbeat.ActiveStreamIDs))
+	for _, id := range heartbeat.ActiveStreamIDs {
+		active[id] = true
+	}
+	connectedBefore := now.Add(-models.IngestNodeConnectGrace)
+	disconnected := 0
+	for _, stream := range streams {
+		if active[stream.Identifier] {
+			continue
+		}
+		if stream.ConnectedDate.Valid && !stream.ConnectedDate.Time.Before(connectedBefore) {
+			continue
+		}
+		if err := s.disconnectStream(stream, node.ID); err != nil {
+			return disconnected, err
+		}
+		disconnected++
+	}
+	return disconnected, nil
+
+}
+
+// DisconnectStaleNodes marks the streams on ingest nodes that stopped heartbeating, or were revoked, as
+// disconnected. Their ingest policy then applies the same as if the encoder had disconnected. It returns
+// the number of streams that were disconnected
+func (s *IngestNodesService) DisconnectStaleNodes() (int, error) {
+
+	// Find the nodes that are offline
+	var nodeIDs []uint64
+	err := s.DB.
+		Model(&models.IngestNode{}).
+		Where("last_heartbeat_date < ? OR revoked_date IS NOT NULL", time.Now().Add(-models.IngestNodeHeartbeatTimeout)).
+		Pluck("id", &nodeIDs).
+		Error
+	if err != nil {
+		return 0, err
+	}
+	if len(nodeIDs) == 0 {
+		return 0, nil
+	}
+
+	// Disconnect the streams still on them
+	streams, err := s.getStreamingOnNodes(nodeIDs)
+	if err != nil {
+		return 0, err
+	}
+	for i, stream := range streams {
+		if err := s.disconnectStream(stream, uint64(stream.IngestNodeID.Int64)); err != nil {
+			return i, err
+		}
+	}
+	return len(streams), nil
+
+}
+
+// getStreamingOnNodes gets the streams with an encoder connected to any of the ingest nodes
+func (s *IngestNodesService) getStreamingOnNodes(nodeIDs []uint64) ([]*models.Stream, error) {
+	var streams []*models.Stream
+	err := s.DB.
+		Where("deleted_date IS NULL").
+		Where("streaming = ?", true).
+		Where("ingest_node_id IN ?", nodeIDs).
+		Find(&streams).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return streams, nil
+}
+
+// disconnectStream marks a stream's encoder as disconnected from an ingest node
+func (s *IngestNodesService) disconnectStream(stream *models.Stream, nodeID uint64) error {
+	_, err := s.StreamsService.UpdateStreaming(stream, false, &StreamStatusTrigger{
+		Source:       models.StreamStatusSource_Rtmp,
+		IngestNodeID: nodeID,
+	})
+	if err != nil {
+		// The stream was changed since it was loaded, so leave it alone
+		var transitionErr *StreamTransitionError
+		if errors.As(err, &transitionErr) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// GetIngestUrls gets the URL each stream's encoder should connect to, or an empty string if there is none.
+// Streams already on an online node stay there, so that reconnects land on the same node, and the rest
+// are sent to the least loaded node with spare capacity. Finished streams don't have one
+func (s *IngestNodesService) GetIngestUrls(streams []*models.Stream) ([]string, error) {
+
+	// Get the nodes that are online
+	var nodes []*models.IngestNode
+	err := s.DB.
+		Where("revoked_date IS NULL").
+		Where("last_heartbeat_date >= ?", time.Now().Add(-models.IngestNodeHeartbeatTimeout)).
+		Where("ingest_url <> ''").
+		Find(&nodes).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the least loaded node with room for another stream
+	var recommended *models.IngestNode
+	nodesByID := make(map[uint64]*models.IngestNode, len(nodes))
+	for _, node := range nodes {
+		nodesByID[node.ID] = node
+		if !node.HasCapacity() {
+			continue
+		}
+		if recommended == nil || ingestNodeLoad(node) < ingestNodeLoad(recommended) {
+			recommended = node
+		}
+	}
+
+	// Pick the URL for each stream
+	urls := make([]string, len(streams))
+	for i, stream := range streams {
+		if stream.IsFinished() {
+			continue
+		}
+		if node := nodesByID[uint64(stream.IngestNodeID.Int64)]; stream.IngestNodeID.Valid && node != nil {
+			urls[i] = node.IngestUrl
+		} else if recommended != nil {
+			urls[i] = recommended.IngestUrl
+		}
+	}
+	return urls, nil
+
+}
+
+// ingestNodeLoad gets the fraction of an ingest node's capacity that is in use
+func ingestNodeLoad(node *models.IngestNode) float64 {
+	if node.Capacity <= 0 {
+		return 1
+	}
+	return float64(node.ActiveStreams) / float64(node.Capacity)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+)
+
+func TestIngestNodeLoad(t *testing.T) {
+	tests := []struct {
+		capacity int
+		active   int
+		expected float64
+	}{
+		{capacity: 10, active: 0, expected: 0},
+		{capacity: 10, active: 5, expected: 0.5},
+		{capacity: 4, active: 4, expected: 1},
+		{capacity: 4, active: 6, expected: 1.5},
+		{capacity: 0, active: 0, expected: 1},
+		{capacity: -1, active: 0, expected: 1},
+	}
+	for _, test := range tests {
+		node := &models.IngestNode{Capacity: test.capacity, ActiveStreams: test.active}
+		if load := ingestNodeLoad(node); load != test.expected {
+			t.Errorf("expected load %v for %d of %d, got %v", test.expected, test.active, test.capacity, load)
+		}
+	}
+}
+
+func TestGetIngestUrls(t *testing.T) {
+	s := IngestNodesService{DB: openTestDB(t, &models.IngestNode{})}
+	online := sql.NullTime{Valid: true, Time: time.Now()}
+	nodes := []*models.IngestNode{
+		{Identifier: "busy", IngestUrl: "rtmp://busy", Capacity: 10, ActiveStreams: 8, LastHeartbeatDate: online},
+		{Identifier: "idle", IngestUrl: "rtmp://idle", Capacity: 10, ActiveStreams: 2, LastHeartbeatDate: online},
+		{Identifier: "full", IngestUrl: "rtmp://full", Capacity: 1, ActiveStreams: 1, LastHeartbeatDate: online},
+		{
+			Identifier:        "offline",
+			IngestUrl:         "rtmp://offline",
+			Capacity:          10,
+			LastHeartbeatDate: sql.NullTime{Valid: true, Time: time.Now().Add(-time.Hour)},
+		},
+		{
+			Identifier:        "revoked",
+			IngestUrl:         "rtmp://revoked",
+			Capacity:          10,
+			LastHeartbeatDate: online,
+			RevokedDate:       online,
+		},
+	}
+	if err := s.DB.Create(&nodes).Error; err != nil {
+		t.Fatalf("error creating nodes: %s", err.Error())
+	}
+	onNode := func(i int) sql.NullInt64 {
+		return sql.NullInt64{Valid: true, Int64: int64(nodes[i].ID)}
+	}
+
+	streams := []*models.Stream{
+		// A new stream goes to the least loaded node
+		{Status: models.StreamStatus_Upcoming},
+		// Streams stay on the online node they are already on, even if it's busy or full
+		{Status: models.StreamStatus_Live, IngestNodeID: onNode(0)},
+		{Status: models.StreamStatus_Live, IngestNodeID: onNode(2)},
+		// Streams on a node that is offline or revoked move to the least loaded node
+		{Status: models.StreamStatus_Live, IngestNodeID: onNode(3)},
+		{Status: models.StreamStatus_Live, IngestNodeID: onNode(4)},
+		// Finished streams don't have one
+		{Status: models.StreamStatus_Ended, IngestNodeID: onNode(1)},
+		{Status: models.StreamStatus_Cancelled},
+	}
+	expected := []string{"rtmp://idle", "rtmp://busy", "rtmp://full", "rtmp://idle", "rtmp://idle", "", ""}
+	urls, err := s.GetIngestUrls(streams)
+	if err != nil {
+		t.Fatalf("error getting ingest URLs: %s", err.Error())
+	}
+	for i := range expected {
+		if urls[i] != expected[i] {
+			t.Errorf("expected stream %d to get %q, got %q", i, expected[i], urls[i])
+		}
+	}
+
+	// There is nowhere to send new streams when every node is full
+	s.DB.Model(&models.IngestNode{}).Where("1 = 1").Update("active_streams", 10)
+	urls, err = s.GetIngestUrls(streams[:1])
+	if err != nil {
+		t.Fatalf("error getting ingest URLs: %s", err.Error())
+	}
+	if urls[0] != "" {
+		t.Errorf("expected no ingest URL when every node is full, got %q", urls[0])
+	}
+}
+
+func TestHeartbeatKeepsStreamsThatJustConnected(t *testing.T) {
+	db := openTestDB(t, &models.IngestNode{}, &models.Stream{}, &models.StreamStatusChange{})
+	s := IngestNodesService{DB: db, StreamsService: &StreamsService{DB: db}}
+	node := &models.IngestNode{Identifier: "node", Capacity: 10}
+	if err := db.Create(node).Error; err != nil {
+		t.Fatalf("error creating node: %s", err.Error())
+	}
+
+	// One stream has been on the node for a while, and the other connected while the heartbeat
+	// was on its way
+	var streams []*models.Stream
+	for _, identifier := range []string{"old", "new"} {
+		stream := &models.Stream{Identifier: identifier, Status: models.StreamStatus_Live}
+		if err := db.Create(stream).Error; err != nil {
+			t.Fatalf("error creating stream: %s", err.Error())
+		}
+		_, err := s.StreamsService.UpdateStreaming(stream, true, &StreamStatusTrigger{
+			Source:       models.StreamStatusSource_Rtmp,
+			IngestNodeID: node.ID,
+		})
+		if err != nil {
+			t.Fatalf("error connecting stream: %s", err.Error())
+		}
+		streams = append(streams, stream)
+	}
+	db.Model(streams[0]).Update("connected_date", time.Now().Add(-time.Minute))
+
+	// A heartbeat without either stream only disconnects the old one
+	disconnected, err := s.Heartbeat(node, &IngestHeartbeat{Capacity: 10})
+	if err != nil {
+		t.Fatalf("error recording heartbeat: %s", err.Error())
+	}
+	if disconnected != 1 {
+		t.Errorf("expected 1 stream to be disconnected, got %d", disconnected)
+	}
+	for i, expected := range []bool{false, true} {
+		var saved models.Stream
+		db.First(&saved, streams[i].ID)
+		if saved.Streaming != expected {
+			t.Errorf("expected stream %q streaming to be %v", saved.Identifier, expected)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// ingestMaxBitrateKbps is the highest bitrate a stream or rendition may be limited to
+	ingestMaxBitrateKbps = 50000
+
+	// ingestMaxWidth and ingestMaxHeight are the largest resolution a stream or rendition may be limited to
+	ingestMaxWidth  = 7680
+	ingestMaxHeight = 4320
+
+	// ingestMaxFramerate is the highest framerate a rendition may have
+	ingestMaxFramerate = 120
+
+	// ingestMaxDurationSeconds is the longest a stream may be limited to
+	ingestMaxDurationSeconds = 48 * 60 * 60
+
+	// ingestMaxRenditions is the most renditions a transcoding ladder may have
+	ingestMaxRenditions = 8
+)
+
+// IngestVideoCodecs and IngestAudioCodecs are the codecs the RTMP server supports
+var (
+	IngestVideoCodecs = []string{"h264", "h265", "vp9", "av1"}
+	IngestAudioCodecs = []string{"aac", "mp3", "opus"}
+)
+
+// DefaultIngestConfig is the ingest config that applies when neither the stream nor its creator profile
+// has a setting
+var DefaultIngestConfig = IngestConfig{
+	MaxBitrateKbps:     6000,
+	MaxWidth:           1920,
+	MaxHeight:          1080,
+	MaxDurationSeconds: 12 * 60 * 60,
+	Record:             false,
+	AllowedVideoCodecs: []string{"h264"},
+	AllowedAudioCodecs: []string{"aac"},
+	Renditions: []models.IngestRendition{
+		{Name: "720p", Width: 1280, Height: 720, BitrateKbps: 3000, Framerate: 30},
+		{Name: "480p", Width: 854, Height: 480, BitrateKbps: 1500, Framerate: 30},
+		{Name: "360p", Width: 640, Height: 360, BitrateKbps: 800, Framerate: 30},
+	},
+}
+
+// IngestConfig is the complete set of limits and behavior the RTMP server applies to a stream
+type IngestConfig struct {
+	MaxBitrateKbps     int                      `json:"max_bitrate_kbps"`
+	MaxWidth           int                      `json:"max_width"`
+	MaxHeight          int                      `json:"max_height"`
+	MaxDurationSeconds int                      `json:"max_duration_seconds"`
+	Record             bool                     `json:"record"`
+	AllowedVideoCodecs []string                 `json:"allowed_video_codecs"`
+	AllowedAudioCodecs []string                 `json:"allowed_audio_codecs"`
+	Renditions         []models.IngestRendition `json:"renditions"`
+}
+
+// IngestSettingsValues are the ingest settings set on a creator profile or stream. Nil fields aren't set,
+// and are inherited instead. An empty list of renditions turns off transcoding
+type IngestSettingsValues struct {
+	MaxBitrateKbps     *int                     `json:"max_bitrate_kbps"`
+	MaxWidth           *int                     `json:"max_width"`
+	MaxHeight          *int                     `json:"max_height"`
+	MaxDurationSeconds *int                     `json:"max_duration_seconds"`
+	Record             *bool                    `json:"record"`
+	AllowedVideoCodecs []string                 `json:"allowed_video_codecs"`
+	AllowedAudioCodecs []string                 `json:"allowed_audio_codecs"`
+	Renditions         []models.IngestRendition `json:"renditions"`
+}
+
+// IngestSettingsService manages the ingest settings of creator profiles and streams
+type IngestSettingsService struct {
+	DB *gorm.DB
+}
+
+// GetCreatorSettings gets the ingest settings set on a creator profile
+func (s *IngestSettingsService) GetCreatorSettings(creatorID uint64) (*IngestSettingsValues, error) {
+	settings, err := s.getSettings("creator_profile_id", creatorID)
+	if err != nil {
+		return nil, err
+	}
+	return settingsToValues(settings)
+}
+
+// GetStreamSettings gets the ingest settings set on a stream
+func (s *IngestSettingsService) GetStreamSettings(streamID uint64) (*IngestSettingsValues, error) {
+	settings, err := s.getSettings("stream_id", streamID)
+	if err != nil {
+		return nil, err
+	}
+	return settingsToValues(settings)
+}
+
+// SetCreatorSettings replaces the ingest settings set on a creator profile
+func (s *IngestSettingsService) SetCreatorSettings(creatorID uint64, values *IngestSettingsValues) error {
+	return s.setSettings("creator_profile_id", creatorID, values)
+}
+
+// SetStreamSettings replaces the ingest settings set on a stream
+func (s *IngestSettingsService) SetStreamSettings(streamID uint64, values *IngestSettingsValues) error {
+	return s.setSettings("stream_id", streamID, values)
+}
+
+// ResolveCreatorConfig gets the ingest config new streams on a creator profile get. Each setting comes from
+// the creator profile if it's set there, and otherwise from the defaults
+func (s *IngestSettingsService) ResolveCreatorConfig(creatorID uint64) (*IngestConfig, error) {
+	creatorValues, err := s.GetCreatorSettings(creatorID)
+	if err != nil {
+		return nil, err
+	}
+	return resolveIngestConfig(creatorValues), nil
+}
+
+// ResolveConfig gets the ingest config for a stream. Each setting comes from the stream if it's set there,
+// and otherwise from its creator profile, and otherwise from the defaults
+func (s *IngestSettingsService) ResolveConfig(stream *models.Stream) (*IngestConfig, error) {
+	creatorValues, err := s.GetCreatorSettings(stream.CreatorProfileID)
+	if err != nil {
+		return nil, err
+	}
+	streamValues, err := s.GetStreamSettings(stream.ID)
+	if err != nil {
+		return nil, err
+	}
+	return resolveIngestConfig(creatorValues, streamValues), nil
+}
+
+// resolveIngestConfig applies levels of ingest settings over the defaults, from the least to the most specific
+func resolveIngestConfig(levels ...*IngestSettingsValues) *IngestConfig {
+
+	// Apply each level over the defaults
+	config := DefaultIngestConfig
+	for _, values := range levels {
+		if values.MaxBitrateKbps != nil {
+			config.MaxBitrateKbps = *values.MaxBitrateKbps
+		}
+		if values.MaxWidth != nil {
+			config.MaxWidth = *values.MaxWidth
+		}
+		if values.MaxHeight != nil {
+			config.MaxHeight = *values.MaxHeight
+		}
+		if values.MaxDurationSeconds != nil {
+			config.MaxDurationSeconds = *values.MaxDurationSeconds
+		}
+		if values.Record != nil {
+			config.Record = *values.Record
+		}
+		if values.AllowedVideoCodecs != nil {
+			config.AllowedVideoCodecs = values.AllowedVideoCodecs
+		}
+		if values.AllowedAudioCodecs != nil {
+			config.AllowedAudioCodecs = values.AllowedAudioCodecs
+		}
+		if values.Renditions != nil {
+			config.Renditions = values.Renditions
+		}
+	}
+
+	// Drop the renditions that exceed the limits, since the RTMP server couldn't produce them
+	renditions := make([]models.IngestRendition, 0, len(config.Renditions))
+	for _, rendition := range config.Renditions {
+		if rendition.Width > config.MaxWidth ||
+			rendition.Height > config.MaxHeight ||
+			rendition.BitrateKbps > config.MaxBitrateKbps {
+			continue
+		}
+		renditions = append(renditions, rendition)
+	}
+	config.Renditions = renditions
+	return &config
+
+}
+
+// getSettings gets the ingest settings owned by a creator profile or stream, or nil if there are none
+func (s *IngestSettingsService) getSettings(ownerColumn string, ownerID uint64) (*models.IngestSettings, error) {
+	var settings models.IngestSettings
+	err := s.DB.
+		Where(ownerColumn+" = ?", ownerID).
+		First(&settings).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// setSettings validates and saves the ingest settings owned by a creator profile or stream
+func (s *IngestSettingsService) setSettings(ownerColumn string, ownerID uint64, values *IngestSettingsValues) error {
+
+	// Validate the values
+	if err := validateIngestSettings(values); err != nil {
+		return err
+	}
+
+	// Get the existing settings, or start new ones
+	settings, err := s.getSettings(ownerColumn, ownerID)
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		settings = &models.IngestSettings{}
+		if ownerColumn == "stream_id" {
+			settings.StreamID = nullInt64(ownerID)
+		} else {
+			settings.CreatorProfileID = nullInt64(ownerID)
+		}
+	}
+
+	// Apply the values and save them
+	settings.MaxBitrateKbps = nullInt32Ptr(values.MaxBitrateKbps)
+	settings.MaxWidth = nullInt32Ptr(values.MaxWidth)
+	settings.MaxHeight = nullInt32Ptr(values.MaxHeight)
+	settings.MaxDurationSeconds = nullInt32Ptr(values.MaxDurationSeconds)
+	settings.Record = sql.NullBool{}
+	if values.Record != nil {
+		settings.Record = sql.NullBool{Valid: true, Bool: *values.Record}
+	}
+	settings.AllowedVideoCodecs = nullStringList(values.AllowedVideoCodecs)
+	settings.AllowedAudioCodecs = nullStringList(values.AllowedAudioCodecs)
+	settings.Renditions = sql.NullString{}
+	if values.Renditions != nil {
+		encoded, err := json.Marshal(values.Renditions)
+		if err != nil {
+			return err
+		}
+		settings.Renditions = sql.NullString{Valid: true, String: string(encoded)}
+	}
+	settings.UpdatedDate = time.Now()
+	return s.DB.Save(settings).Error
+
+}
+
+// validateIngestSettings checks that the ingest settings are within the limits of the RTMP server
+func validateIngestSettings(values *IngestSettingsValues) error {
+
+	// Validate the limits
+	if err := validateIngestRange("max bitrate", values.MaxBitrateKbps, 1, ingestMaxBitrateKbps); err != nil {
+		return err
+	}
+	if err := validateIngestRange("max width", values.MaxWidth, 1, ingestMaxWidth); err != nil {
+		return err
+	}
+	if err := validateIngestRange("max height", values.MaxHeight, 1, ingestMaxHeight); err != nil {
+		return err
+	}
+	if err := validateIngestRange("max duration", values.MaxDurationSeconds, 60, ingestMaxDurationSeconds); err != nil {
+		return err
+	}
+
+	// Validate the codecs
+	if err := validateIngestCodecs("video", values.AllowedVideoCodecs, IngestVideoCodecs); err != nil {
+		return err
+	}
+	if err := validateIngestCodecs("audio", values.AllowedAudioCodecs, IngestAudioCodecs); err != nil {
+		return err
+	}
+
+	// Validate the renditions
+	if len(values.Renditions) > ingestMaxRenditions {
+		return fmt.Errorf("there can be at most %d renditions", ingestMaxRenditions)
+	}
+	names := map[string]bool{}
+	for _, rendition := range values.Renditions {
+		if len(rendition.Name) == 0 || len(rendition.Name) > 32 {
+			return errors.New("rendition name must be between 1 and 32 characters")
+		}
+		if names[rendition.Name] {
+			return fmt.Errorf("duplicate rendition name: \"%s\"", rendition.Name)
+		}
+		names[rendition.Name] = true
+		if rendition.Width < 1 || rendition.Width > ingestMaxWidth ||
+			rendition.Height < 1 || rendition.Height > ingestMaxHeight {
+			return fmt.Errorf("rendition \"%s\" must be at most %dx%d", rendition.Name, ingestMaxWidth, ingestMaxHeight)
+		}
+		if rendition.BitrateKbps < 1 || rendition.BitrateKbps > ingestMaxBitrateKbps {
+			return fmt.Errorf("rendition \"%s\" bitrate must be between 1 and %d kbps", rendition.Name, ingestMaxBitrateKbps)
+		}
+		if rendition.Framerate < 1 || rendition.Framerate > ingestMaxFramerate {
+			return fmt.Errorf("rendition \"%s\" framerate must be between 1 and %d", rendition.Name, ingestMaxFramerate)
+		}
+	}
+	return nil
+
+}
+
+// validateIngestRange checks that an ingest setting is within a range, if it's set
+func validateIngestRange(name string, value *int, min, max int) error {
+	if value != nil && (*value < min || *value > max) {
+		return fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return nil
+}
+
+// validateIngestCodecs checks that a list of allowed codecs is supported, if it's set
+func validateIngestCodecs(kind string, codecs []string, supported []string) error {
+	if codecs == nil {
+		return nil
+	}
+	if len(codecs) == 0 {
+		return fmt.Errorf("at least one %s codec must be allowed", kind)
+	}
+	for _, codec := range codecs {
+		if !containsString(supported, codec) {
+			return fmt.Errorf("unsupported %s codec: \"%s\"", kind, codec)
+		}
+	}
+	return nil
+}
+
+// settingsToValues converts the stored ingest settings to their values. Missing settings have no values set
+func settingsToValues(settings *models.IngestSettings) (*IngestSettingsValues, error) {
+	values := IngestSettingsValues{}
+	if settings == nil {
+		return &values, nil
+	}
+	values.MaxBitrateKbps = nullInt32ToPtr(settings.MaxBitrateKbps)
+	values.MaxWidth = nullInt32ToPtr(settings.MaxWidth)
+	values.MaxHeight = nullInt32ToPtr(settings.MaxHeight)
+	values.MaxDurationSeconds = nullInt32ToPtr(settings.MaxDurationSeconds)
+	if settings.Record.Valid {
+		values.Record = &settings.Record.Bool
+	}
+	if settings.AllowedVideoCodecs.Valid {
+		values.AllowedVideoCodecs = strings.Fields(settings.AllowedVideoCodecs.String)
+	}
+	if settings.AllowedAudioCodecs.Valid {
+		values.AllowedAudioCodecs = strings.Fields(settings.AllowedAudioCodecs.String)
+	}
+	if settings.Renditions.Valid {
+		values.Renditions = []models.IngestRendition{}
+		if err := json.Unmarshal([]byte(settings.Renditions.String), &values.Renditions); err != nil {
+			return nil, err
+		}
+	}
+	return &values, nil
+}
+
+// nullInt32Ptr converts an optional integer to a nullable column value
+func nullInt32Ptr(value *int) sql.NullInt32 {
+	if value == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Valid: true, Int32: int32(*value)}
+}
+
+// nullInt32ToPtr converts a nullable column value to an optional integer
+func nullInt32ToPtr(value sql.NullInt32) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int32)
+	return &v
+}
+
+// nullStringList converts an optional list of strings to a nullable column value
+func nullStringList(values []string) sql.NullString {
+	if values == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{Valid: true, String: strings.Join(values, " ")}
+}
+
+// containsString checks if a slice of strings contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/connerdouglass/livestream-api/models"
+)
+
+func TestResolveIngestConfig(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	boolPtr := func(v bool) *bool { return &v }
+	rendition := func(name string, width, height, bitrate int) models.IngestRendition {
+		return models.IngestRendition{Name: name, Width: width, Height: height, BitrateKbps: bitrate, Framerate: 30}
+	}
+	tests := []struct {
+		name     string
+		creator  *IngestSettingsValues
+		stream   *IngestSettingsValues
+		expected func(config *IngestConfig)
+	}{
+		{
+			name:     "defaults",
+			creator:  &IngestSettingsValues{},
+			stream:   &IngestSettingsValues{},
+			expected: func(config *IngestConfig) {},
+		},
+		{
+			name:    "creator over defaults",
+			creator: &IngestSettingsValues{MaxBitrateKbps: intPtr(8000), Record: boolPtr(true)},
+			stream:  &IngestSettingsValues{},
+			expected: func(config *IngestConfig) {
+				config.MaxBitrateKbps = 8000
+				config.Record = true
+			},
+		},
+		{
+			name: "stream over creator",
+			creator: &IngestSettingsValues{
+				MaxBitrateKbps:     intPtr(8000),
+				Record:             boolPtr(true),
+				AllowedVideoCodecs: []string{"h264", "h265"},
+			},
+			stream: &IngestSettingsValues{
+				MaxBitrateKbps:     intPtr(9000),
+				Record:             boolPtr(false),
+				MaxDurationSeconds: intPtr(3600),
+			},
+			expected: func(config *IngestConfig) {
+				config.MaxBitrateKbps = 9000
+				config.MaxDurationSeconds = 3600
+				config.AllowedVideoCodecs = []string{"h264", "h265"}
+			},
+		},
+		{
+			name:    "empty renditions turn off transcoding",
+			creator: &IngestSettingsValues{Renditions: []models.IngestRendition{rendition("240p", 426, 240, 400)}},
+			stream:  &IngestSettingsValues{Renditions: []models.IngestRendition{}},
+			expected: func(config *IngestConfig) {
+				config.Renditions = []models.IngestRendition{}
+			},
+		},
+		{
+			name:    "default renditions over the limits are dropped",
+			creator: &IngestSettingsValues{MaxWidth: intPtr(1000), MaxHeight: intPtr(600)},
+			stream:  &IngestSettingsValues{MaxBitrateKbps: intPtr(1000)},
+			expected: func(config *IngestConfig) {
+				config.MaxWidth = 1000
+				config.MaxHeight = 600
+				config.MaxBitrateKbps = 1000
+				config.Renditions = []models.IngestRendition{rendition("360p", 640, 360, 800)}
+			},
+		},
+		{
+			name: "creator renditions over the stream limits are dropped",
+			creator: &IngestSettingsValues{
+				Renditions: []models.IngestRendition{
+					rendition("wide", 2000, 500, 1000),
+					rendition("tall", 500, 1200, 1000),
+					rendition("fast", 500, 500, 7000),
+					rendition("fits", 1920, 1080, 6000),
+				},
+			},
+			stream: &IngestSettingsValues{},
+			expected: func(config *IngestConfig) {
+				config.Renditions = []models.IngestRendition{rendition("fits", 1920, 1080, 6000)}
+			},
+		},
+	}
+	for _, test := range tests {
+		expected := DefaultIngestConfig
+		test.expected(&expected)
+		config := resolveIngestConfig(test.creator, test.stream)
+		if !reflect.DeepEqual(*config, expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, expected, *config)
+		}
+	}
+}
+
+func TestValidateIngestSettings(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	rendition := models.IngestRendition{Name: "720p", Width: 1280, Height: 720, BitrateKbps: 3000, Framerate: 30}
+	withRendition := func(edit func(r *models.IngestRendition)) *IngestSettingsValues {
+		r := rendition
+		edit(&r)
+		return &IngestSettingsValues{Renditions: []models.IngestRendition{r}}
+	}
+	tests := []struct {
+		name   string
+		values *IngestSettingsValues
+		valid  bool
+	}{
+		{name: "nothing set", values: &IngestSettingsValues{}, valid: true},
+		{
+			name: "everything at the limits",
+			values: &IngestSettingsValues{
+				MaxBitrateKbps:     intPtr(ingestMaxBitrateKbps),
+				MaxWidth:           intPtr(ingestMaxWidth),
+				MaxHeight:          intPtr(ingestMaxHeight),
+				MaxDurationSeconds: intPtr(ingestMaxDurationSeconds),
+				AllowedVideoCodecs: IngestVideoCodecs,
+				AllowedAudioCodecs: IngestAudioCodecs,
+				Renditions:         []models.IngestRendition{rendition},
+			},
+			valid: true,
+		},
+		{name: "no renditions", values: &IngestSettingsValues{Renditions: []models.IngestRendition{}}, valid: true},
+		{name: "zero bitrate", values: &IngestSettingsValues{MaxBitrateKbps: intPtr(0)}, valid: false},
+		{name: "bitrate too high", values: &IngestSettingsValues{MaxBitrateKbps: intPtr(ingestMaxBitrateKbps + 1)}, valid: false},
+		{name: "width too high", values: &IngestSettingsValues{MaxWidth: intPtr(ingestMaxWidth + 1)}, valid: false},
+		{name: "height too high", values: &IngestSettingsValues{MaxHeight: intPtr(ingestMaxHeight + 1)}, valid: false},
+		{name: "duration too short", values: &IngestSettingsValues{MaxDurationSeconds: intPtr(59)}, valid: false},
+		{name: "no video codecs", values: &IngestSettingsValues{AllowedVideoCodecs: []string{}}, valid: false},
+		{name: "unsupported audio codec", values: &IngestSettingsValues{AllowedAudioCodecs: []string{"flac"}}, valid: false},
+		{
+			name:   "too many renditions",
+			values: &IngestSettingsValues{Renditions: make([]models.IngestRendition, ingestMaxRenditions+1)},
+			valid:  false,
+		},
+		{
+			name:   "duplicate rendition names",
+			values: &IngestSettingsValues{Renditions: []models.IngestRendition{rendition, rendition}},
+			valid:  false,
+		},
+		{name: "rendition without a name", values: withRendition(func(r *models.IngestRendition) { r.Name = "" }), valid: false},
+		{name: "rendition too wide", values: withRendition(func(r *models.IngestRendition) { r.Width = ingestMaxWidth + 1 }), valid: false},
+		{name: "rendition too tall", values: withRendition(func(r *models.IngestRendition) { r.Height = ingestMaxHeight + 1 }), valid: false},
+		{
+			name:   "rendition bitrate too high",
+			values: withRendition(func(r *models.IngestRendition) { r.BitrateKbps = ingestMaxBitrateKbps + 1 }),
+			valid:  false,
+		},
+		{
+			name:   "rendition framerate too high",
+			values: withRendition(func(r *models.IngestRendition) { r.Framerate = ingestMaxFramerate + 1 }),
+			valid:  false,
+		},
+	}
+	for _, test := range tests {
+		err := validateIngestSettings(test.values)
+		if test.valid && err != nil {
+			t.Errorf("%s: expected to be valid, got %s", test.name, err.Error())
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected to be invalid", test.name)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+// inviteLifetime is how long an invitation to join a creator profile remains valid
+const inviteLifetime = time.Hour * 24 * 7
+
+// InvitesService invites people to join creator profiles as members
+type InvitesService struct {
+	DB                *gorm.DB
+	Mailer            Mailer
+	SiteUrl           string
+	AccountsService   *AccountsService
+	MembershipService *MembershipService
+}
+
+// CreateInvite invites the email address to join the creator profile with the role, and emails them
+// the link to accept it. Any earlier pending invite for the same address is replaced
+func (s *InvitesService) CreateInvite(
+	creator *models.CreatorProfile,
+	invitedByAccountID uint64,
+	email string,
+	role string,
+) (*models.CreatorProfileInvite, error) {
+
+	// Validate the email address and role
+	email = utils.NormalizeEmail(email)
+	if err := utils.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+	if !models.IsMemberRole(role) {
+		return nil, fmt.Errorf("invalid member role: \"%s\"", role)
+	}
+	if role == models.MemberRole_Owner {
+		return nil, errors.New("ownership can only be transferred")
+	}
+
+	// If the address belongs to an account that is already a member
+	account, err := s.AccountsService.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		isMember, err := s.MembershipService.IsMember(creator.ID, account.ID)
+		if err != nil {
+			return nil, err
+		}
+		if isMember {
+			return nil, errors.New("that account is already a member")
+		}
+	}
+
+	// Generate the token. Only the hash is stored in the database
+	token, err := utils.SecureRandHexStr(48)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the pending invites for the address, and send the email, together. If the email can't be
+	// sent, the new invite is never created and the previous ones are still pending
+	invite := models.CreatorProfileInvite{
+		CreatorProfileID:   creator.ID,
+		InvitedByAccountID: invitedByAccountID,
+		Email:              email,
+		Role:               role,
+		TokenHash:          utils.Sha256Hex(token),
+		ExpiresDate:        time.Now().Add(inviteLifetime),
+		CreatedDate:        time.Now(),
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+
+		// Revoke any pending invites for the address
+		err := tx.
+			Model(&models.CreatorProfileInvite{}).
+			Where("creator_profile_id = ?", creator.ID).
+			Where("email = ?", email).
+			Where("accepted_date IS NULL").
+			Where("revoked_date IS NULL").
+			Update("revoked_date", sql.NullTime{Valid: true, Time: time.Now()}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		// Save the invite
+		if err := tx.Create(&invite).Error; err != nil {
+			return err
+		}
+
+		// Send the email with the link to accept
+		return s.Mailer.SendMail(&Email{
+			To:      email,
+			Subject: fmt.Sprintf("You're invited to join %s", creator.Name),
+			Body: fmt.Sprintf(
+				"You've been invited to help manage %s. To accept the invitation, log in or create an account with this email address and open the link below:\n\n%s/invites/accept?token=%s\n\nThis link expires in 7 days.",
+				creator.Name,
+				s.SiteUrl,
+				token,
+			),
+		})
+
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+
+}
+
+// GetPendingInvites gets the invites to a creator profile that can still be accepted
+func (s *InvitesService) GetPendingInvites(creatorID uint64) ([]*models.CreatorProfileInvite, error) {
+	var invites []*models.CreatorProfileInvite
+	err := s.DB.
+		Where("creator_profile_id = ?", creatorID).
+		Where("accepted_date IS NULL").
+		Where("revoked_date IS NULL").
+		Where("expires_date > ?", time.Now()).
+		Preload("InvitedByAccount").
+		Order("created_date DESC").
+		Find(&invites).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+// RevokeInvite revokes a pending invite to a creator profile
+func (s *InvitesService) RevokeInvite(creatorID, inviteID uint64) error {
+	result := s.DB.
+		Model(&models.CreatorProfileInvite{}).
+		Where("id = ?", inviteID).
+		Where("creator_profile_id = ?", creatorID).
+		Where("accepted_date IS NULL").
+		Where("revoked_date IS NULL").
+		Update("revoked_date", sql.NullTime{Valid: true, Time: time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invite not found")
+	}
+	return nil
+}
+
+// AcceptInvite redeems an invite token, making the account a member of the creator profile. The invite
+// can only be accepted by the account with the email address it was sent to
+func (s *InvitesService) AcceptInvite(token string, account *models.Account) (*models.CreatorProfileInvite, error) {
+
+	// Find the pending invite with the hash
+	var invite models.CreatorProfileInvite
+	err := s.DB.
+		Where("token_hash = ?", utils.Sha256Hex(token)).
+		Preload("CreatorProfile").
+		First(&invite).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invitation is invalid or has expired")
+		}
+		return nil, err
+	}
+	if !invite.IsPending(time.Now()) {
+		return nil, errors.New("invitation is invalid or has expired")
+	}
+
+	// The invite must be for this account
+	if utils.NormalizeEmail(account.Email) != invite.Email {
+		return nil, errors.New("this invitation was sent to a different email address")
+	}
+
+	// Mark the invite as accepted, unless it was used in the meantime, and add the account as a member.
+	// Both happen together, so the invite is only spent if the membership is created
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&invite).
+			Where("accepted_date IS NULL").
+			Where("revoked_date IS NULL").
+			Update("accepted_date", sql.NullTime{Valid: true, Time: time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invitation is invalid or has expired")
+		}
+		membershipService := MembershipService{DB: tx}
+		return membershipService.AddMember(invite.CreatorProfileID, account.ID, invite.Role)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+)
+
+func TestAcceptInviteIsAtomic(t *testing.T) {
+	db := openTestDB(t,
+		&models.CreatorProfile{},
+		&models.CreatorProfileInvite{},
+		&models.CreatorProfileMember{},
+	)
+	s := InvitesService{
+		DB:                db,
+		MembershipService: &MembershipService{DB: db},
+	}
+	account := &models.Account{ID: 7, Email: "member@example.com"}
+
+	// Create an invite with a role the membership can't be created with
+	invite := models.CreatorProfileInvite{
+		CreatorProfileID: 1,
+		Email:            "member@example.com",
+		Role:             "nonexistent",
+		TokenHash:        utils.Sha256Hex("token"),
+		ExpiresDate:      time.Now().Add(time.Hour),
+		CreatedDate:      time.Now(),
+	}
+	db.Create(&invite)
+
+	// Accepting it fails, and leaves the invite pending
+	if _, err := s.AcceptInvite("token", account); err == nil {
+		t.Fatalf("invite with an invalid role was accepted")
+	}
+	db.First(&invite, invite.ID)
+	if !invite.IsPending(time.Now()) {
+		t.Errorf("invite was spent even though the membership wasn't created")
+	}
+
+	// Once the role is valid, the invite is accepted along with the membership
+	db.Model(&invite).Update("role", models.MemberRole_Moderator)
+	if _, err := s.AcceptInvite("token", account); err != nil {
+		t.Fatalf("error accepting invite: %s", err.Error())
+	}
+	isMember, err := s.MembershipService.IsMember(1, account.ID)
+	if err != nil || !isMember {
+		t.Errorf("accepting the invite didn't add the member")
+	}
+	if _, err := s.AcceptInvite("token", account); err == nil {
+		t.Errorf("invite was accepted twice")
+	}
+
+}
+
+// failingMailer is a mailer that can't send anything
+type failingMailer struct{}
+
+func (m *failingMailer) SendMail(email *Email) error {
+	return errors.New("mail server is down")
+}
+
+func TestCreateInviteKeepsNothingWhenMailFails(t *testing.T) {
+	db := openTestDB(t,
+		&models.Account{},
+		&models.CreatorProfileInvite{},
+		&models.CreatorProfileMember{},
+	)
+	s := InvitesService{
+		DB:                db,
+		Mailer:            &failingMailer{},
+		AccountsService:   &AccountsService{DB: db},
+		MembershipService: &MembershipService{DB: db},
+	}
+	creator := &models.CreatorProfile{ID: 1, Name: "Alice"}
+
+	// There is already a pending invite for the address
+	previous := models.CreatorProfileInvite{
+		CreatorProfileID: 1,
+		Email:            "member@example.com",
+		Role:             models.MemberRole_Moderator,
+		TokenHash:        utils.Sha256Hex("previous"),
+		ExpiresDate:      time.Now().Add(time.Hour),
+		CreatedDate:      time.Now(),
+	}
+	db.Create(&previous)
+
+	// Inviting the address again fails to send, so no new invite is left and the previous one still works
+	if _, err := s.CreateInvite(creator, 2, "member@example.com", models.MemberRole_Producer); err == nil {
+		t.Fatalf("invite was created even though the email wasn't sent")
+	}
+	var count int64
+	db.Model(&models.CreatorProfileInvite{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected only the previous invite, got %d invites", count)
+	}
+	db.First(&previous, previous.ID)
+	if !previous.IsPending(time.Now()) {
+		t.Errorf("previous invite was revoked even though the new one wasn't sent")
+	}
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+// loginThrottlePolicy describes how quickly failed login attempts lead to backoff and lockout
+type loginThrottlePolicy struct {
+	// Window is how far back failed attempts are counted
+	Window time.Duration
+
+	// FreeAttempts is the number of failures allowed before any backoff applies
+	FreeAttempts int
+
+	// MaxBackoff caps the exponential backoff between attempts
+	MaxBackoff time.Duration
+
+	// LockoutAttempts is the number of failures that locks out further attempts entirely
+	LockoutAttempts int
+
+	// LockoutDuration is how long a lockout lasts after the most recent failure
+	LockoutDuration time.Duration
+
+	// ResetOnSuccess only counts the failures since the most recent successful login. Otherwise every
+	// failure in the window is counted
+	ResetOnSuccess bool
+}
+
+// retryAfter gets how long to wait before another attempt, given the number of recent failures and
+// the time of the most recent one
+func (p *loginThrottlePolicy) retryAfter(failures int, lastFailure time.Time, now time.Time) time.Duration {
+
+	// Determine how long after the last failure the next attempt is allowed
+	var wait time.Duration
+	if failures >= p.LockoutAttempts {
+		wait = p.LockoutDuration
+	} else if failures > p.FreeAttempts {
+		wait = time.Second << uint(failures-p.FreeAttempts-1)
+		if wait > p.MaxBackoff {
+			wait = p.MaxBackoff
+		}
+	}
+
+	// Return the time remaining, if any
+	remaining := lastFailure.Add(wait).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+
+}
+
+var (
+	// accountLoginPolicy throttles guesses against a single account, from anywhere
+	accountLoginPolicy = loginThrottlePolicy{
+		Window:          time.Hour,
+		FreeAttempts:    3,
+		MaxBackoff:      time.Minute * 5,
+		LockoutAttempts: 10,
+		LockoutDuration: time.Minute * 30,
+		ResetOnSuccess:  true,
+	}
+
+	// ipLoginPolicy throttles guesses from a single IP address, against any account. Successes don't
+	// reset it, or logging in to an account of their own would let an attacker keep guessing at others
+	ipLoginPolicy = loginThrottlePolicy{
+		Window:          time.Minute * 15,
+		FreeAttempts:    20,
+		MaxBackoff:      time.Minute * 5,
+		LockoutAttempts: 50,
+		LockoutDuration: time.Minute * 30,
+	}
+
+	// streamPasswordPolicy throttles guesses at a stream's password from a single IP address
+	streamPasswordPolicy = loginThrottlePolicy{
+		Window:          time.Minute * 15,
+		FreeAttempts:    5,
+		MaxBackoff:      time.Minute * 5,
+		LockoutAttempts: 20,
+		LockoutDuration: time.Minute * 30,
+	}
+)
+
+// LoginThrottleService tracks login attempts, and slows down or locks out repeated failures
+type LoginThrottleService struct {
+	DB *gorm.DB
+}
+
+// CheckAllowed gets how long the client must wait before attempting to log in to the account with the
+// email address from the IP address. Zero means the attempt is allowed now
+func (s *LoginThrottleService) CheckAllowed(email, ipAddress string) (time.Duration, error) {
+	now := time.Now()
+
+	// Check the failures against the account
+	accountWait, err := s.checkPolicy(
+		&accountLoginPolicy,
+		s.DB.Where("email = ?", utils.NormalizeEmail(email)),
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// Check the failures from the IP address, other than guesses at stream passwords
+	ipWait, err := s.checkPolicy(
+		&ipLoginPolicy,
+		s.DB.Where("ip_address = ?", ipAddress).Where("stream_id IS NULL"),
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// Return the longer of the two
+	if ipWait > accountWait {
+		return ipWait, nil
+	}
+	return accountWait, nil
+
+}
+
+// checkPolicy counts the recent failures matching the scope, and applies the policy to them
+func (s *LoginThrottleService) checkPolicy(
+	policy *loginThrottlePolicy,
+	scope *gorm.DB,
+	now time.Time,
+) (time.Duration, error) {
+
+	// Get the recent attempts, newest first. Other results are only needed if they reset the count
+	query := s.DB.
+		Where(scope).
+		Where("created_date > ?", now.Add(-policy.Window))
+	if policy.ResetOnSuccess {
+		query = query.Where("result <> ?", models.LoginAttemptResult_Throttled)
+	} else {
+		query = query.Where("result IN ?", models.LoginAttemptFailureResults)
+	}
+	var attempts []*models.LoginAttempt
+	err := query.
+		Order("created_date DESC").
+		Limit(policy.LockoutAttempts).
+		Find(&attempts).
+		Error
+	if err != nil {
+		return 0, err
+	}
+
+	// Count the failures, stopping at the last success
+	failures := 0
+	for _, attempt := range attempts {
+		if !attempt.IsFailure() {
+			break
+		}
+		failures++
+	}
+	if failures == 0 {
+		return 0, nil
+	}
+
+	// Apply the policy
+	return policy.retryAfter(failures, attempts[0].CreatedDate, now), nil
+
+}
+
+// RecordAttempt records the result of a login attempt
+func (s *LoginThrottleService) RecordAttempt(
+	account *models.Account,
+	email string,
+	ipAddress string,
+	userAgent string,
+	result string,
+) error {
+	attempt := models.LoginAttempt{
+		Email:       utils.NormalizeEmail(email),
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Result:      result,
+		CreatedDate: time.Now(),
+	}
+	if account != nil {
+		attempt.AccountID = sql.NullInt64{Valid: true, Int64: int64(account.ID)}
+		attempt.Email = utils.NormalizeEmail(account.Email)
+	} else {
+
+		// Link the attempt to the account with the email, if there is one
+		var ids []uint64
+		err := s.DB.
+			Model(&models.Account{}).
+			Where("deleted_date IS NULL").
+			Where("LOWER(email) = ?", attempt.Email).
+			Limit(1).
+			Pluck("id", &ids).
+			Error
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			attempt.AccountID = sql.NullInt64{Valid: true, Int64: int64(ids[0])}
+		}
+
+	}
+	return s.DB.Create(&attempt).Error
+}
+
+// CheckStreamPasswordAllowed gets how long the client must wait before guessing the password of the stream
+// from the IP address again. Zero means the attempt is allowed now
+func (s *LoginThrottleService) CheckStreamPasswordAllowed(stream *models.Stream, ipAddress string) (time.Duration, error) {
+	return s.checkPolicy(
+		&streamPasswordPolicy,
+		s.DB.Where("stream_id = ?", stream.ID).Where("ip_address = ?", ipAddress),
+		time.Now(),
+	)
+}
+
+// RecordStreamPasswordFailure records an incorrect guess at the password of the stream
+func (s *LoginThrottleService) RecordStreamPasswordFailure(
+	stream *models.Stream,
+	ipAddress string,
+	userAgent string,
+) error {
+	attempt := models.LoginAttempt{
+		StreamID:    sql.NullInt64{Valid: true, Int64: int64(stream.ID)},
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Result:      models.LoginAttemptResult_BadPassword,
+		CreatedDate: time.Now(),
+	}
+	return s.DB.Create(&attempt).Error
+}
+
+// GetFailedAttemptsForAccounts gets the most recent failed or throttled login attempts on any of the
+// given accounts
+func (s *LoginThrottleService) GetFailedAttemptsForAccounts(accountIDs []uint64, limit int) ([]*models.LoginAttempt, error) {
+	var attempts []*models.LoginAttempt
+	err := s.DB.
+		Where("account_id IN (?)", accountIDs).
+		Where("result <> ?", models.LoginAttemptResult_Success).
+		Order("created_date DESC").
+		Limit(limit).
+		Find(&attempts).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+)
+
+func TestLoginThrottlePolicyRetryAfter(t *testing.T) {
+	policy := loginThrottlePolicy{
+		Window:          time.Hour,
+		FreeAttempts:    3,
+		MaxBackoff:      time.Second * 10,
+		LockoutAttempts: 10,
+		LockoutDuration: time.Minute * 30,
+	}
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name        string
+		failures    int
+		lastFailure time.Time
+		expected    time.Duration
+	}{
+		{"no failures", 0, now, 0},
+		{"free attempts", 3, now, 0},
+		{"first backoff", 4, now, time.Second},
+		{"doubled backoff", 6, now, time.Second * 4},
+		{"capped backoff", 9, now, time.Second * 10},
+		{"backoff elapsing", 6, now.Add(-time.Second * 3), time.Second},
+		{"backoff elapsed", 6, now.Add(-time.Second * 5), 0},
+		{"lockout", 10, now, time.Minute * 30},
+		{"lockout elapsing", 12, now.Add(-time.Minute * 10), time.Minute * 20},
+		{"lockout elapsed", 10, now.Add(-time.Hour), 0},
+	}
+	for _, test := range tests {
+		if wait := policy.retryAfter(test.failures, test.lastFailure, now); wait != test.expected {
+			t.Errorf("%s: expected a wait of %s, got %s", test.name, test.expected, wait)
+		}
+	}
+}
+
+func TestLoginThrottleCheckPolicy(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	policy := func(resetOnSuccess bool) *loginThrottlePolicy {
+		return &loginThrottlePolicy{
+			Window:          time.Hour,
+			FreeAttempts:    2,
+			MaxBackoff:      time.Minute,
+			LockoutAttempts: 5,
+			LockoutDuration: time.Minute * 30,
+			ResetOnSuccess:  resetOnSuccess,
+		}
+	}
+	tests := []struct {
+		name     string
+		policy   *loginThrottlePolicy
+		results  []string
+		age      time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "no attempts",
+			policy:   policy(true),
+			expected: 0,
+		},
+		{
+			name:   "backoff",
+			policy: policy(true),
+			results: []string{
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_UnknownEmail,
+				models.LoginAttemptResult_BadTotp,
+			},
+			expected: time.Second,
+		},
+		{
+			name:   "throttled attempts ignored",
+			policy: policy(true),
+			results: []string{
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_Throttled,
+				models.LoginAttemptResult_Throttled,
+			},
+			expected: 0,
+		},
+		{
+			name:   "reset by success",
+			policy: policy(true),
+			results: []string{
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_Success,
+				models.LoginAttemptResult_BadPassword,
+			},
+			expected: 0,
+		},
+		{
+			name:   "not reset by success",
+			policy: policy(false),
+			results: []string{
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_Success,
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_Unverified,
+				models.LoginAttemptResult_BadPassword,
+				models.LoginAttemptResult_Success,
+				models.LoginAttemptResult_BadPassword,
+			},
+			expected: time.Minute * 30,
+		},
+		{
+			name:   "old failures outside the window",
+			policy: policy(false),
+			results: []string{
+				models.LoginAttemptResult_BadPassword,
+			},
+			age:      time.Hour * 2,
+			expected: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := LoginThrottleService{DB: openTestDB(t, &models.LoginAttempt{})}
+
+			// Record the attempts a second apart, with the last one at the age of the test
+			start := now.Add(-test.age - time.Duration(len(test.results)-1)*time.Second)
+			for i, result := range test.results {
+				s.DB.Create(&models.LoginAttempt{
+					IPAddress:   "203.0.113.1",
+					Result:      result,
+					CreatedDate: start.Add(time.Duration(i) * time.Second),
+				})
+			}
+
+			// Check the wait
+			wait, err := s.checkPolicy(test.policy, s.DB.Where("ip_address = ?", "203.0.113.1"), now)
+			if err != nil {
+				t.Fatalf("error checking policy: %s", err.Error())
+			}
+			if wait != test.expected {
+				t.Errorf("expected a wait of %s, got %s", test.expected, wait)
+			}
+		})
+	}
+}
+
+func TestStreamPasswordFailuresDontThrottleLogins(t *testing.T) {
+	s := LoginThrottleService{DB: openTestDB(t, &models.LoginAttempt{}, &models.Account{})}
+	stream := &models.Stream{ID: 1}
+	for i := 0; i < ipLoginPolicy.LockoutAttempts; i++ {
+		if err := s.RecordStreamPasswordFailure(stream, "203.0.113.1", ""); err != nil {
+			t.Fatalf("error recording failure: %s", err.Error())
+		}
+	}
+
+	// The stream's password is locked out from the IP address, but logins aren't
+	if wait, err := s.CheckStreamPasswordAllowed(stream, "203.0.113.1"); err != nil || wait == 0 {
+		t.Errorf("expected stream password guesses to be throttled (err: %v)", err)
+	}
+	if wait, err := s.CheckAllowed("a@example.com", "203.0.113.1"); err != nil || wait != 0 {
+		t.Errorf("expected logins not to be throttled, got %s (err: %v)", wait, err)
+	}
+}
@@ -0,0 +1,14 @@
+package services
+
+// Email is a plain-text email message to be delivered to a single recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. Implementations may send them for real (SMTP) or just record them
+// somewhere for local development and tests.
+type Mailer interface {
+	SendMail(email *Email) error
+}
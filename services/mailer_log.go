@@ -0,0 +1,36 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer is a mailer for development and tests. Instead of delivering emails, it prints them to
+// stdout, and optionally writes each one to a file in Dir so they can be inspected later.
+type LogMailer struct {
+	Dir string
+}
+
+func (m *LogMailer) SendMail(email *Email) error {
+
+	// Format the email as text
+	text := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", email.To, email.Subject, email.Body)
+
+	// If there is no directory, just log the email
+	if len(m.Dir) == 0 {
+		fmt.Println("Email (not delivered):\n" + text)
+		return nil
+	}
+
+	// Make sure the directory exists
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+
+	// Write the email to a uniquely-named file
+	filename := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(m.Dir, filename), []byte(text), 0644)
+
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SmtpMailer delivers emails through an SMTP server
+type SmtpMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SmtpMailer) SendMail(email *Email) error {
+
+	// Only authenticate if credentials were configured
+	var auth smtp.Auth
+	if len(m.Username) > 0 {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// Build the raw message with the required headers
+	headers := []string{
+		fmt.Sprintf("From: %s", m.From),
+		fmt.Sprintf("To: %s", sanitizeHeader(email.To)),
+		fmt.Sprintf("Subject: %s", sanitizeHeader(email.Subject)),
+		fmt.Sprintf("Date: %s", time.Now().Format(time.RFC1123Z)),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+	}
+	message := strings.Join(headers, "\r\n") + "\r\n\r\n" + email.Body
+
+	// Send the message
+	return smtp.SendMail(
+		net.JoinHostPort(m.Host, m.Port),
+		auth,
+		m.From,
+		[]string{email.To},
+		[]byte(message),
+	)
+
+}
+
+// sanitizeHeader strips line breaks from a header value so it cannot inject extra headers
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
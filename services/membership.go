@@ -1,6 +1,9 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/connerdouglass/livestream-api/models"
@@ -73,8 +76,47 @@ func (s *MembershipService) IsMember(creatorID, accountID uint64) (bool, error)
 	return (count > 0), nil
 }
 
-// AddMember adds an account as a member to a given creator profile
-func (s *MembershipService) AddMember(creatorID, accountID uint64) error {
+// GetMember gets the active membership of an account in a creator profile, or nil if the account is
+// not a member
+func (s *MembershipService) GetMember(creatorID, accountID uint64) (*models.CreatorProfileMember, error) {
+	var member models.CreatorProfileMember
+	err := s.DB.
+		Where("deleted_date IS NULL").
+		Where("creator_profile_id = ?", creatorID).
+		Where("account_id = ?", accountID).
+		Preload("Account").
+		First(&member).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// HasPermission checks if an account is a member of a creator profile with a role that grants the
+// permission
+func (s *MembershipService) HasPermission(creatorID, accountID uint64, permission string) (bool, error) {
+	member, err := s.GetMember(creatorID, accountID)
+	if err != nil {
+		return false, err
+	}
+	return member != nil && member.HasPermission(permission), nil
+}
+
+// AddMember adds an account as a member to a given creator profile with the role. A profile only
+// ever has one owner, so ownership can only be given by transferring it
+func (s *MembershipService) AddMember(creatorID, accountID uint64, role string) error {
+
+	// Validate the role
+	if !models.IsMemberRole(role) {
+		return fmt.Errorf("invalid member role: \"%s\"", role)
+	}
+	if role == models.MemberRole_Owner {
+		return errors.New("ownership can only be transferred")
+	}
 
 	// If the user is already a member
 	isMember, err := s.IsMember(creatorID, accountID)
@@ -89,8 +131,157 @@ func (s *MembershipService) AddMember(creatorID, accountID uint64) error {
 	member := models.CreatorProfileMember{
 		CreatorProfileID: creatorID,
 		AccountID:        accountID,
+		Role:             role,
 		CreatedDate:      time.Now(),
 	}
 	return s.DB.Create(&member).Error
 
 }
+
+// SetRole changes the role of a member of the creator profile. The owner's role can't be changed, and
+// no one can be made owner this way, since ownership can only be transferred
+func (s *MembershipService) SetRole(creatorID, accountID uint64, role string) error {
+
+	// Validate the role
+	if !models.IsMemberRole(role) {
+		return fmt.Errorf("invalid member role: \"%s\"", role)
+	}
+	if role == models.MemberRole_Owner {
+		return errors.New("ownership can only be transferred")
+	}
+
+	// Get the membership
+	member, err := s.GetMember(creatorID, accountID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return errors.New("member not found")
+	}
+	if member.Role == models.MemberRole_Owner {
+		return errors.New("the owner's role can't be changed until ownership is transferred")
+	}
+
+	// Update the role
+	return s.DB.
+		Model(member).
+		Update("role", role).
+		Error
+
+}
+
+// RemoveMember removes an account from the creator profile. The owner can't be removed, so ownership
+// must be transferred first
+func (s *MembershipService) RemoveMember(creatorID, accountID uint64) error {
+
+	// Get the membership
+	member, err := s.GetMember(creatorID, accountID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return errors.New("member not found")
+	}
+	if member.Role == models.MemberRole_Owner {
+		return errors.New("the owner can't be removed until ownership is transferred")
+	}
+
+	// Soft-delete the membership
+	return s.DB.
+		Model(member).
+		Update("deleted_date", sql.NullTime{Valid: true, Time: time.Now()}).
+		Error
+
+}
+
+// TransferOwnership makes another member the owner of the creator profile. The previous owner stays on
+// as an admin
+func (s *MembershipService) TransferOwnership(creatorID, toAccountID uint64) error {
+
+	// The new owner must already be a member
+	member, err := s.GetMember(creatorID, toAccountID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return errors.New("the new owner must already be a member")
+	}
+	if member.Role == models.MemberRole_Owner {
+		return nil
+	}
+
+	// Swap the roles together, so the profile always has exactly one owner
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&models.CreatorProfileMember{}).
+			Where("deleted_date IS NULL").
+			Where("creator_profile_id = ?", creatorID).
+			Where("role = ?", models.MemberRole_Owner).
+			Update("role", models.MemberRole_Admin).
+			Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Model(member).
+			Update("role", models.MemberRole_Owner).
+			Error
+	})
+
+}
+
+// MigrateRoles gives roles to memberships created before roles existed. Those members could already do
+// everything, so they become admins, and the earliest member of each profile becomes its owner
+func (s *MembershipService) MigrateRoles() error {
+
+	// Make all of the members without a role into admins
+	err := s.DB.
+		Model(&models.CreatorProfileMember{}).
+		Where("role = '' OR role IS NULL").
+		Update("role", models.MemberRole_Admin).
+		Error
+	if err != nil {
+		return err
+	}
+
+	// Find the profiles with members but no owner
+	var creatorIDs []uint64
+	err = s.DB.
+		Model(&models.CreatorProfileMember{}).
+		Where("deleted_date IS NULL").
+		Where("creator_profile_id NOT IN (?)", s.DB.
+			Model(&models.CreatorProfileMember{}).
+			Select("creator_profile_id").
+			Where("deleted_date IS NULL").
+			Where("role = ?", models.MemberRole_Owner),
+		).
+		Distinct().
+		Pluck("creator_profile_id", &creatorIDs).
+		Error
+	if err != nil {
+		return err
+	}
+
+	// Promote the earliest member of each one
+	for _, creatorID := range creatorIDs {
+		var member models.CreatorProfileMember
+		err := s.DB.
+			Where("deleted_date IS NULL").
+			Where("creator_profile_id = ?", creatorID).
+			Order("created_date ASC, id ASC").
+			First(&member).
+			Error
+		if err != nil {
+			return err
+		}
+		err = s.DB.
+			Model(&member).
+			Update("role", models.MemberRole_Owner).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
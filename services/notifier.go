@@ -1,5 +1,14 @@
 package services
 
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+)
+
 type Notification struct {
 	Title string
 	Body  string
@@ -10,3 +19,47 @@ type Notification struct {
 type Notifier interface {
 	NotifySubscribers(creatorID uint64, notification *Notification) error
 }
+
+// NotifyStreamLive tells the subscribers of a stream's creator that the stream just went live. The
+// stream's creator profile must be loaded. Streams that aren't public are never announced. Relative
+// image URLs are resolved against publicUrl, the absolute URL of the API server
+func NotifyStreamLive(notifier Notifier, stream *models.Stream, publicUrl string) error {
+
+	// Only public streams are announced to subscribers
+	if !stream.IsPublic() {
+		return nil
+	}
+
+	// Make sure the creator is loaded
+	creator := stream.CreatorProfile
+	if creator == nil {
+		return errors.New("stream creator is not loaded")
+	}
+
+	// Use the stream's thumbnail, falling back to the creator's image
+	link := os.Getenv("TEMP_NOTIFY_LINK")
+	var image *string
+	if stream.ThumbnailUrl.Valid {
+		image = &stream.ThumbnailUrl.String
+	} else if len(creator.Image) > 0 {
+		image = &creator.Image
+	}
+
+	// Push clients load the image on their own, so it can't be relative to the API server
+	if image != nil {
+		absolute := utils.AbsoluteUrl(publicUrl, *image)
+		image = &absolute
+	}
+
+	// Send the notification
+	return notifier.NotifySubscribers(
+		stream.CreatorProfileID,
+		&Notification{
+			Title: creator.Name,
+			Body:  fmt.Sprintf("%s just went live!", creator.Name),
+			Link:  &link,
+			Image: image,
+		},
+	)
+
+}
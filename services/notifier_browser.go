@@ -165,6 +165,54 @@ func (bn *BrowserNotifier) GetVapidKeyPair() (*VapidKeyPair, error) {
 
 }
 
+// RotateVapidKeys replaces the VAPID keys with a new keypair. Browser push subscriptions are tied to the
+// public key they were created with, so every existing target and subscription is removed, and browsers
+// must subscribe again with the new key. The number of removed targets is returned
+func (bn *BrowserNotifier) RotateVapidKeys() (int64, error) {
+
+	// Get the site config
+	config, err := bn.SiteConfigService.GetSiteConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	// Generate the new keys
+	privateKey, publicKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	// Store the keys and remove the stale subscriptions together
+	var removed int64
+	err = bn.DB.Transaction(func(tx *gorm.DB) error {
+		config.VapidPublicKey = sql.NullString{Valid: true, String: publicKey}
+		config.VapidPrivateKey = sql.NullString{Valid: true, String: privateKey}
+		if err := tx.Save(config).Error; err != nil {
+			return err
+		}
+		now := sql.NullTime{Valid: true, Time: time.Now()}
+		err := tx.
+			Model(&models.BrowserNotifySub{}).
+			Where("deleted_date IS NULL").
+			Update("deleted_date", now).
+			Error
+		if err != nil {
+			return err
+		}
+		result := tx.
+			Model(&models.BrowserNotifyTarget{}).
+			Where("deleted_date IS NULL").
+			Update("deleted_date", now)
+		removed = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+
+}
+
 func (bn *BrowserNotifier) getOrCreateTarget(regData string) (*models.BrowserNotifyTarget, error) {
 
 	// Get the notify target with this registration data
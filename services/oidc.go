@@ -0,0 +1,440 @@
+package services
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"github.com/dgrijalva/jwt-go/v4"
+	"gorm.io/gorm"
+)
+
+// oidcLoginStateLifetime is how long the user has to log in at the identity provider
+const oidcLoginStateLifetime = time.Minute * 10
+
+// oidcDefaultHttpClient calls the identity provider when no other client is configured
+var oidcDefaultHttpClient = &http.Client{Timeout: time.Second * 10}
+
+// oidcDiscovery is the subset of the provider's discovery document used for logging in
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// oidcJwk is a single public key in the provider's JSON Web Key Set
+type oidcJwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// OidcIdentity is the verified identity of a user, from the ID token issued by the provider
+type OidcIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// OidcService logs users in through an external OpenID Connect identity provider, using the
+// authorization code flow with PKCE
+type OidcService struct {
+	DB              *gorm.DB
+	HttpClient      *http.Client
+	Issuer          string
+	ClientID        string
+	ClientSecret    string
+	RedirectUrl     string
+	AccountsService *AccountsService
+
+	mutex     sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+// Enabled checks if an identity provider has been configured
+func (s *OidcService) Enabled() bool {
+	return len(s.Issuer) > 0 && len(s.ClientID) > 0
+}
+
+// httpClient gets the HTTP client used to call the provider
+func (s *OidcService) httpClient() *http.Client {
+	if s.HttpClient != nil {
+		return s.HttpClient
+	}
+	return oidcDefaultHttpClient
+}
+
+// getJSON fetches a JSON document from the provider
+func (s *OidcService) getJSON(endpoint string, out interface{}) error {
+	resp, err := s.httpClient().Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("identity provider returned status %d for %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// getDiscovery gets the provider's discovery document, fetching it the first time it's needed
+func (s *OidcService) getDiscovery() (*oidcDiscovery, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// If the document was already fetched
+	if s.discovery != nil {
+		return s.discovery, nil
+	}
+
+	// Fetch the discovery document
+	var discovery oidcDiscovery
+	endpoint := strings.TrimRight(s.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := s.getJSON(endpoint, &discovery); err != nil {
+		return nil, err
+	}
+
+	// The document must be for the configured issuer
+	if discovery.Issuer != s.Issuer {
+		return nil, fmt.Errorf("identity provider issuer mismatch: \"%s\"", discovery.Issuer)
+	}
+	s.discovery = &discovery
+	return s.discovery, nil
+
+}
+
+// getKey gets the provider's public key with the key ID. The key set is fetched again when a key isn't
+// found, so that key rotation at the provider is picked up
+func (s *OidcService) getKey(discovery *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// If the key is already known
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+
+	// Fetch the key set
+	var jwks struct {
+		Keys []*oidcJwk `json:"keys"`
+	}
+	if err := s.getJSON(discovery.JwksUri, &jwks); err != nil {
+		return nil, err
+	}
+
+	// Decode all of the RSA signing keys
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (len(jwk.Use) > 0 && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	s.keys = keys
+
+	// Return the key, if it was found this time
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("identity provider has no signing key \"%s\"", kid)
+	}
+	return key, nil
+
+}
+
+// BeginLogin starts a login, and returns the URL at the provider to send the user to
+func (s *OidcService) BeginLogin() (string, error) {
+
+	// If no provider is configured
+	if !s.Enabled() {
+		return "", errors.New("external login is not configured")
+	}
+
+	// Get the provider's endpoints
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	// Generate the state, nonce, and PKCE verifier
+	state, err := utils.SecureRandHexStr(48)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.SecureRandHexStr(48)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := utils.SecureRandHexStr(64)
+	if err != nil {
+		return "", err
+	}
+
+	// Save the login state. Only the hash of the state is stored, since it's a bearer secret
+	record := models.OidcLoginState{
+		StateHash:    utils.Sha256Hex(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresDate:  time.Now().Add(oidcLoginStateLifetime),
+		CreatedDate:  time.Now(),
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	// Build the authorization URL
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", s.ClientID)
+	query.Set("redirect_uri", s.RedirectUrl)
+	query.Set("scope", "openid email")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+
+}
+
+// CompleteLogin finishes a login once the provider redirects the user back with an authorization
+// code, and returns the account the user is logged in to
+func (s *OidcService) CompleteLogin(code, state string) (*models.Account, error) {
+
+	// If no provider is configured
+	if !s.Enabled() {
+		return nil, errors.New("external login is not configured")
+	}
+
+	// Find and use up the login state
+	var record models.OidcLoginState
+	err := s.DB.
+		Where("state_hash = ?", utils.Sha256Hex(state)).
+		Where("used_date IS NULL").
+		Where("expires_date > ?", time.Now()).
+		First(&record).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("login is invalid or has expired")
+		}
+		return nil, err
+	}
+	result := s.DB.
+		Model(&record).
+		Where("used_date IS NULL").
+		Update("used_date", sql.NullTime{Valid: true, Time: time.Now()})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("login is invalid or has expired")
+	}
+
+	// Exchange the code for an ID token
+	rawIdToken, err := s.exchangeCode(code, record.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the ID token
+	identity, err := s.VerifyIdToken(rawIdToken, record.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the account for the identity
+	return s.resolveAccount(identity)
+
+}
+
+// exchangeCode exchanges an authorization code at the provider's token endpoint, and returns the raw
+// ID token
+func (s *OidcService) exchangeCode(code, verifier string) (string, error) {
+
+	// Get the provider's endpoints
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	// Send the token request
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.RedirectUrl)
+	form.Set("client_id", s.ClientID)
+	form.Set("client_secret", s.ClientSecret)
+	form.Set("code_verifier", verifier)
+	resp, err := s.httpClient().PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Decode the response
+	var body struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || len(body.Error) > 0 {
+		return "", fmt.Errorf("identity provider rejected the login: %s %s", body.Error, body.ErrorDescription)
+	}
+	if len(body.IdToken) == 0 {
+		return "", errors.New("identity provider did not return an ID token")
+	}
+	return body.IdToken, nil
+
+}
+
+// VerifyIdToken checks the signature and claims of an ID token from the provider, and returns the
+// identity in it
+func (s *OidcService) VerifyIdToken(rawIdToken, nonce string) (*OidcIdentity, error) {
+
+	// Get the provider's endpoints
+	discovery, err := s.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse and verify the token
+	token, err := jwt.Parse(
+		rawIdToken,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return s.getKey(discovery, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithAudience(s.ClientID),
+		jwt.WithIssuer(s.Issuer),
+	)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("unrecognized claims type in ID token")
+	}
+
+	// The audience, expiration, and nonce are required
+	if _, ok := claims["aud"]; !ok {
+		return nil, errors.New("ID token claims missing \"aud\" field")
+	}
+	if _, ok := claims["exp"].(float64); !ok {
+		return nil, errors.New("ID token claims missing \"exp\" field")
+	}
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	// Get the identity from the claims
+	subject, _ := claims["sub"].(string)
+	if len(subject) == 0 {
+		return nil, errors.New("ID token claims missing \"sub\" field")
+	}
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	return &OidcIdentity{
+		Issuer:        s.Issuer,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: emailVerified,
+	}, nil
+
+}
+
+// resolveAccount gets the account linked to the identity. If there is none, the identity is linked to
+// the account with the same verified email address, or a new account is created for it. An unverified
+// account with the address loses its password before it's linked, since whoever registered it may not
+// own the address
+func (s *OidcService) resolveAccount(identity *OidcIdentity) (*models.Account, error) {
+
+	// Find the account already linked to the identity
+	var link models.AccountIdentity
+	err := s.DB.
+		Where("deleted_date IS NULL").
+		Where("issuer = ?", identity.Issuer).
+		Where("subject = ?", identity.Subject).
+		Preload("Account").
+		First(&link).
+		Error
+	if err == nil {
+		if link.Account == nil || link.Account.DeletedDate.Valid {
+			return nil, errors.New("the linked account no longer exists")
+		}
+		return link.Account, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Only a verified email address can be trusted to link or create an account
+	if len(identity.Email) == 0 || !identity.EmailVerified {
+		return nil, errors.New("identity provider did not supply a verified email address")
+	}
+
+	// Find the account with the email, or create one
+	account, err := s.AccountsService.GetByEmail(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		account, err = s.AccountsService.RegisterExternal(identity.Email)
+		if err != nil {
+			return nil, err
+		}
+	} else if !account.IsEmailVerified() {
+		if err := s.AccountsService.ClaimUnverifiedAccount(account); err != nil {
+			return nil, err
+		}
+	}
+
+	// Link the identity to the account
+	link = models.AccountIdentity{
+		AccountID:   account.ID,
+		Issuer:      identity.Issuer,
+		Subject:     identity.Subject,
+		Email:       utils.NormalizeEmail(identity.Email),
+		CreatedDate: time.Now(),
+	}
+	if err := s.DB.Create(&link).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+
+}
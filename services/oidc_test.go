@@ -0,0 +1,254 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+// stubIdp is a minimal OpenID Connect provider for testing logins
+type stubIdp struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	subject string
+	email   string
+	nonce   string
+	codes   map[string]string
+}
+
+func newStubIdp(t *testing.T) *stubIdp {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %s", err.Error())
+	}
+	idp := &stubIdp{
+		key:     key,
+		subject: "user-1",
+		email:   "Creator@Example.com",
+		codes:   map[string]string{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		// The code must have been issued, and the verifier must match its challenge
+		challenge, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || challenge != base64.RawURLEncoding.EncodeToString(sum[:]) || r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken(t)})
+	})
+	idp.server = httptest.NewServer(mux)
+	return idp
+}
+
+// authorize simulates the user logging in at the provider, and returns the code and state it
+// redirects back with
+func (idp *stubIdp) authorize(t *testing.T, authorizationUrl string) (string, string) {
+	parsed, err := url.Parse(authorizationUrl)
+	if err != nil {
+		t.Fatalf("error parsing authorization URL: %s", err.Error())
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization URL missing PKCE challenge: %s", authorizationUrl)
+	}
+	code := "code-" + query.Get("state")[:8]
+	idp.codes[code] = query.Get("code_challenge")
+	idp.nonce = query.Get("nonce")
+	return code, query.Get("state")
+}
+
+// idToken signs an ID token for the current user
+func (idp *stubIdp) idToken(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            "livestream",
+		"sub":            idp.subject,
+		"email":          idp.email,
+		"email_verified": true,
+		"nonce":          idp.nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatalf("error signing ID token: %s", err.Error())
+	}
+	return signed
+}
+
+func newTestOidcService(t *testing.T, idp *stubIdp) *OidcService {
+	db := openTestDB(t,
+		&models.Account{},
+		&models.AccountIdentity{},
+		&models.OidcLoginState{},
+		&models.Session{},
+	)
+	return &OidcService{
+		DB:              db,
+		HttpClient:      idp.server.Client(),
+		Issuer:          idp.server.URL,
+		ClientID:        "livestream",
+		ClientSecret:    "secret",
+		RedirectUrl:     "http://site/login/callback",
+		AccountsService: &AccountsService{DB: db},
+	}
+}
+
+func TestOidcLogin(t *testing.T) {
+
+	// Start the stub provider
+	idp := newStubIdp(t)
+	defer idp.server.Close()
+	s := newTestOidcService(t, idp)
+
+	// The first login should create an account
+	authorizationUrl, err := s.BeginLogin()
+	if err != nil {
+		t.Fatalf("error beginning login: %s", err.Error())
+	}
+	code, state := idp.authorize(t, authorizationUrl)
+	account, err := s.CompleteLogin(code, state)
+	if err != nil {
+		t.Fatalf("error completing login: %s", err.Error())
+	}
+	if account.Email != "creator@example.com" || !account.IsEmailVerified() {
+		t.Errorf("unexpected account created: %s (verified: %t)", account.Email, account.IsEmailVerified())
+	}
+
+	// The state can't be used again
+	if _, err := s.CompleteLogin(code, state); err == nil {
+		t.Errorf("login state was accepted twice")
+	}
+
+	// A later login should find the same account through the linked identity, even if the email changes
+	idp.email = "renamed@example.com"
+	authorizationUrl, _ = s.BeginLogin()
+	code, state = idp.authorize(t, authorizationUrl)
+	again, err := s.CompleteLogin(code, state)
+	if err != nil {
+		t.Fatalf("error completing second login: %s", err.Error())
+	}
+	if again.ID != account.ID {
+		t.Errorf("second login resolved a different account: %d (expected %d)", again.ID, account.ID)
+	}
+
+	// A token with the wrong nonce should be rejected
+	authorizationUrl, _ = s.BeginLogin()
+	code, state = idp.authorize(t, authorizationUrl)
+	idp.nonce = "wrong"
+	if _, err := s.CompleteLogin(code, state); err == nil {
+		t.Errorf("ID token with the wrong nonce was accepted")
+	}
+
+}
+
+func TestOidcLinksExistingAccount(t *testing.T) {
+
+	// Start the stub provider
+	idp := newStubIdp(t)
+	defer idp.server.Close()
+	s := newTestOidcService(t, idp)
+
+	// Register an account with a password first
+	existing, err := s.AccountsService.Register("creator@example.com", "correct horse battery")
+	if err != nil {
+		t.Fatalf("error registering account: %s", err.Error())
+	}
+
+	// Logging in with the same verified email should link to it
+	authorizationUrl, _ := s.BeginLogin()
+	code, state := idp.authorize(t, authorizationUrl)
+	account, err := s.CompleteLogin(code, state)
+	if err != nil {
+		t.Fatalf("error completing login: %s", err.Error())
+	}
+	if account.ID != existing.ID {
+		t.Errorf("login created a new account instead of linking %d", existing.ID)
+	}
+
+}
+
+func TestOidcClaimsUnverifiedAccount(t *testing.T) {
+
+	// Start the stub provider
+	idp := newStubIdp(t)
+	defer idp.server.Close()
+	s := newTestOidcService(t, idp)
+
+	// Someone else registers the address with their own password, and has a session on it
+	existing, err := s.AccountsService.Register("creator@example.com", "attacker password")
+	if err != nil {
+		t.Fatalf("error registering account: %s", err.Error())
+	}
+	s.DB.Create(&models.Session{
+		AccountID:   existing.ID,
+		ExpiresDate: time.Now().Add(time.Hour),
+		CreatedDate: time.Now(),
+	})
+
+	// The owner of the address logs in through the provider
+	authorizationUrl, _ := s.BeginLogin()
+	code, state := idp.authorize(t, authorizationUrl)
+	account, err := s.CompleteLogin(code, state)
+	if err != nil {
+		t.Fatalf("error completing login: %s", err.Error())
+	}
+	if account.ID != existing.ID || !account.IsEmailVerified() {
+		t.Fatalf("login didn't verify and link the existing account")
+	}
+
+	// The password and sessions set up before the address was verified no longer work
+	reloaded, err := s.AccountsService.GetByID(existing.ID)
+	if err != nil {
+		t.Fatalf("error reloading account: %s", err.Error())
+	}
+	if reloaded.VerifyPassword("attacker password") {
+		t.Errorf("the password chosen before verification still works")
+	}
+	if reloaded.PasswordSalt == existing.PasswordSalt {
+		t.Errorf("the salt wasn't rotated, so earlier auth tokens still work")
+	}
+	var active int64
+	s.DB.Model(&models.Session{}).Where("revoked_date IS NULL").Count(&active)
+	if active != 0 {
+		t.Errorf("%d sessions from before verification are still active", active)
+	}
+
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+// passwordResetTokenLifetime is how long a password reset link remains valid
+const passwordResetTokenLifetime = time.Hour
+
+// PasswordResetService issues and redeems the tokens used to reset forgotten passwords
+type PasswordResetService struct {
+	DB              *gorm.DB
+	Mailer          Mailer
+	SiteUrl         string
+	AccountsService *AccountsService
+	SessionsService *SessionsService
+}
+
+// RequestReset emails a password reset link to the account with the email address, if there is one
+func (s *PasswordResetService) RequestReset(email string) error {
+
+	// Find the account with the email. If there is none, there's nothing to do
+	account, err := s.AccountsService.GetByEmail(email)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return nil
+	}
+
+	// Generate the token. Only the hash is stored in the database
+	token, err := utils.SecureRandHexStr(48)
+	if err != nil {
+		return err
+	}
+
+	// Save the token
+	record := models.PasswordResetToken{
+		AccountID:   account.ID,
+		TokenHash:   utils.Sha256Hex(token),
+		ExpiresDate: time.Now().Add(passwordResetTokenLifetime),
+		CreatedDate: time.Now(),
+	}
+	if err := s.DB.Create(&record).Error; err != nil {
+		return err
+	}
+
+	// Send the email with the reset link
+	return s.Mailer.SendMail(&Email{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone requested a password reset for your account. To choose a new password, open the link below:\n\n%s/reset-password?token=%s\n\nThis link expires in 1 hour. If you didn't request this, you can ignore this email.",
+			s.SiteUrl,
+			token,
+		),
+	})
+
+}
+
+// ResetPassword redeems a reset token and sets a new password on its account
+func (s *PasswordResetService) ResetPassword(token, password string) (*models.Account, error) {
+
+	// Validate the password before spending the token
+	if err := utils.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	// Find the unused token with the hash
+	var record models.PasswordResetToken
+	err := s.DB.
+		Where("token_hash = ?", utils.Sha256Hex(token)).
+		Where("used_date IS NULL").
+		Where("expires_date > ?", time.Now()).
+		Preload("Account").
+		First(&record).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reset link is invalid or has expired")
+		}
+		return nil, err
+	}
+	account := record.Account
+	if account == nil || account.DeletedDate.Valid {
+		return nil, errors.New("reset link is invalid or has expired")
+	}
+
+	// Use up the token, unless a concurrent request already did
+	now := sql.NullTime{Valid: true, Time: time.Now()}
+	result := s.DB.
+		Model(&record).
+		Where("used_date IS NULL").
+		Update("used_date", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("reset link is invalid or has expired")
+	}
+
+	// Use up every other outstanding reset token for the account
+	err = s.DB.
+		Model(&models.PasswordResetToken{}).
+		Where("account_id = ?", account.ID).
+		Where("used_date IS NULL").
+		Update("used_date", now).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Verification links sent before the reset can't be used to log in either
+	if err := spendVerificationTokens(s.DB, account.ID); err != nil {
+		return nil, err
+	}
+
+	// Set the new password, which rotates the salt and invalidates existing tokens
+	if err := s.AccountsService.UpdatePassword(account, password); err != nil {
+		return nil, err
+	}
+
+	// Log out every session, since refresh tokens would otherwise keep working
+	if err := s.SessionsService.RevokeAllSessions(account.ID); err != nil {
+		return nil, err
+	}
+
+	// Receiving the reset email proves ownership of the address
+	if !account.IsEmailVerified() {
+		if err := s.AccountsService.MarkEmailVerified(account); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+
+}
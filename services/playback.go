@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+// PlaybackTokenLifetime is how long a signed playback token is valid for. Players should request a new
+// token before it expires
+const PlaybackTokenLifetime = 6 * time.Hour
+
+// PlaybackService builds the URLs viewers play streams from
+type PlaybackService struct {
+	DB *gorm.DB
+	// HlsUrlTemplate is the URL of a stream's HLS playlist on the media server or CDN, where "{stream_id}"
+	// is replaced with the identifier of the stream. Ingest nodes may override it with their own template
+	HlsUrlTemplate string
+	// TokenSecret signs playback tokens. When it's set, playback URLs are only handed out with a signed
+	// token, which the media server or CDN verifies with the same secret
+	TokenSecret string
+}
+
+// Playback is a signed playback URL for a single viewer
+type Playback struct {
+	HlsUrl  string
+	Token   string
+	Expires time.Time
+}
+
+// ValidatePlaybackUrlTemplate checks that a playback URL template is an HTTP(S) URL that includes the
+// stream identifier
+func ValidatePlaybackUrlTemplate(template string) error {
+	parsed, err := url.Parse(template)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return errors.New("playback URL template must be an http:// or https:// URL")
+	}
+	if !strings.Contains(template, "{stream_id}") {
+		return errors.New("playback URL template must include {stream_id}")
+	}
+	return nil
+}
+
+// TokensRequired checks if playback URLs must be signed with a token
+func (s *PlaybackService) TokensRequired() bool {
+	return len(s.TokenSecret) > 0
+}
+
+// GetHlsUrl gets the URL of a stream's HLS playlist, or an empty string if the stream can't be played.
+// Only live streams can be played, and members-only and password-protected streams only with tokens.
+// Streams on an ingest node with its own template are played from it
+func (s *PlaybackService) GetHlsUrl(stream *models.Stream) (string, error) {
+	if stream.Status != models.StreamStatus_Live {
+		return "", nil
+	}
+	if (stream.MembersOnly() || stream.RequiresPassword()) && !s.TokensRequired() {
+		return "", nil
+	}
+
+	// Use the template of the node the stream is on, if it has one
+	template := s.HlsUrlTemplate
+	if stream.IngestNodeID.Valid {
+		var node models.IngestNode
+		err := s.DB.
+			Where("id = ?", stream.IngestNodeID.Int64).
+			First(&node).
+			Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		if len(node.PlaybackUrlTemplate) > 0 {
+			template = node.PlaybackUrlTemplate
+		}
+	}
+	if len(template) == 0 {
+		return "", nil
+	}
+	return strings.ReplaceAll(template, "{stream_id}", stream.Identifier), nil
+}
+
+// IssuePlayback creates a playback URL for a viewer of a live stream. When tokens are required, the URL
+// carries a token signed over the stream, the viewer, and its expiry in its "token" query parameter
+func (s *PlaybackService) IssuePlayback(stream *models.Stream, viewerID string) (*Playback, error) {
+
+	// Validate the viewer and the stream
+	if !viewerIDPattern.MatchString(viewerID) {
+		return nil, errors.New("invalid viewer ID")
+	}
+	if stream.Status != models.StreamStatus_Live {
+		return nil, ErrStreamNotLive
+	}
+
+	// Get the playback URL
+	hlsUrl, err := s.GetHlsUrl(stream)
+	if err != nil {
+		return nil, err
+	}
+	if len(hlsUrl) == 0 {
+		return nil, errors.New("playback is not configured")
+	}
+	if !s.TokensRequired() {
+		return &Playback{HlsUrl: hlsUrl}, nil
+	}
+
+	// Sign a token for the viewer, and add it to the URL
+	expires := time.Now().Add(PlaybackTokenLifetime).Truncate(time.Second)
+	token := utils.SignPlaybackToken(&utils.PlaybackToken{
+		StreamID: stream.Identifier,
+		ViewerID: viewerID,
+		Expires:  expires,
+	}, s.TokenSecret)
+	parsed, err := url.Parse(hlsUrl)
+	if err != nil {
+		return nil, err
+	}
+	query := parsed.Query()
+	query.Set("token", token)
+	parsed.RawQuery = query.Encode()
+	return &Playback{
+		HlsUrl:  parsed.String(),
+		Token:   token,
+		Expires: expires,
+	}, nil
+
+}
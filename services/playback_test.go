@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/connerdouglass/livestream-api/models"
+)
+
+func TestGetHlsUrlNeedsTokensForPrivateStreams(t *testing.T) {
+	tests := []struct {
+		visibility string
+		tokens     bool
+		playable   bool
+	}{
+		{models.StreamVisibility_Public, false, true},
+		{models.StreamVisibility_Unlisted, false, true},
+		{models.StreamVisibility_Members, false, false},
+		{models.StreamVisibility_Password, false, false},
+		{models.StreamVisibility_Members, true, true},
+		{models.StreamVisibility_Password, true, true},
+	}
+	for _, test := range tests {
+		s := PlaybackService{HlsUrlTemplate: "https://cdn.example.com/{stream_id}.m3u8"}
+		if test.tokens {
+			s.TokenSecret = "secret"
+		}
+		stream := &models.Stream{Identifier: "abc", Status: models.StreamStatus_Live, Visibility: test.visibility}
+		url, err := s.GetHlsUrl(stream)
+		if err != nil {
+			t.Fatalf("error getting HLS URL: %s", err.Error())
+		}
+		if playable := len(url) > 0; playable != test.playable {
+			t.Errorf("%s stream with tokens %v: expected playable %v, got %q", test.visibility, test.tokens, test.playable, url)
+		}
+	}
+}
@@ -1,16 +1,260 @@
 package services
 
-// RtmpAuthService manages authentication for the RTMP server when it calls into this
-// API. By design, the RTMP server (github.com/connerdouglass/livestream-rtmp) has a
-// hard-coded passcode.
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// IngestSignatureWindow is how far the timestamp of a signed ingest request may be from the current
+	// time. Captured requests can't be replayed once they fall outside of it
+	IngestSignatureWindow = 5 * time.Minute
+
+	// ingestNodeLastSeenInterval limits how often the last-seen date of a node is written
+	ingestNodeLastSeenInterval = time.Minute
+)
+
+// IngestRequest is a request from an ingest node, with the parts covered by its signature
+type IngestRequest struct {
+	NodeIdentifier string
+	Timestamp      string
+	Signature      string
+	Method         string
+	Path           string
+	Body           []byte
+	IPAddress      string
+}
+
+// IngestSignaturePayload builds the string an ingest node signs with HMAC-SHA256 using its secret. The
+// timestamp is in Unix seconds, and the path doesn't include the query string
+func IngestSignaturePayload(timestamp, method, path string, body []byte) string {
+	return strings.Join([]string{timestamp, method, path, string(body)}, "\n")
+}
+
+// RtmpAuthService manages authentication for the RTMP servers when they call into this API.
+// Each ingest node is registered with its own secret, and signs every request with it. The
+// RTMP server (github.com/connerdouglass/livestream-rtmp) was originally built with a single
+// hard-coded passcode, which is still accepted when one is configured.
 //
-// For more security later, we can validate the caller IP address, passcode, and more.
 // Remember that no matter what, the stream key must also be valid for the creator streaming.
 type RtmpAuthService struct {
+	DB                 *gorm.DB
 	RtmpServerPasscode string
 }
 
-// CheckPasscode checks if the provided passcode is valid
+// CheckPasscode checks if the provided passcode is the legacy shared passcode. No passcode is
+// valid when none is configured
 func (s *RtmpAuthService) CheckPasscode(passcode string) bool {
-	return passcode == s.RtmpServerPasscode
+	if len(s.RtmpServerPasscode) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(passcode), []byte(s.RtmpServerPasscode)) == 1
+}
+
+// FindRequestingNode gets the ingest node a request claims to be from, checking everything that can be
+// checked before the body is read: the timestamp, that the node exists, and the caller's address. The
+// signature must then be checked with VerifyNodeSignature
+func (s *RtmpAuthService) FindRequestingNode(req *IngestRequest) (*models.IngestNode, error) {
+
+	// Make sure the request is recent
+	unix, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("malformed request timestamp")
+	}
+	skew := time.Since(time.Unix(unix, 0))
+	if skew > IngestSignatureWindow || skew < -IngestSignatureWindow {
+		return nil, errors.New("request timestamp is outside of the allowed window")
+	}
+
+	// Find the node
+	var node models.IngestNode
+	err = s.DB.
+		Where("identifier = ?", req.NodeIdentifier).
+		Where("revoked_date IS NULL").
+		First(&node).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid ingest node")
+		}
+		return nil, err
+	}
+
+	// Make sure the node is calling from an allowed address
+	if cidrs := node.GetAllowedCidrs(); len(cidrs) > 0 && !utils.IPInCidrs(req.IPAddress, cidrs) {
+		return nil, errors.New("ingest node is not allowed to call from this address")
+	}
+	return &node, nil
+
+}
+
+// VerifyNodeSignature checks the signature on a request from an ingest node, including its body, and
+// records that the node was seen
+func (s *RtmpAuthService) VerifyNodeSignature(node *models.IngestNode, req *IngestRequest) error {
+
+	// Check the signature in constant time
+	payload := IngestSignaturePayload(req.Timestamp, req.Method, req.Path, req.Body)
+	expected := utils.HmacSha256(payload, node.Secret)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(req.Signature))) {
+		return errors.New("invalid request signature")
+	}
+
+	// Record that the node was seen
+	now := time.Now()
+	if !node.LastSeenDate.Valid || now.Sub(node.LastSeenDate.Time) >= ingestNodeLastSeenInterval {
+		node.LastSeenDate = sql.NullTime{Valid: true, Time: now}
+		err := s.DB.
+			Model(node).
+			Update("last_seen_date", node.LastSeenDate).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
+
+// CreateNode registers a new ingest node, which encoders connect to at the ingest URL. The playback URL
+// template is optional. The secret the node signs its requests with is returned along with it, and
+// isn't shown again
+func (s *RtmpAuthService) CreateNode(
+	name string,
+	ingestUrl string,
+	playbackUrlTemplate string,
+	allowedCidrs []string,
+) (*models.IngestNode, string, error) {
+
+	// Validate the name, URLs, and address ranges
+	name = strings.TrimSpace(name)
+	if len(name) == 0 || len(name) > 64 {
+		return nil, "", errors.New("ingest node name must be between 1 and 64 characters")
+	}
+	if err := ValidateIngestUrl(ingestUrl); err != nil {
+		return nil, "", err
+	}
+	if len(playbackUrlTemplate) > 0 {
+		if err := ValidatePlaybackUrlTemplate(playbackUrlTemplate); err != nil {
+			return nil, "", err
+		}
+	}
+	if _, err := utils.ParseCidrs(allowedCidrs); err != nil {
+		return nil, "", err
+	}
+
+	// Generate the identifier and secret
+	identifier, err := utils.SecureRandHexStr(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := utils.SecureRandHexStr(64)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Create the node record
+	node := models.IngestNode{
+		Name:                name,
+		Identifier:          identifier,
+		Secret:              secret,
+		AllowedCidrs:        strings.Join(allowedCidrs, " "),
+		IngestUrl:           ingestUrl,
+		PlaybackUrlTemplate: playbackUrlTemplate,
+		CreatedDate:         time.Now(),
+	}
+	if err := s.DB.Create(&node).Error; err != nil {
+		return nil, "", err
+	}
+	return &node, secret, nil
+
+}
+
+// GetNodes gets all of the ingest nodes that haven't been revoked
+func (s *RtmpAuthService) GetNodes() ([]*models.IngestNode, error) {
+	var nodes []*models.IngestNode
+	err := s.DB.
+		Where("revoked_date IS NULL").
+		Order("created_date ASC").
+		Find(&nodes).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// SetNodePlaybackUrlTemplate sets the URL template that streams on an ingest node are played from
+func (s *RtmpAuthService) SetNodePlaybackUrlTemplate(nodeID uint64, template string) (*models.IngestNode, error) {
+
+	// Validate the template. An empty template plays the node's streams from the default
+	if len(template) > 0 {
+		if err := ValidatePlaybackUrlTemplate(template); err != nil {
+			return nil, err
+		}
+	}
+
+	// Find the node
+	var node models.IngestNode
+	err := s.DB.
+		Where("id = ?", nodeID).
+		Where("revoked_date IS NULL").
+		First(&node).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ingest node not found")
+		}
+		return nil, err
+	}
+
+	// Update the template
+	node.PlaybackUrlTemplate = template
+	err = s.DB.
+		Model(&node).
+		Update("playback_url_template", template).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+
+}
+
+// RevokeNode revokes an ingest node, so it can no longer call into the API
+func (s *RtmpAuthService) RevokeNode(nodeID uint64) (*models.IngestNode, error) {
+
+	// Find the node
+	var node models.IngestNode
+	err := s.DB.
+		Where("id = ?", nodeID).
+		Where("revoked_date IS NULL").
+		First(&node).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ingest node not found")
+		}
+		return nil, err
+	}
+
+	// Revoke it
+	node.RevokedDate = sql.NullTime{Valid: true, Time: time.Now()}
+	err = s.DB.
+		Model(&node).
+		Update("revoked_date", node.RevokedDate).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+
 }
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// accessTokenLifetime is how long an access token can be used before it must be refreshed
+	accessTokenLifetime = time.Minute * 15
+
+	// sessionLifetime is how long a session stays alive without being refreshed
+	sessionLifetime = time.Hour * 24 * 30
+
+	// impersonationSessionLifetime is how long a session started by an admin impersonating an account
+	// stays alive. These sessions are never extended by refreshing
+	impersonationSessionLifetime = time.Hour
+
+	// sessionLastSeenInterval limits how often the last-seen date of a session is written
+	sessionLastSeenInterval = time.Minute
+)
+
+// SessionClient describes the device a session is used from
+type SessionClient struct {
+	Device    string
+	IPAddress string
+	UserAgent string
+}
+
+// SessionTokens is the set of tokens issued when a session is created or refreshed
+type SessionTokens struct {
+	Session            *models.Session
+	AccessToken        string
+	AccessTokenExpires time.Time
+	RefreshToken       string
+}
+
+// SessionsService manages the login sessions of accounts
+type SessionsService struct {
+	DB                *gorm.DB
+	AuthTokensService *AuthTokensService
+}
+
+// CreateSession starts a new session for the account, and issues its first tokens
+func (s *SessionsService) CreateSession(account *models.Account, client *SessionClient) (*SessionTokens, error) {
+	return s.createSession(account, nil, sessionLifetime, client)
+}
+
+// CreateImpersonationSession starts a short-lived session on the account for an admin. The session
+// records the admin, so everything done with it can be traced back to them
+func (s *SessionsService) CreateImpersonationSession(
+	account *models.Account,
+	admin *models.Account,
+	client *SessionClient,
+) (*SessionTokens, error) {
+	return s.createSession(account, admin, impersonationSessionLifetime, client)
+}
+
+// createSession starts a new session on the account, optionally on behalf of an impersonating admin
+func (s *SessionsService) createSession(
+	account *models.Account,
+	impersonator *models.Account,
+	lifetime time.Duration,
+	client *SessionClient,
+) (*SessionTokens, error) {
+
+	// Disabled accounts can't start new sessions
+	if account.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	// Generate the refresh token. Only the hash is stored in the database
+	refreshToken, err := utils.SecureRandHexStr(64)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the session
+	now := time.Now()
+	session := models.Session{
+		AccountID:        account.ID,
+		RefreshTokenHash: utils.Sha256Hex(refreshToken),
+		Device:           client.Device,
+		IPAddress:        client.IPAddress,
+		UserAgent:        client.UserAgent,
+		LastSeenDate:     now,
+		ExpiresDate:      now.Add(lifetime),
+		CreatedDate:      now,
+	}
+	if impersonator != nil {
+		session.ImpersonatorAccountID = sql.NullInt64{Valid: true, Int64: int64(impersonator.ID)}
+	}
+	if err := s.DB.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	// Issue an access token for the session
+	return s.issueTokens(account, &session, refreshToken)
+
+}
+
+// RefreshSession exchanges a refresh token for a new access token. The refresh token is rotated, so
+// the old one cannot be used again. Presenting a refresh token that was already rotated out revokes the
+// session, since either the client or whoever copied the token is using a stale one
+func (s *SessionsService) RefreshSession(refreshToken string, client *SessionClient) (*SessionTokens, error) {
+
+	// Find the session with the refresh token, current or retired
+	refreshTokenHash := utils.Sha256Hex(refreshToken)
+	var session models.Session
+	err := s.DB.
+		Where("refresh_token_hash = ? OR previous_refresh_token_hash = ?", refreshTokenHash, refreshTokenHash).
+		Preload("Account").
+		First(&session).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session is invalid or has expired")
+		}
+		return nil, err
+	}
+
+	// A retired refresh token was reused, so revoke the session
+	if session.RefreshTokenHash != refreshTokenHash {
+		if err := s.revokeReusedSession(&session); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("session is invalid or has expired")
+	}
+	if !session.IsActive(time.Now()) || session.Account == nil || session.Account.DeletedDate.Valid {
+		return nil, errors.New("session is invalid or has expired")
+	}
+	if session.Account.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	// Rotate the refresh token and extend the session
+	newRefreshToken, err := utils.SecureRandHexStr(64)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session.PreviousRefreshTokenHash = refreshTokenHash
+	session.RefreshTokenHash = utils.Sha256Hex(newRefreshToken)
+	session.IPAddress = client.IPAddress
+	session.UserAgent = client.UserAgent
+	session.LastSeenDate = now
+	if !session.IsImpersonation() {
+		session.ExpiresDate = now.Add(sessionLifetime)
+	}
+
+	// Only rotate if the token hasn't been rotated since it was read. If it has, the same refresh token
+	// was used twice, which means it may have been stolen, so the whole session is revoked
+	result := s.DB.
+		Model(&session).
+		Where("refresh_token_hash = ?", refreshTokenHash).
+		Select(
+			"refresh_token_hash",
+			"previous_refresh_token_hash",
+			"ip_address",
+			"user_agent",
+			"last_seen_date",
+			"expires_date",
+		).
+		Updates(&session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := s.revokeReusedSession(&session); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("session is invalid or has expired")
+	}
+
+	// Issue a new access token for the session
+	return s.issueTokens(session.Account, &session, newRefreshToken)
+
+}
+
+// revokeReusedSession revokes a session whose refresh token was used more than once
+func (s *SessionsService) revokeReusedSession(session *models.Session) error {
+	return s.DB.
+		Model(&models.Session{}).
+		Where("id = ?", session.ID).
+		Update("revoked_date", sql.NullTime{Valid: true, Time: time.Now()}).
+		Error
+}
+
+// issueTokens creates an access token for the session, and bundles it with the refresh token
+func (s *SessionsService) issueTokens(
+	account *models.Account,
+	session *models.Session,
+	refreshToken string,
+) (*SessionTokens, error) {
+	now := time.Now()
+	expires := now.Add(accessTokenLifetime)
+	accessToken, err := s.AuthTokensService.CreateToken(account, session, now, expires)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{
+		Session:            session,
+		AccessToken:        accessToken,
+		AccessTokenExpires: expires,
+		RefreshToken:       refreshToken,
+	}, nil
+}
+
+// GetActiveSessions gets the sessions on an account that haven't expired or been revoked
+func (s *SessionsService) GetActiveSessions(accountID uint64) ([]*models.Session, error) {
+	var sessions []*models.Session
+	err := s.DB.
+		Where("account_id = ?", accountID).
+		Where("revoked_date IS NULL").
+		Where("expires_date > ?", time.Now()).
+		Order("last_seen_date DESC").
+		Find(&sessions).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes a session on an account, so its tokens can no longer be used
+func (s *SessionsService) RevokeSession(accountID, sessionID uint64) error {
+	result := s.DB.
+		Model(&models.Session{}).
+		Where("id = ?", sessionID).
+		Where("account_id = ?", accountID).
+		Where("revoked_date IS NULL").
+		Update("revoked_date", sql.NullTime{Valid: true, Time: time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every session on an account
+func (s *SessionsService) RevokeAllSessions(accountID uint64) error {
+	return s.DB.
+		Model(&models.Session{}).
+		Where("account_id = ?", accountID).
+		Where("revoked_date IS NULL").
+		Update("revoked_date", sql.NullTime{Valid: true, Time: time.Now()}).
+		Error
+}
+
+// TouchSession records that a session was just used. Writes are throttled, so this is cheap to call
+// on every request
+func (s *SessionsService) TouchSession(session *models.Session) error {
+	now := time.Now()
+	if now.Sub(session.LastSeenDate) < sessionLastSeenInterval {
+		return nil
+	}
+	session.LastSeenDate = now
+	return s.DB.
+		Model(session).
+		Update("last_seen_date", now).
+		Error
+}
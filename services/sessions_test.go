@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/connerdouglass/livestream-api/models"
+)
+
+func TestRefreshSessionRevokesOnReplay(t *testing.T) {
+	db := openTestDB(t, &models.Account{}, &models.Session{})
+	s := SessionsService{DB: db, AuthTokensService: &AuthTokensService{DB: db, SigningPepper: "pepper"}}
+	account := &models.Account{Email: "a@example.com", PasswordSalt: "salt"}
+	db.Create(account)
+	client := &SessionClient{}
+	tokens, err := s.CreateSession(account, client)
+	if err != nil {
+		t.Fatalf("error creating session: %s", err.Error())
+	}
+
+	// Refresh once, which rotates the refresh token
+	rotated, err := s.RefreshSession(tokens.RefreshToken, client)
+	if err != nil {
+		t.Fatalf("error refreshing session: %s", err.Error())
+	}
+
+	// Replaying the retired token later fails, and revokes the session for whoever holds the new one
+	if _, err := s.RefreshSession(tokens.RefreshToken, client); err == nil {
+		t.Errorf("retired refresh token was accepted")
+	}
+	if _, err := s.RefreshSession(rotated.RefreshToken, client); err == nil {
+		t.Errorf("session wasn't revoked after the retired refresh token was replayed")
+	}
+	var session models.Session
+	db.First(&session, tokens.Session.ID)
+	if !session.RevokedDate.Valid {
+		t.Errorf("session wasn't revoked")
+	}
+}
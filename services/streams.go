@@ -11,9 +11,27 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	// streamDisconnectGraceDefault is how long a new stream's encoder has to reconnect, in seconds
+	streamDisconnectGraceDefault = 60
+
+	// streamDisconnectGraceMax is the longest grace period a stream can have, in seconds
+	streamDisconnectGraceMax = 60 * 60
+
+	// streamKeyLength is the number of hex characters in a stream key
+	streamKeyLength = 32
+
+	// streamPasswordMinLength and streamPasswordMaxLength are the limits on the length of the password
+	// of a password-protected stream
+	streamPasswordMinLength = 4
+	streamPasswordMaxLength = 128
+)
+
 // StreamsService manages the streams in the system
 type StreamsService struct {
 	DB *gorm.DB
+	// PlaybackService is used to check that private streams can only be played with a playback token
+	PlaybackService *PlaybackService
 }
 
 type CreateStreamOptions struct {
@@ -40,13 +58,15 @@ func (s *StreamsService) CreateStream(
 
 	// Create the stream
 	stream := models.Stream{
-		CreatorProfileID:   creator.ID,
-		Identifier:         identifier,
-		Title:              options.Title,
-		StreamKey:          streamKey,
-		Status:             models.StreamStatus_Upcoming,
-		ScheduledStartDate: options.ScheduledStartDate,
-		CreatedDate:        time.Now(),
+		CreatorProfileID:       creator.ID,
+		Identifier:             identifier,
+		Title:                  options.Title,
+		StreamKey:              streamKey,
+		Status:                 models.StreamStatus_Upcoming,
+		IngestPolicy:           models.StreamIngestPolicy_Manual,
+		DisconnectGraceSeconds: streamDisconnectGraceDefault,
+		ScheduledStartDate:     options.ScheduledStartDate,
+		CreatedDate:            time.Now(),
 	}
 	if err := s.DB.Create(&stream).Error; err != nil {
 		return nil, err
@@ -135,68 +155,372 @@ func (s *StreamsService) GenerateUnusedIdentifier() (string, error) {
 	return "", errors.New("GenerateUnusedIdentifier exceeded max attempts")
 }
 
+// GenerateUnusedStreamKey generates a random stream key that no stream or creator is using
 func (s *StreamsService) GenerateUnusedStreamKey() (string, error) {
-	maxAttempts := 1000
+	maxAttempts := 10
 	for i := 0; i < maxAttempts; i++ {
-		streamKey := utils.RandHexStrInt64()
+		streamKey, err := utils.SecureRandHexStr(streamKeyLength)
+		if err != nil {
+			return "", err
+		}
 		stream, err := s.GetStreamByStreamKey(streamKey)
 		if err != nil {
 			return "", err
 		}
-		if stream == nil {
+		creator, err := s.getCreatorByStreamKey(streamKey)
+		if err != nil {
+			return "", err
+		}
+		if stream == nil && creator == nil {
 			return streamKey, nil
 		}
 	}
 	return "", errors.New("GenerateUnusedStreamKey exceeded max attempts")
 }
 
-func (s *StreamsService) UpdateStreaming(stream *models.Stream, streaming bool) error {
-	stream.Streaming = streaming
+// getCreatorByStreamKey gets the creator with the persistent stream key
+func (s *StreamsService) getCreatorByStreamKey(streamKey string) (*models.CreatorProfile, error) {
+	var creator models.CreatorProfile
+	err := s.DB.
+		Where("stream_key = ?", streamKey).
+		Where("deleted_date IS NULL").
+		First(&creator).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &creator, nil
+}
+
+// ResolveStreamKey finds the stream that an encoder connecting with the key streams to. A stream's own
+// key always maps to that stream, even if it has finished. A creator's persistent key maps to the
+// creator's live stream, or else their next upcoming one
+func (s *StreamsService) ResolveStreamKey(streamKey string) (*models.Stream, error) {
+
+	// Empty keys never match anything
+	if len(streamKey) == 0 {
+		return nil, nil
+	}
+
+	// Check for a stream with the key
+	stream, err := s.GetStreamByStreamKey(streamKey)
+	if err != nil || stream != nil {
+		return stream, err
+	}
+
+	// Check for a creator with the key
+	creator, err := s.getCreatorByStreamKey(streamKey)
+	if err != nil || creator == nil {
+		return nil, err
+	}
+	stream, err = s.GetLiveStreamForCreator(creator)
+	if err != nil || stream != nil {
+		return stream, err
+	}
+	return s.GetNextStreamForCreator(creator)
+
+}
+
+// RotateStreamKey replaces the key of a stream, so the old key can no longer be used to stream. Streams
+// that have finished can't be streamed to anyway, so their keys aren't rotated
+func (s *StreamsService) RotateStreamKey(stream *models.Stream) error {
+	if stream.IsFinished() {
+		return errors.New("the stream has already finished")
+	}
+	streamKey, err := s.GenerateUnusedStreamKey()
+	if err != nil {
+		return err
+	}
+	err = s.DB.
+		Model(stream).
+		Update("stream_key", streamKey).
+		Error
+	if err != nil {
+		return err
+	}
+	stream.StreamKey = streamKey
+	return nil
+}
+
+// RotateCreatorStreamKey gives a creator a new persistent stream key, replacing their old one if they had
+// one. Encoders using the key stream to whichever of the creator's streams is live or upcoming next
+func (s *StreamsService) RotateCreatorStreamKey(creator *models.CreatorProfile) error {
+	streamKey, err := s.GenerateUnusedStreamKey()
+	if err != nil {
+		return err
+	}
+	creator.StreamKey = sql.NullString{Valid: true, String: streamKey}
+	return s.DB.
+		Model(creator).
+		Update("stream_key", creator.StreamKey).
+		Error
+}
+
+// RevokeCreatorStreamKey removes a creator's persistent stream key
+func (s *StreamsService) RevokeCreatorStreamKey(creator *models.CreatorProfile) error {
+	creator.StreamKey = sql.NullString{}
+	return s.DB.
+		Model(creator).
+		Update("stream_key", creator.StreamKey).
+		Error
+}
+
+// StreamStatusTrigger describes what caused a stream's status to change. Zero identifiers mean the
+// field doesn't apply
+type StreamStatusTrigger struct {
+	Source       string
+	AccountID    uint64
+	ApiKeyID     uint64
+	IngestNodeID uint64
+}
+
+// StreamTransitionError is returned when a stream can't move from its current status to another one
+type StreamTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *StreamTransitionError) Error() string {
+	return fmt.Sprintf("a stream that is %s cannot become %s", e.From, e.To)
+}
+
+// ErrStreamFinished is returned when an encoder connects to a stream that has ended or was cancelled
+var ErrStreamFinished = errors.New("stream has already finished")
+
+// UpdateStreaming records whether the encoder is connected to a stream, and applies the stream's ingest
+// policy. When an ingest node reports the connection, the stream is recorded as being on that node, and
+// disconnects reported by any other node are ignored, since the encoder has since moved. Finished streams
+// can't be connected to. It reports whether the stream just went live, so that subscribers can be notified
+func (s *StreamsService) UpdateStreaming(
+	stream *models.Stream,
+	streaming bool,
+	trigger *StreamStatusTrigger,
+) (bool, error) {
+
+	// Encoders can't connect to streams that are over
+	if streaming && stream.IsFinished() {
+		return false, ErrStreamFinished
+	}
+
+	// Update the streaming flag, and remember when the encoder disconnected
+	disconnectedDate := sql.NullTime{}
 	if !streaming {
-		stream.Status = models.StreamStatus_Ended
+		disconnectedDate = sql.NullTime{Valid: true, Time: time.Now()}
+	}
+	updates := map[string]interface{}{
+		"streaming":         streaming,
+		"disconnected_date": disconnectedDate,
+	}
+	ingestNodeID := stream.IngestNodeID
+	connectedDate := stream.ConnectedDate
+	if streaming {
+		ingestNodeID = nullInt64(trigger.IngestNodeID)
+		connectedDate = sql.NullTime{Valid: true, Time: time.Now()}
+		updates["ingest_node_id"] = ingestNodeID
+		updates["connected_date"] = connectedDate
+	}
+	query := s.DB.Model(stream)
+	if !streaming && trigger.IngestNodeID != 0 {
+		query = query.Where("ingest_node_id IS NULL OR ingest_node_id = ?", trigger.IngestNodeID)
+	}
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	stream.Streaming = streaming
+	stream.ConnectedDate = connectedDate
+	stream.DisconnectedDate = disconnectedDate
+	stream.IngestNodeID = ingestNodeID
+
+	// Go live when the encoder connects, if the policy allows it
+	if streaming && stream.GoesLiveOnIngest() && stream.Status == models.StreamStatus_Upcoming {
+		if err := s.transitionStatus(stream, models.StreamStatus_Live, trigger); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	// End the stream when the encoder disconnects, if the policy allows it and there is no grace
+	// period. Otherwise it's ended later by EndDisconnectedStreams
+	if !streaming &&
+		stream.EndsOnDisconnect() &&
+		stream.Status == models.StreamStatus_Live &&
+		stream.DisconnectGracePeriod() <= 0 {
+		return false, s.transitionStatus(stream, models.StreamStatus_Ended, trigger)
+	}
+	return false, nil
+
+}
+
+// EndDisconnectedStreams ends the live streams whose encoder has been disconnected for longer than the
+// grace period, if their policy allows it. It returns the number of streams that were ended
+func (s *StreamsService) EndDisconnectedStreams() (int, error) {
+
+	// Find the streams waiting for their encoder to reconnect
+	var streams []*models.Stream
+	err := s.DB.
+		Where("deleted_date IS NULL").
+		Where("status = ?", models.StreamStatus_Live).
+		Where("streaming = ?", false).
+		Where("ingest_policy IN ?", []string{
+			models.StreamIngestPolicy_AutoLiveEnd,
+			models.StreamIngestPolicy_AutoEnd,
+		}).
+		Where("disconnected_date IS NOT NULL").
+		Find(&streams).
+		Error
+	if err != nil {
+		return 0, err
+	}
+
+	// End the streams whose grace period is over
+	now := time.Now()
+	ended := 0
+	for _, stream := range streams {
+		if now.Before(stream.DisconnectedDate.Time.Add(stream.DisconnectGracePeriod())) {
+			continue
+		}
+		err := s.transitionStatus(stream, models.StreamStatus_Ended, &StreamStatusTrigger{
+			Source: models.StreamStatusSource_Rtmp,
+		})
+		if err != nil {
+			// The stream was changed since it was loaded, so leave it alone
+			var transitionErr *StreamTransitionError
+			if errors.As(err, &transitionErr) {
+				continue
+			}
+			return ended, err
+		}
+		ended++
 	}
-	return s.DB.Save(stream).Error
+	return ended, nil
+
 }
 
-func (s *StreamsService) UpdateStatus(stream *models.Stream, status string) error {
+// UpdateStatus moves a stream to a new status. Only the transitions in the stream status table are
+// allowed, and anything else returns a StreamTransitionError
+func (s *StreamsService) UpdateStatus(stream *models.Stream, status string, trigger *StreamStatusTrigger) error {
 
 	// If the stream is nil
 	if stream == nil {
 		return errors.New("cannot update nil stream status")
 	}
 
-	// Define the slice of permitted status values
-	possibleStatusValues := []string{
-		models.StreamStatus_Upcoming,
-		models.StreamStatus_Live,
-		models.StreamStatus_Ended,
-		models.StreamStatus_Cancelled,
-	}
-
 	// Make sure the status value is one of the permitted values
-	contained := false
-	for _, option := range possibleStatusValues {
-		if option == status {
-			contained = true
-			break
-		}
+	if !models.IsStreamStatus(status) {
+		return streamUpdateErrorf("unsupported stream status value: \"%s\"", status)
 	}
-	if !contained {
-		return fmt.Errorf("unsupported stream status value: \"%s\"", status)
+
+	// Make sure the stream can move to the status
+	if !models.CanTransitionStreamStatus(stream.Status, status) {
+		return &StreamTransitionError{From: stream.Status, To: status}
 	}
 
 	// Update the stream
-	stream.Status = status
-	if status == models.StreamStatus_Ended {
-		stream.EndedDate = sql.NullTime{
-			Valid: true,
-			Time:  time.Now(),
+	return s.transitionStatus(stream, status, trigger)
+
+}
+
+// ForceEndStream ends a stream that hasn't finished yet, even if it never went live, and marks its
+// encoder as disconnected. Streams that already finished are left with their status
+func (s *StreamsService) ForceEndStream(stream *models.Stream, trigger *StreamStatusTrigger) error {
+
+	// Mark the encoder as disconnected
+	err := s.DB.
+		Model(stream).
+		Update("streaming", false).
+		Error
+	if err != nil {
+		return err
+	}
+	stream.Streaming = false
+
+	// End the stream, skipping the transition table
+	if stream.IsFinished() {
+		return nil
+	}
+	return s.transitionStatus(stream, models.StreamStatus_Ended, trigger)
+
+}
+
+// transitionStatus moves a stream to a new status, and records the change in its history. The update
+// only applies if the stream still has the status it was loaded with, so concurrent changes can't both
+// succeed
+func (s *StreamsService) transitionStatus(stream *models.Stream, status string, trigger *StreamStatusTrigger) error {
+
+	// Build the columns to update
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status": status,
+	}
+	startedDate := stream.StartedDate
+	endedDate := stream.EndedDate
+	switch status {
+	case models.StreamStatus_Live:
+		startedDate = sql.NullTime{Valid: true, Time: now}
+		updates["started_date"] = startedDate
+	case models.StreamStatus_Ended:
+		endedDate = sql.NullTime{Valid: true, Time: now}
+		updates["ended_date"] = endedDate
+	}
+
+	// Update the stream and record the change together
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&models.Stream{}).
+			Where("id = ?", stream.ID).
+			Where("status = ?", stream.Status).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &StreamTransitionError{From: stream.Status, To: status}
 		}
+		change := models.StreamStatusChange{
+			StreamID:       stream.ID,
+			FromStatus:     stream.Status,
+			ToStatus:       status,
+			Source:         trigger.Source,
+			ActorAccountID: nullInt64(trigger.AccountID),
+			ApiKeyID:       nullInt64(trigger.ApiKeyID),
+			IngestNodeID:   nullInt64(trigger.IngestNodeID),
+			CreatedDate:    now,
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		return err
 	}
-	return s.DB.Save(stream).Error
+
+	// Apply the changes to the stream
+	stream.Status = status
+	stream.StartedDate = startedDate
+	stream.EndedDate = endedDate
+	return nil
 
 }
 
+// GetStatusChanges gets the history of status changes on a stream, oldest first
+func (s *StreamsService) GetStatusChanges(streamID uint64) ([]*models.StreamStatusChange, error) {
+	var changes []*models.StreamStatusChange
+	err := s.DB.
+		Where("stream_id = ?", streamID).
+		Order("id ASC").
+		Find(&changes).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 func (s *StreamsService) UpdateViewerCount(stream *models.Stream, count int) error {
 	return s.DB.
 		Model(&models.Stream{}).
@@ -207,13 +531,27 @@ func (s *StreamsService) UpdateViewerCount(stream *models.Stream, count int) err
 
 // GetLiveStreamForCreator gets the stream that is currently live for a creator
 func (s *StreamsService) GetLiveStreamForCreator(creator *models.CreatorProfile) (*models.Stream, error) {
-	var stream models.Stream
-	err := s.DB.
+	return s.getLiveStreamForCreator(creator, false)
+}
+
+// GetPublicLiveStreamForCreator gets the stream that is currently live for a creator, if it's public
+func (s *StreamsService) GetPublicLiveStreamForCreator(creator *models.CreatorProfile) (*models.Stream, error) {
+	return s.getLiveStreamForCreator(creator, true)
+}
+
+func (s *StreamsService) getLiveStreamForCreator(
+	creator *models.CreatorProfile,
+	publicOnly bool,
+) (*models.Stream, error) {
+	query := s.DB.
 		Where("creator_profile_id = ?", creator.ID).
-		Where("status = ?", models.StreamStatus_Live).
-		First(&stream).
-		Error
-	if err != nil {
+		Where("deleted_date IS NULL").
+		Where("status = ?", models.StreamStatus_Live)
+	if publicOnly {
+		query = query.Where("visibility = ?", models.StreamVisibility_Public)
+	}
+	var stream models.Stream
+	if err := query.First(&stream).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -224,14 +562,28 @@ func (s *StreamsService) GetLiveStreamForCreator(creator *models.CreatorProfile)
 
 // GetNextStreamForCreator gets the stream that is next upcoming (and not currently live) for a creator
 func (s *StreamsService) GetNextStreamForCreator(creator *models.CreatorProfile) (*models.Stream, error) {
-	var stream models.Stream
-	err := s.DB.
+	return s.getNextStreamForCreator(creator, false)
+}
+
+// GetNextPublicStreamForCreator gets the public stream that is next upcoming for a creator. Streams
+// that aren't public are skipped over
+func (s *StreamsService) GetNextPublicStreamForCreator(creator *models.CreatorProfile) (*models.Stream, error) {
+	return s.getNextStreamForCreator(creator, true)
+}
+
+func (s *StreamsService) getNextStreamForCreator(
+	creator *models.CreatorProfile,
+	publicOnly bool,
+) (*models.Stream, error) {
+	query := s.DB.
 		Where("creator_profile_id = ?", creator.ID).
-		Where("status = ?", models.StreamStatus_Upcoming).
-		Order("scheduled_start_date ASC").
-		First(&stream).
-		Error
-	if err != nil {
+		Where("deleted_date IS NULL").
+		Where("status = ?", models.StreamStatus_Upcoming)
+	if publicOnly {
+		query = query.Where("visibility = ?", models.StreamVisibility_Public)
+	}
+	var stream models.Stream
+	if err := query.Order("scheduled_start_date ASC").First(&stream).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -241,26 +593,108 @@ func (s *StreamsService) GetNextStreamForCreator(creator *models.CreatorProfile)
 }
 
 type StreamUpdates struct {
-	Title *string `json:"title"`
+	Title                  *string `json:"title"`
+	IngestPolicy           *string `json:"ingest_policy"`
+	DisconnectGraceSeconds *int    `json:"disconnect_grace_seconds"`
+	Visibility             *string `json:"visibility"`
+	Password               *string `json:"password"`
 }
 
-// UpdateStream commits a series of updates to the provided stream
+// StreamUpdateError is returned when the updates to a stream, or the status it's set to, are invalid
+type StreamUpdateError struct {
+	Message string
+}
+
+func (e *StreamUpdateError) Error() string {
+	return e.Message
+}
+
+// streamUpdateErrorf formats a StreamUpdateError
+func streamUpdateErrorf(format string, args ...interface{}) error {
+	return &StreamUpdateError{Message: fmt.Sprintf(format, args...)}
+}
+
+// UpdateStream commits a series of updates to the provided stream. Invalid updates are reported with a
+// StreamUpdateError. Only the edited columns are written,
+// so changes made to the stream by ingest or viewer reports since it was loaded aren't overwritten
 func (s *StreamsService) UpdateStream(stream *models.Stream, updates *StreamUpdates) error {
 
-	// Track if any changes were made
-	var changed bool
+	// Track the columns that were edited
+	var columns []string
 
 	// Update the fields
 	if updates.Title != nil {
 		stream.Title = *updates.Title
-		changed = true
+		columns = append(columns, "title")
+	}
+	if updates.IngestPolicy != nil {
+		if !models.IsStreamIngestPolicy(*updates.IngestPolicy) {
+			return streamUpdateErrorf("unsupported ingest policy: \"%s\"", *updates.IngestPolicy)
+		}
+		stream.IngestPolicy = *updates.IngestPolicy
+		columns = append(columns, "ingest_policy")
+	}
+	if updates.DisconnectGraceSeconds != nil {
+		grace := *updates.DisconnectGraceSeconds
+		if grace < 0 || grace > streamDisconnectGraceMax {
+			return streamUpdateErrorf("disconnect grace period must be between 0 and %d seconds", streamDisconnectGraceMax)
+		}
+		stream.DisconnectGraceSeconds = grace
+		columns = append(columns, "disconnect_grace_seconds")
+	}
+	if updates.Visibility != nil {
+		if !models.IsStreamVisibility(*updates.Visibility) {
+			return streamUpdateErrorf("unsupported visibility: \"%s\"", *updates.Visibility)
+		}
+		stream.Visibility = *updates.Visibility
+		if (stream.MembersOnly() || stream.RequiresPassword()) && !s.canPlayPrivately() {
+			return streamUpdateErrorf("members-only and password-protected streams require playback tokens to be configured")
+		}
+		columns = append(columns, "visibility")
+	}
+	if updates.Password != nil {
+		if !stream.RequiresPassword() {
+			return streamUpdateErrorf("a password can only be set on password-protected streams")
+		}
+		password := *updates.Password
+		if len(password) < streamPasswordMinLength || len(password) > streamPasswordMaxLength {
+			return streamUpdateErrorf(
+				"stream password must be between %d and %d characters",
+				streamPasswordMinLength,
+				streamPasswordMaxLength,
+			)
+		}
+		hash, err := utils.HashSharedPassword(password)
+		if err != nil {
+			return err
+		}
+		stream.PasswordHash = hash
+		columns = append(columns, "password_hash")
 	}
 
-	// If a change was made, save to the database. Otherwise just return without error
-	if changed {
-		return s.DB.Save(stream).Error
-	} else {
+	// Password-protected streams need a password, and other streams don't keep theirs
+	if stream.RequiresPassword() && len(stream.PasswordHash) == 0 {
+		return streamUpdateErrorf("a password is required for password-protected streams")
+	}
+	if !stream.RequiresPassword() && len(stream.PasswordHash) > 0 {
+		stream.PasswordHash = ""
+		columns = append(columns, "password_hash")
+	}
+
+	// If a change was made, save the edited columns to the database. Otherwise just return without error
+	if len(columns) == 0 {
 		return nil
 	}
+	return s.DB.
+		Model(stream).
+		Select(columns).
+		Updates(stream).
+		Error
+
+}
 
+// canPlayPrivately checks if streams can be limited to some viewers. Without playback tokens, anyone
+// with a stream's playback URL could watch it
+func (s *StreamsService) canPlayPrivately() bool {
+	return s.PlaybackService != nil && s.PlaybackService.TokensRequired()
 }
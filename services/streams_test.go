@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+)
+
+func TestUpdateStreamKeepsOtherColumns(t *testing.T) {
+	s := StreamsService{DB: openTestDB(t, &models.Stream{}, &models.StreamStatusChange{})}
+	creator := &models.CreatorProfile{ID: 1}
+	stream, err := s.CreateStream(creator, &CreateStreamOptions{
+		Title:              "Before",
+		ScheduledStartDate: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("error creating stream: %s", err.Error())
+	}
+
+	// The stream goes live and gets viewers after a studio user loaded it
+	stale := *stream
+	live := *stream
+	if err := s.transitionStatus(&live, models.StreamStatus_Live, &StreamStatusTrigger{}); err != nil {
+		t.Fatalf("error going live: %s", err.Error())
+	}
+	s.DB.Model(&models.Stream{}).Where("id = ?", stream.ID).Update("current_viewers", 42)
+
+	// Editing the title of the stale copy only changes the title
+	title := "After"
+	if err := s.UpdateStream(&stale, &StreamUpdates{Title: &title}); err != nil {
+		t.Fatalf("error updating stream: %s", err.Error())
+	}
+	var saved models.Stream
+	s.DB.First(&saved, stream.ID)
+	if saved.Title != "After" {
+		t.Errorf("title wasn't updated: %s", saved.Title)
+	}
+	if saved.Status != models.StreamStatus_Live || !saved.StartedDate.Valid || saved.CurrentViewers != 42 {
+		t.Errorf("update overwrote the stream's status or viewers: %s, %d", saved.Status, saved.CurrentViewers)
+	}
+
+}
+
+func TestUpdateStreamVisibility(t *testing.T) {
+	strPtr := func(v string) *string { return &v }
+	tests := []struct {
+		name           string
+		tokens         bool
+		from           string
+		updates        StreamUpdates
+		valid          bool
+		expectPassword bool
+	}{
+		{"public", false, models.StreamVisibility_Unlisted, StreamUpdates{Visibility: strPtr(models.StreamVisibility_Public)}, true, false},
+		{"unlisted", false, models.StreamVisibility_Public, StreamUpdates{Visibility: strPtr(models.StreamVisibility_Unlisted)}, true, false},
+		{"unsupported", true, models.StreamVisibility_Public, StreamUpdates{Visibility: strPtr("secret")}, false, false},
+		{
+			"members without tokens",
+			false,
+			models.StreamVisibility_Public,
+			StreamUpdates{Visibility: strPtr(models.StreamVisibility_Members)},
+			false,
+			false,
+		},
+		{
+			"password without tokens",
+			false,
+			models.StreamVisibility_Public,
+			StreamUpdates{Visibility: strPtr(models.StreamVisibility_Password), Password: strPtr("letmein")},
+			false,
+			false,
+		},
+		{"members", true, models.StreamVisibility_Public, StreamUpdates{Visibility: strPtr(models.StreamVisibility_Members)}, true, false},
+		{
+			"password",
+			true,
+			models.StreamVisibility_Public,
+			StreamUpdates{Visibility: strPtr(models.StreamVisibility_Password), Password: strPtr("letmein")},
+			true,
+			true,
+		},
+		{
+			"password missing",
+			true,
+			models.StreamVisibility_Public,
+			StreamUpdates{Visibility: strPtr(models.StreamVisibility_Password)},
+			false,
+			false,
+		},
+		{
+			"password too short",
+			true,
+			models.StreamVisibility_Public,
+			StreamUpdates{Visibility: strPtr(models.StreamVisibility_Password), Password: strPtr("abc")},
+			false,
+			false,
+		},
+		{"password on a public stream", true, models.StreamVisibility_Public, StreamUpdates{Password: strPtr("letmein")}, false, false},
+		{"password kept", true, models.StreamVisibility_Password, StreamUpdates{Title: strPtr("Renamed")}, true, true},
+		{"password cleared", true, models.StreamVisibility_Password, StreamUpdates{Visibility: strPtr(models.StreamVisibility_Public)}, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := StreamsService{
+				DB:              openTestDB(t, &models.Stream{}),
+				PlaybackService: &PlaybackService{},
+			}
+			if test.tokens {
+				s.PlaybackService.TokenSecret = "secret"
+			}
+			stream := &models.Stream{Identifier: "stream", Visibility: test.from}
+			if test.from == models.StreamVisibility_Password {
+				stream.PasswordHash, _ = utils.HashSharedPassword("letmein")
+			}
+			if err := s.DB.Create(stream).Error; err != nil {
+				t.Fatalf("error creating stream: %s", err.Error())
+			}
+
+			// Apply the updates
+			err := s.UpdateStream(stream, &test.updates)
+			var updateErr *StreamUpdateError
+			if test.valid && err != nil {
+				t.Fatalf("expected the update to be valid, got %s", err.Error())
+			}
+			if !test.valid {
+				if !errors.As(err, &updateErr) {
+					t.Fatalf("expected a StreamUpdateError, got %v", err)
+				}
+				return
+			}
+
+			// Check what was saved
+			var saved models.Stream
+			s.DB.First(&saved, stream.ID)
+			if saved.Visibility != stream.Visibility {
+				t.Errorf("expected visibility %s to be saved, got %s", stream.Visibility, saved.Visibility)
+			}
+			if !test.expectPassword && len(saved.PasswordHash) > 0 {
+				t.Errorf("expected the password to be cleared")
+			}
+			if test.expectPassword && !saved.VerifyPassword("letmein") {
+				t.Errorf("expected the password to be saved")
+			}
+		})
+	}
+}
+
+func TestUpdateStreamingIgnoresDisconnectsFromOldNode(t *testing.T) {
+	s := StreamsService{DB: openTestDB(t, &models.Stream{}, &models.StreamStatusChange{})}
+	stream := &models.Stream{
+		Identifier:   "stream",
+		Status:       models.StreamStatus_Upcoming,
+		IngestPolicy: models.StreamIngestPolicy_AutoLiveEnd,
+	}
+	s.DB.Create(stream)
+	s.DB.Model(stream).Update("disconnect_grace_seconds", 0)
+	onNode := func(nodeID uint64) *StreamStatusTrigger {
+		return &StreamStatusTrigger{Source: models.StreamStatusSource_Rtmp, IngestNodeID: nodeID}
+	}
+
+	// The encoder connects to one node, and fails over to another
+	if _, err := s.UpdateStreaming(stream, true, onNode(1)); err != nil {
+		t.Fatalf("error connecting: %s", err.Error())
+	}
+	stale := *stream
+	if _, err := s.UpdateStreaming(stream, true, onNode(2)); err != nil {
+		t.Fatalf("error reconnecting: %s", err.Error())
+	}
+
+	// The first node reports the disconnect late, which doesn't end the stream
+	if _, err := s.UpdateStreaming(&stale, false, onNode(1)); err != nil {
+		t.Fatalf("error disconnecting: %s", err.Error())
+	}
+	var saved models.Stream
+	s.DB.First(&saved, stream.ID)
+	if !saved.Streaming || saved.IngestNodeID.Int64 != 2 || saved.Status != models.StreamStatus_Live {
+		t.Errorf("late disconnect from the old node was applied: %v on %d, %s",
+			saved.Streaming, saved.IngestNodeID.Int64, saved.Status)
+	}
+
+	// The current node's disconnect ends it
+	if _, err := s.UpdateStreaming(stream, false, onNode(2)); err != nil {
+		t.Fatalf("error disconnecting: %s", err.Error())
+	}
+	s.DB.First(&saved, stream.ID)
+	if saved.Streaming || saved.Status != models.StreamStatus_Ended {
+		t.Errorf("disconnect from the current node wasn't applied: %v, %s", saved.Streaming, saved.Status)
+	}
+}
+
+func TestUpdateStreamingRejectsFinishedStreams(t *testing.T) {
+	s := StreamsService{DB: openTestDB(t, &models.Stream{}, &models.StreamStatusChange{})}
+	for _, status := range []string{models.StreamStatus_Ended, models.StreamStatus_Cancelled} {
+		stream := &models.Stream{Identifier: status, Status: status}
+		s.DB.Create(stream)
+		_, err := s.UpdateStreaming(stream, true, &StreamStatusTrigger{Source: models.StreamStatusSource_Rtmp})
+		if !errors.Is(err, ErrStreamFinished) {
+			t.Errorf("expected connecting to a %s stream to fail, got %v", status, err)
+		}
+		var saved models.Stream
+		s.DB.First(&saved, stream.ID)
+		if saved.Streaming {
+			t.Errorf("%s stream was marked as streaming", status)
+		}
+	}
+}
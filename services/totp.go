@@ -0,0 +1,223 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// totpSkew is the number of time steps of clock drift tolerated when checking codes
+	totpSkew = 1
+
+	// totpRecoveryCodeCount is the number of recovery codes generated for an account
+	totpRecoveryCodeCount = 10
+)
+
+// TotpEnrollment is the info needed to add a new TOTP secret to an authenticator app
+type TotpEnrollment struct {
+	Secret          string
+	ProvisioningUri string
+}
+
+// TotpService manages TOTP two-factor authentication on accounts
+type TotpService struct {
+	DB     *gorm.DB
+	Issuer string
+}
+
+// BeginEnrollment generates a new TOTP secret for the account. The secret is not required to log in
+// until it has been confirmed with a valid code
+func (s *TotpService) BeginEnrollment(account *models.Account) (*TotpEnrollment, error) {
+
+	// If TOTP is already enabled
+	if account.IsTotpEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	// Generate the secret
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the pending secret on the account
+	account.TotpSecret = sql.NullString{Valid: true, String: secret}
+	account.TotpLastStep = sql.NullInt64{}
+	err = s.DB.
+		Model(account).
+		Select("totp_secret", "totp_last_step").
+		Updates(account).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the enrollment info
+	return &TotpEnrollment{
+		Secret:          secret,
+		ProvisioningUri: utils.TotpProvisioningUri(s.issuer(), account.Email, secret),
+	}, nil
+
+}
+
+// issuer gets the issuer name shown in authenticator apps
+func (s *TotpService) issuer() string {
+	if len(s.Issuer) == 0 {
+		return "Livestream"
+	}
+	return s.Issuer
+}
+
+// ConfirmEnrollment enables TOTP on the account once the user proves their authenticator works, and
+// returns a fresh set of recovery codes
+func (s *TotpService) ConfirmEnrollment(account *models.Account, code string) ([]string, error) {
+
+	// If there is no pending secret
+	if account.IsTotpEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if !account.TotpSecret.Valid {
+		return nil, errors.New("two-factor authentication enrollment has not been started")
+	}
+
+	// Check the code against the pending secret
+	ok, err := s.checkCode(account, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("incorrect two-factor authentication code")
+	}
+
+	// Enable TOTP on the account
+	account.TotpEnabledDate = sql.NullTime{Valid: true, Time: time.Now()}
+	err = s.DB.
+		Model(account).
+		Update("totp_enabled_date", account.TotpEnabledDate).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate the recovery codes
+	return s.RegenerateRecoveryCodes(account)
+
+}
+
+// Disable turns off TOTP on the account, and removes its secret and recovery codes
+func (s *TotpService) Disable(account *models.Account) error {
+
+	// Clear the secret on the account
+	account.TotpSecret = sql.NullString{}
+	account.TotpEnabledDate = sql.NullTime{}
+	account.TotpLastStep = sql.NullInt64{}
+	err := s.DB.
+		Model(account).
+		Select("totp_secret", "totp_enabled_date", "totp_last_step").
+		Updates(account).
+		Error
+	if err != nil {
+		return err
+	}
+
+	// Delete the recovery codes
+	return s.DB.
+		Where("account_id = ?", account.ID).
+		Delete(&models.TotpRecoveryCode{}).
+		Error
+
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes on the account with a new set
+func (s *TotpService) RegenerateRecoveryCodes(account *models.Account) ([]string, error) {
+
+	// Generate the codes. Only their hashes are stored
+	codes := make([]string, totpRecoveryCodeCount)
+	records := make([]*models.TotpRecoveryCode, totpRecoveryCodeCount)
+	for i := range codes {
+		raw, err := utils.SecureRandHexStr(10)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = fmt.Sprintf("%s-%s", raw[:5], raw[5:])
+		records[i] = &models.TotpRecoveryCode{
+			AccountID:   account.ID,
+			CodeHash:    utils.Sha256Hex(codes[i]),
+			CreatedDate: time.Now(),
+		}
+	}
+
+	// Replace the existing codes
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("account_id = ?", account.ID).Delete(&models.TotpRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+
+}
+
+// Verify checks a TOTP code on an account with TOTP enabled. Each code is only accepted once
+func (s *TotpService) Verify(account *models.Account, code string) (bool, error) {
+	if !account.IsTotpEnabled() {
+		return false, errors.New("two-factor authentication is not enabled")
+	}
+	return s.checkCode(account, code)
+}
+
+// VerifyRecoveryCode checks a recovery code on an account, and uses it up if it is valid
+func (s *TotpService) VerifyRecoveryCode(account *models.Account, code string) (bool, error) {
+	result := s.DB.
+		Model(&models.TotpRecoveryCode{}).
+		Where("account_id = ?", account.ID).
+		Where("code_hash = ?", utils.Sha256Hex(strings.ToLower(strings.TrimSpace(code)))).
+		Where("used_date IS NULL").
+		Update("used_date", sql.NullTime{Valid: true, Time: time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// checkCode checks a TOTP code against the account's secret, and records the step it matched so the
+// same code can't be replayed
+func (s *TotpService) checkCode(account *models.Account, code string) (bool, error) {
+
+	// Check the code
+	step, ok := utils.VerifyTotp(account.TotpSecret.String, code, time.Now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+
+	// Refuse codes at or before the last step that was used
+	if account.TotpLastStep.Valid && step <= account.TotpLastStep.Int64 {
+		return false, nil
+	}
+
+	// Record the step, unless a concurrent request already used this step or a later one
+	lastStep := sql.NullInt64{Valid: true, Int64: step}
+	result := s.DB.
+		Model(account).
+		Where("totp_last_step IS NULL OR totp_last_step < ?", step).
+		Update("totp_last_step", lastStep)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	account.TotpLastStep = lastStep
+	return true, nil
+
+}
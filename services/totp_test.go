@@ -0,0 +1,35 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+)
+
+func TestTotpCodeAcceptedOnce(t *testing.T) {
+	s := TotpService{DB: openTestDB(t, &models.Account{})}
+	secret, err := utils.GenerateTotpSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %s", err.Error())
+	}
+	account := &models.Account{
+		Email:           "a@example.com",
+		TotpSecret:      sql.NullString{Valid: true, String: secret},
+		TotpEnabledDate: sql.NullTime{Valid: true, Time: time.Now()},
+	}
+	s.DB.Create(account)
+	code, _ := utils.TotpCode(secret, utils.TotpStep(time.Now()))
+
+	// Two requests load the account before either uses the code
+	first := *account
+	second := *account
+	if ok, err := s.Verify(&first, code); err != nil || !ok {
+		t.Fatalf("code was rejected (err: %v)", err)
+	}
+	if ok, err := s.Verify(&second, code); err != nil || ok {
+		t.Errorf("code was accepted twice (err: %v)", err)
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// ViewerSessionTimeout is how long a viewer may go without a heartbeat before their session ends
+	ViewerSessionTimeout = 60 * time.Second
+
+	// ViewerHeartbeatInterval is how often viewers are asked to heartbeat while they watch
+	ViewerHeartbeatInterval = 20 * time.Second
+)
+
+// viewerIDPattern is the format of the anonymous IDs viewers generate for themselves
+var viewerIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// ErrStreamNotLive is returned when a viewer tries to watch a stream that isn't live
+var ErrStreamNotLive = errors.New("stream is not live")
+
+// StreamAnalyticsResult is the analytics of a stream, and whether they're final
+type StreamAnalyticsResult struct {
+	Stream    *models.Stream
+	Analytics *models.StreamAnalytics
+	Final     bool
+}
+
+// CreatorAnalyticsRollup sums up the analytics of a creator profile's streams over a date range
+type CreatorAnalyticsRollup struct {
+	Streams        []*StreamAnalyticsResult
+	StreamCount    int
+	PeakViewers    int
+	AverageViewers float64
+	UniqueViewers  int
+	WatchMinutes   float64
+}
+
+// ViewerAnalyticsService tracks the sessions of viewers watching streams, and computes analytics from them
+type ViewerAnalyticsService struct {
+	DB *gorm.DB
+}
+
+// JoinStream starts a new viewing session on a live stream. Any session the viewer already had open on
+// the stream is ended
+func (s *ViewerAnalyticsService) JoinStream(stream *models.Stream, viewerID string) error {
+
+	// Validate the request
+	if !viewerIDPattern.MatchString(viewerID) {
+		return errors.New("invalid viewer ID")
+	}
+	if stream.Status != models.StreamStatus_Live {
+		return ErrStreamNotLive
+	}
+
+	// End the open session, and start a new one
+	now := time.Now()
+	if err := s.endOpenSession(stream.ID, viewerID, now); err != nil {
+		return err
+	}
+	return s.startSession(stream.ID, viewerID, now)
+
+}
+
+// HeartbeatStream extends a viewer's session on a live stream. If the session already timed out, a new one
+// is started. When the stream is no longer live, the session is ended instead
+func (s *ViewerAnalyticsService) HeartbeatStream(stream *models.Stream, viewerID string) error {
+
+	// Validate the request
+	if !viewerIDPattern.MatchString(viewerID) {
+		return errors.New("invalid viewer ID")
+	}
+	now := time.Now()
+	if stream.Status != models.StreamStatus_Live {
+		if err := s.endOpenSession(stream.ID, viewerID, now); err != nil {
+			return err
+		}
+		return ErrStreamNotLive
+	}
+
+	// Find the viewer's open session
+	session, err := s.getOpenSession(stream.ID, viewerID)
+	if err != nil {
+		return err
+	}
+
+	// If it's still active, extend it
+	if session != nil && now.Sub(session.LastSeenDate) < ViewerSessionTimeout {
+		return s.DB.
+			Model(session).
+			Update("last_seen_date", now).
+			Error
+	}
+
+	// Otherwise, end it and start a new one
+	if err := s.endOpenSession(stream.ID, viewerID, now); err != nil {
+		return err
+	}
+	return s.startSession(stream.ID, viewerID, now)
+
+}
+
+// LeaveStream ends a viewer's session on a stream
+func (s *ViewerAnalyticsService) LeaveStream(stream *models.Stream, viewerID string) error {
+	return s.endOpenSession(stream.ID, viewerID, time.Now())
+}
+
+// getOpenSession gets the session a viewer has open on a stream, or nil if there is none
+func (s *ViewerAnalyticsService) getOpenSession(streamID uint64, viewerID string) (*models.ViewerSession, error) {
+	var session models.ViewerSession
+	err := s.DB.
+		Where("stream_id = ?", streamID).
+		Where("viewer_id = ?", viewerID).
+		Where("ended_date IS NULL").
+		Order("started_date DESC").
+		First(&session).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// startSession starts a viewer's session on a stream
+func (s *ViewerAnalyticsService) startSession(streamID uint64, viewerID string, now time.Time) error {
+	session := models.ViewerSession{
+		StreamID:     streamID,
+		ViewerID:     viewerID,
+		StartedDate:  now,
+		LastSeenDate: now,
+	}
+	return s.DB.Create(&session).Error
+}
+
+// endOpenSession ends the session a viewer has open on a stream. A session that already timed out ends
+// when the viewer was last seen, rather than now
+func (s *ViewerAnalyticsService) endOpenSession(streamID uint64, viewerID string, now time.Time) error {
+	session, err := s.getOpenSession(streamID, viewerID)
+	if err != nil || session == nil {
+		return err
+	}
+	return s.DB.
+		Model(session).
+		Update("ended_date", sql.NullTime{Valid: true, Time: sessionEnd(session, now)}).
+		Error
+}
+
+// CloseStaleSessions ends the sessions of viewers who stopped heartbeating, as of when they were last seen
+func (s *ViewerAnalyticsService) CloseStaleSessions() error {
+	return s.DB.
+		Model(&models.ViewerSession{}).
+		Where("ended_date IS NULL").
+		Where("last_seen_date < ?", time.Now().Add(-ViewerSessionTimeout)).
+		Update("ended_date", gorm.Expr("last_seen_date")).
+		Error
+}
+
+// ComputeEndedStreams computes and saves the analytics of the streams that ended, once their viewers'
+// sessions have had time to end. It returns the number of streams computed
+func (s *ViewerAnalyticsService) ComputeEndedStreams() (int, error) {
+
+	// Find the ended streams without analytics
+	var streams []*models.Stream
+	err := s.DB.
+		Where("status = ?", models.StreamStatus_Ended).
+		Where("ended_date < ?", time.Now().Add(-ViewerSessionTimeout)).
+		Where("id NOT IN (?)", s.DB.Model(&models.StreamAnalytics{}).Select("stream_id")).
+		Find(&streams).
+		Error
+	if err != nil {
+		return 0, err
+	}
+
+	// Compute and save the analytics of each one
+	for i, stream := range streams {
+		analytics, err := s.computeAnalytics(stream, time.Now())
+		if err != nil {
+			return i, err
+		}
+		if err := s.DB.Create(analytics).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(streams), nil
+
+}
+
+// GetStreamAnalytics gets the analytics of a stream. Once the stream has ended and its analytics were
+// computed, those are final. Until then, they're computed as of now, and reported as not final
+func (s *ViewerAnalyticsService) GetStreamAnalytics(stream *models.Stream) (*models.StreamAnalytics, bool, error) {
+
+	// Get the final analytics, if they were computed
+	var analytics models.StreamAnalytics
+	err := s.DB.
+		Where("stream_id = ?", stream.ID).
+		First(&analytics).
+		Error
+	if err == nil {
+		return &analytics, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	// Compute them as of now
+	computed, err := s.computeAnalytics(stream, time.Now())
+	if err != nil {
+		return nil, false, err
+	}
+	return computed, false, nil
+
+}
+
+// GetCreatorRollup gets the analytics of a creator profile's streams that started within a date range,
+// and sums them up
+func (s *ViewerAnalyticsService) GetCreatorRollup(creatorID uint64, start, end time.Time) (*CreatorAnalyticsRollup, error) {
+
+	// Find the streams that started in the range
+	var streams []*models.Stream
+	err := s.DB.
+		Where("creator_profile_id = ?", creatorID).
+		Where("deleted_date IS NULL").
+		Where("started_date >= ?", start).
+		Where("started_date < ?", end).
+		Order("started_date ASC").
+		Find(&streams).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the analytics of each stream, and add them up
+	rollup := CreatorAnalyticsRollup{
+		Streams:     make([]*StreamAnalyticsResult, len(streams)),
+		StreamCount: len(streams),
+	}
+	var streamTime time.Duration
+	streamIDs := make([]uint64, len(streams))
+	for i, stream := range streams {
+		analytics, final, err := s.GetStreamAnalytics(stream)
+		if err != nil {
+			return nil, err
+		}
+		rollup.Streams[i] = &StreamAnalyticsResult{
+			Stream:    stream,
+			Analytics: analytics,
+			Final:     final,
+		}
+		if analytics.PeakViewers > rollup.PeakViewers {
+			rollup.PeakViewers = analytics.PeakViewers
+		}
+		rollup.WatchMinutes += analytics.WatchMinutes
+		streamTime += streamWindowEnd(stream, time.Now()).Sub(stream.StartedDate.Time)
+		streamIDs[i] = stream.ID
+	}
+
+	// The average is over all of the time the streams were live
+	if streamTime > 0 {
+		rollup.AverageViewers = rollup.WatchMinutes / streamTime.Minutes()
+	}
+
+	// Count the viewers who watched any of the streams, only counting each once
+	if len(streamIDs) > 0 {
+		var unique int64
+		err := s.DB.
+			Model(&models.ViewerSession{}).
+			Where("stream_id IN ?", streamIDs).
+			Distinct("viewer_id").
+			Count(&unique).
+			Error
+		if err != nil {
+			return nil, err
+		}
+		rollup.UniqueViewers = int(unique)
+	}
+	return &rollup, nil
+
+}
+
+// computeAnalytics computes the analytics of a stream from its viewers' sessions, as of a point in time
+func (s *ViewerAnalyticsService) computeAnalytics(stream *models.Stream, now time.Time) (*models.StreamAnalytics, error) {
+
+	// Streams that never went live have no viewers
+	analytics := models.StreamAnalytics{
+		StreamID:     stream.ID,
+		ComputedDate: now,
+	}
+	if !stream.StartedDate.Valid {
+		return &analytics, nil
+	}
+
+	// Get all of the sessions on the stream
+	var sessions []*models.ViewerSession
+	err := s.DB.
+		Where("stream_id = ?", stream.ID).
+		Find(&sessions).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Limit the sessions to the time the stream was live
+	windowStart := stream.StartedDate.Time
+	windowEnd := streamWindowEnd(stream, now)
+	intervals := make([]utils.Interval, len(sessions))
+	for i, session := range sessions {
+		intervals[i] = utils.Interval{
+			Start: session.StartedDate,
+			End:   sessionEnd(session, now),
+		}
+	}
+	intervals = utils.ClipIntervals(intervals, windowStart, windowEnd)
+
+	// Count the unique viewers
+	viewers := map[string]bool{}
+	for _, session := range sessions {
+		viewers[session.ViewerID] = true
+	}
+
+	// Compute the analytics
+	analytics.PeakViewers = utils.PeakOverlap(intervals)
+	analytics.AverageViewers = utils.AverageOverlap(intervals, windowStart, windowEnd)
+	analytics.UniqueViewers = len(viewers)
+	analytics.WatchMinutes = utils.TotalDuration(intervals).Minutes()
+	return &analytics, nil
+
+}
+
+// sessionEnd gets when a viewer's session ended, or would end as of now. Sessions without a recent heartbeat
+// ended when the viewer was last seen
+func sessionEnd(session *models.ViewerSession, now time.Time) time.Time {
+	if session.EndedDate.Valid {
+		return session.EndedDate.Time
+	}
+	if now.Sub(session.LastSeenDate) >= ViewerSessionTimeout {
+		return session.LastSeenDate
+	}
+	return now
+}
+
+// streamWindowEnd gets when a stream stopped being live, or now if it still is
+func streamWindowEnd(stream *models.Stream, now time.Time) time.Time {
+	if stream.EndedDate.Valid {
+		return stream.EndedDate.Time
+	}
+	return now
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// ViewerReportTimeout is how long a viewer count reported by an edge node counts towards the
+	// stream's total. Edge nodes should report more often than this
+	ViewerReportTimeout = 30 * time.Second
+
+	// viewerReportsMaxBatch is the most streams an edge node may report on at once
+	viewerReportsMaxBatch = 500
+)
+
+// ViewerCountsService aggregates the viewer counts reported by edge nodes into the current viewer count of
+// each stream, and samples them over time
+type ViewerCountsService struct {
+	DB             *gorm.DB
+	StreamsService *StreamsService
+}
+
+// ReportViewers records the number of viewers an edge node has on each stream, keyed by stream identifier,
+// and updates the total viewer counts of the streams. Unknown streams are skipped. It returns the number
+// of streams that were updated
+func (s *ViewerCountsService) ReportViewers(nodeID uint64, counts map[string]int) (int, error) {
+
+	// Validate the reports
+	if len(counts) > viewerReportsMaxBatch {
+		return 0, fmt.Errorf("at most %d streams can be reported at once", viewerReportsMaxBatch)
+	}
+	identifiers := make([]string, 0, len(counts))
+	for identifier, viewers := range counts {
+		if viewers < 0 {
+			return 0, errors.New("viewer counts cannot be negative")
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	if len(identifiers) == 0 {
+		return 0, nil
+	}
+
+	// Find the streams
+	var streams []*models.Stream
+	err := s.DB.
+		Where("deleted_date IS NULL").
+		Where("identifier IN ?", identifiers).
+		Find(&streams).
+		Error
+	if err != nil {
+		return 0, err
+	}
+	if len(streams) == 0 {
+		return 0, nil
+	}
+
+	// Save the node's latest report for each stream
+	now := time.Now()
+	reports := make([]*models.StreamViewerReport, len(streams))
+	for i, stream := range streams {
+		reports[i] = &models.StreamViewerReport{
+			StreamID:     stream.ID,
+			IngestNodeID: nodeID,
+			Viewers:      counts[stream.Identifier],
+			ReportedDate: now,
+		}
+	}
+	err = s.DB.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "stream_id"}, {Name: "ingest_node_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"viewers", "reported_date"}),
+		}).
+		Create(&reports).
+		Error
+	if err != nil {
+		return 0, err
+	}
+
+	// Update the totals
+	if err := s.updateTotals(streams, now); err != nil {
+		return 0, err
+	}
+	return len(streams), nil
+
+}
+
+// ExpireViewerReports drops the reports that edge nodes stopped sending, and updates the total viewer counts
+// of the streams they were for
+func (s *ViewerCountsService) ExpireViewerReports() error {
+
+	// Find the streams that may have stale viewer counts
+	now := time.Now()
+	var streams []*models.Stream
+	err := s.DB.
+		Where("current_viewers > 0").
+		Find(&streams).
+		Error
+	if err != nil {
+		return err
+	}
+
+	// Update their totals, and delete the stale reports
+	if err := s.updateTotals(streams, now); err != nil {
+		return err
+	}
+	return s.DB.
+		Where("reported_date < ?", now.Add(-ViewerReportTimeout)).
+		Delete(&models.StreamViewerReport{}).
+		Error
+
+}
+
+// updateTotals sets the viewer count of each stream to the sum of its recent reports
+func (s *ViewerCountsService) updateTotals(streams []*models.Stream, now time.Time) error {
+
+	// If there are no streams, there's nothing to do
+	if len(streams) == 0 {
+		return nil
+	}
+
+	// Sum up the recent reports for the streams
+	streamIDs := make([]uint64, len(streams))
+	for i, stream := range streams {
+		streamIDs[i] = stream.ID
+	}
+	var totals []struct {
+		StreamID uint64
+		Viewers  int
+	}
+	err := s.DB.
+		Model(&models.StreamViewerReport{}).
+		Select("stream_id, SUM(viewers) AS viewers").
+		Where("stream_id IN ?", streamIDs).
+		Where("reported_date >= ?", now.Add(-ViewerReportTimeout)).
+		Group("stream_id").
+		Scan(&totals).
+		Error
+	if err != nil {
+		return err
+	}
+	viewers := make(map[uint64]int, len(totals))
+	for _, total := range totals {
+		viewers[total.StreamID] = total.Viewers
+	}
+
+	// Save the totals that changed
+	for _, stream := range streams {
+		if stream.CurrentViewers == viewers[stream.ID] {
+			continue
+		}
+		if err := s.StreamsService.UpdateViewerCount(stream, viewers[stream.ID]); err != nil {
+			return err
+		}
+		stream.CurrentViewers = viewers[stream.ID]
+	}
+	return nil
+
+}
+
+// RecordViewerSamples saves the current viewer count of every live stream to its time series
+func (s *ViewerCountsService) RecordViewerSamples() error {
+
+	// Get the live streams
+	var streams []*models.Stream
+	err := s.DB.
+		Where("deleted_date IS NULL").
+		Where("status = ?", models.StreamStatus_Live).
+		Find(&streams).
+		Error
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return nil
+	}
+
+	// Save a sample for each one
+	now := time.Now()
+	samples := make([]*models.StreamViewerSample, len(streams))
+	for i, stream := range streams {
+		samples[i] = &models.StreamViewerSample{
+			StreamID:    stream.ID,
+			Viewers:     stream.CurrentViewers,
+			CreatedDate: now,
+		}
+	}
+	return s.DB.Create(&samples).Error
+
+}
+
+// GetViewerSamples gets the viewer count time series of a stream, oldest first
+func (s *ViewerCountsService) GetViewerSamples(streamID uint64) ([]*models.StreamViewerSample, error) {
+	var samples []*models.StreamViewerSample
+	err := s.DB.
+		Where("stream_id = ?", streamID).
+		Order("created_date ASC").
+		Find(&samples).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
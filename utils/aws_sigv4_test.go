@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignAwsV4(t *testing.T) {
+
+	// The "get-vanilla" and "get-vanilla-query-order-key-case" cases from the AWS Signature Version 4
+	// test suite
+	type sigTest struct {
+		url           string
+		authorization string
+	}
+	testCases := []sigTest{
+		{
+			"https://example.amazonaws.com/",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			"https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+
+	credentials := AwsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	signTime := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	for _, testCase := range testCases {
+		req, err := http.NewRequest("GET", testCase.url, nil)
+		if err != nil {
+			t.Fatalf("error creating request: %s", err.Error())
+		}
+		SignAwsV4(req, Sha256Hex(""), credentials, "us-east-1", "service", signTime)
+		if result := req.Header.Get("Authorization"); result != testCase.authorization {
+			t.Errorf("incorrect signature for %s => '%s' (expected '%s')", testCase.url, result, testCase.authorization)
+		}
+		if result := req.Header.Get("X-Amz-Date"); result != "20150830T123600Z" {
+			t.Errorf("incorrect X-Amz-Date header: %s", result)
+		}
+	}
+
+}
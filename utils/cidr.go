@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+)
+
+// ParseCidrs parses a list of CIDR ranges, such as "10.0.0.0/8" or "2001:db8::/32"
+func ParseCidrs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range: \"%s\"", cidr)
+		}
+		nets[i] = ipNet
+	}
+	return nets, nil
+}
+
+// IPInCidrs checks if the IP address is in any of the CIDR ranges. Invalid addresses and ranges never match
+func IPInCidrs(ip string, cidrs []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if ipNet.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
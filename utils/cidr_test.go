@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestParseCidrs(t *testing.T) {
+	if _, err := ParseCidrs([]string{"10.0.0.0/8", "192.168.1.7/32", "2001:db8::/32"}); err != nil {
+		t.Errorf("valid CIDR ranges failed to parse: %s", err.Error())
+	}
+	for _, cidr := range []string{"", "10.0.0.1", "10.0.0.0/33", "example.com/8"} {
+		if _, err := ParseCidrs([]string{cidr}); err == nil {
+			t.Errorf("invalid CIDR range '%s' was parsed", cidr)
+		}
+	}
+}
+
+func TestIPInCidrs(t *testing.T) {
+	type cidrTest struct {
+		ip     string
+		cidrs  []string
+		output bool
+	}
+	testCases := []cidrTest{
+		{"10.1.2.3", []string{"10.0.0.0/8"}, true},
+		{"11.1.2.3", []string{"10.0.0.0/8"}, false},
+		{"192.168.1.7", []string{"10.0.0.0/8", "192.168.1.7/32"}, true},
+		{"192.168.1.8", []string{"192.168.1.7/32"}, false},
+		{"2001:db8::1", []string{"2001:db8::/32"}, true},
+		{"10.1.2.3", []string{"2001:db8::/32"}, false},
+		{"10.1.2.3", []string{"bad", "10.0.0.0/8"}, true},
+		{"10.1.2.3", []string{}, false},
+		{"not an ip", []string{"0.0.0.0/0"}, false},
+	}
+	for _, testCase := range testCases {
+		result := IPInCidrs(testCase.ip, testCase.cidrs)
+		if result != testCase.output {
+			t.Errorf("IPInCidrs('%s', %v) => %v (expected %v)\n", testCase.ip, testCase.cidrs, result, testCase.output)
+		}
+	}
+}
@@ -17,8 +17,10 @@ func Sha256Hex(input string) string {
 
 }
 
+// HmacSha256 calculates the HMAC-SHA256 of the input string keyed with the secret, and returns it as a
+// hexadecimal-encoded string
 func HmacSha256(input, secret string) string {
 	h := hmac.New(sha256.New, []byte(secret))
-	sum := h.Sum([]byte(input))
-	return hex.EncodeToString(sum)
+	h.Write([]byte(input))
+	return hex.EncodeToString(h.Sum(nil))
 }
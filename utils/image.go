@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// imageMaxPixels is the largest image that will be decoded, to avoid running out of memory on images
+// with huge dimensions but a tiny compressed size. Each copy of an image this size takes 64 MB as RGBA
+const imageMaxPixels = 16_000_000
+
+// ImageContentTypes are the image formats that can be uploaded, by their sniffed content type
+var ImageContentTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+}
+
+// DecodeImage validates and decodes an uploaded image. The format is detected from the data itself rather
+// than trusting the file name or declared content type, and the content type is returned
+func DecodeImage(data []byte) (image.Image, string, error) {
+
+	// Detect the format
+	contentType := http.DetectContentType(data)
+	supported := false
+	for _, t := range ImageContentTypes {
+		if t == contentType {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, "", errors.New("image must be a JPEG, PNG, or GIF")
+	}
+
+	// Check the dimensions before decoding the whole image
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image: %s", err.Error())
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > imageMaxPixels {
+		return nil, "", errors.New("image dimensions are too large")
+	}
+
+	// Decode the image
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		img, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid image: %s", err.Error())
+	}
+	return img, contentType, nil
+
+}
+
+// EncodeImage encodes an image as JPEG, or as PNG if the image may have transparency, and returns the
+// encoded bytes with their content type
+func EncodeImage(img image.Image, transparent bool) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if transparent {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// FitImageSize calculates the size of an image scaled down to fit within the maximum size, keeping its
+// aspect ratio. Images that already fit are not scaled up
+func FitImageSize(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, maxInt(1, height*maxWidth/width)
+	}
+	return maxInt(1, width*maxHeight/height), maxHeight
+}
+
+// CropImageToAspect crops the center of an image to the aspect ratio. Images that support sub-images,
+// which includes every decoded format, share their pixels with the crop instead of being copied
+func CropImageToAspect(img image.Image, aspectWidth, aspectHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	cropWidth, cropHeight := width, height
+	if width*aspectHeight > height*aspectWidth {
+		cropWidth = maxInt(1, height*aspectWidth/aspectHeight)
+	} else {
+		cropHeight = maxInt(1, width*aspectHeight/aspectWidth)
+	}
+	x := bounds.Min.X + (width-cropWidth)/2
+	y := bounds.Min.Y + (height-cropHeight)/2
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(image.Rect(x, y, x+cropWidth, y+cropHeight))
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, cropWidth, cropHeight))
+	draw.Draw(dst, dst.Bounds(), img, image.Pt(x, y), draw.Src)
+	return dst
+}
+
+// ImageToRGBA gets the image in the RGBA pixel layout. RGBA images are returned as they are, and any
+// other image is copied
+func ImageToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// ResizeImage scales an image to the size, averaging the source pixels that cover each destination pixel.
+// Images that aren't RGBA are copied first, so convert them once with ImageToRGBA when resizing repeatedly
+func ResizeImage(img image.Image, width, height int) *image.RGBA {
+
+	// Read the source in a known pixel layout
+	src := ImageToRGBA(img)
+	srcMin := src.Bounds().Min
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// Average the box of source pixels under each destination pixel
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := maxInt(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := maxInt(x0+1, (x+1)*srcWidth/width)
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(srcMin.X+x0, srcMin.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+
+}
+
+// ImageIsOpaque checks if every pixel of the image is fully opaque
+func ImageIsOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestFitImageSize(t *testing.T) {
+
+	// Each case is the source size, the maximum size, and the expected result
+	testCases := [][6]int{
+		{1920, 1080, 1280, 720, 1280, 720},
+		{1080, 1920, 1280, 720, 405, 720},
+		{640, 360, 1280, 720, 640, 360},
+		{4000, 10, 400, 400, 400, 1},
+	}
+	for _, testCase := range testCases {
+		width, height := FitImageSize(testCase[0], testCase[1], testCase[2], testCase[3])
+		if width != testCase[4] || height != testCase[5] {
+			t.Errorf(
+				"incorrect fit for %dx%d in %dx%d => %dx%d (expected %dx%d)",
+				testCase[0], testCase[1], testCase[2], testCase[3], width, height, testCase[4], testCase[5],
+			)
+		}
+	}
+
+}
+
+func TestResizeImage(t *testing.T) {
+
+	// Create an image that is red on the left half and blue on the right half
+	src := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 100; x++ {
+			if x < 50 {
+				src.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				src.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	// Scaling it down should keep the halves
+	dst := ResizeImage(src, 10, 5)
+	if dst.Bounds().Dx() != 10 || dst.Bounds().Dy() != 5 {
+		t.Fatalf("incorrect resized bounds: %v", dst.Bounds())
+	}
+	if c := dst.RGBAAt(0, 0); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("incorrect left pixel: %v", c)
+	}
+	if c := dst.RGBAAt(9, 4); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("incorrect right pixel: %v", c)
+	}
+
+	// Cropping it to a square should keep the center
+	square := CropImageToAspect(src, 1, 1)
+	if square.Bounds().Dx() != 50 || square.Bounds().Dy() != 50 {
+		t.Errorf("incorrect cropped bounds: %v", square.Bounds())
+	}
+
+	// Resizing the crop should read from its offset, keeping the red and blue quarters
+	small := ResizeImage(square, 2, 2)
+	if c := small.RGBAAt(0, 1); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("incorrect left pixel of resized crop: %v", c)
+	}
+	if c := small.RGBAAt(1, 0); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("incorrect right pixel of resized crop: %v", c)
+	}
+
+}
+
+func TestDecodeImage(t *testing.T) {
+
+	// A PNG should decode
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if _, contentType, err := DecodeImage(buf.Bytes()); err != nil || contentType != "image/png" {
+		t.Errorf("failed to decode PNG: %v (%s)", err, contentType)
+	}
+
+	// Anything else should be rejected, whatever it claims to be
+	if _, _, err := DecodeImage([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")); err == nil {
+		t.Errorf("decoded an SVG")
+	}
+
+}
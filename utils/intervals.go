@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"sort"
+	"time"
+)
+
+// Interval is a span of time from Start up to, but not including, End
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ClipIntervals limits the intervals to the window from start to end. Intervals that end up empty are dropped
+func ClipIntervals(intervals []Interval, start, end time.Time) []Interval {
+	clipped := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.Start.Before(start) {
+			interval.Start = start
+		}
+		if interval.End.After(end) {
+			interval.End = end
+		}
+		if interval.End.After(interval.Start) {
+			clipped = append(clipped, interval)
+		}
+	}
+	return clipped
+}
+
+// TotalDuration sums up the lengths of the intervals. Overlapping time is counted once for each interval
+func TotalDuration(intervals []Interval) time.Duration {
+	var total time.Duration
+	for _, interval := range intervals {
+		if interval.End.After(interval.Start) {
+			total += interval.End.Sub(interval.Start)
+		}
+	}
+	return total
+}
+
+// PeakOverlap gets the most intervals that overlap at any one time. Intervals that only touch, where one
+// ends at the same time the next starts, don't overlap
+func PeakOverlap(intervals []Interval) int {
+
+	// Turn the intervals into a list of events, where each start adds one and each end removes one
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	events := make([]event, 0, len(intervals)*2)
+	for _, interval := range intervals {
+		if !interval.End.After(interval.Start) {
+			continue
+		}
+		events = append(events, event{interval.Start, 1}, event{interval.End, -1})
+	}
+
+	// Sort the events by time, with ends before starts at the same time
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	// Walk through the events, tracking the highest count
+	peak, count := 0, 0
+	for _, e := range events {
+		count += e.delta
+		if count > peak {
+			peak = count
+		}
+	}
+	return peak
+
+}
+
+// AverageOverlap gets the average number of intervals that overlap over the window from start to end
+func AverageOverlap(intervals []Interval, start, end time.Time) float64 {
+	if !end.After(start) {
+		return 0
+	}
+	total := TotalDuration(ClipIntervals(intervals, start, end))
+	return float64(total) / float64(end.Sub(start))
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// minutes builds an interval between two minute offsets from a fixed time
+func minutes(start, end int) Interval {
+	base := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	return Interval{
+		Start: base.Add(time.Duration(start) * time.Minute),
+		End:   base.Add(time.Duration(end) * time.Minute),
+	}
+}
+
+func TestClipIntervals(t *testing.T) {
+	window := minutes(10, 20)
+	result := ClipIntervals([]Interval{
+		minutes(0, 5),
+		minutes(5, 15),
+		minutes(12, 14),
+		minutes(18, 30),
+		minutes(20, 25),
+	}, window.Start, window.End)
+	expected := []Interval{minutes(10, 15), minutes(12, 14), minutes(18, 20)}
+	if len(result) != len(expected) {
+		t.Fatalf("clipped to %d intervals (expected %d)", len(result), len(expected))
+	}
+	for i := range expected {
+		if !result[i].Start.Equal(expected[i].Start) || !result[i].End.Equal(expected[i].End) {
+			t.Errorf("interval %d clipped to %v (expected %v)", i, result[i], expected[i])
+		}
+	}
+}
+
+func TestTotalDuration(t *testing.T) {
+	result := TotalDuration([]Interval{minutes(0, 10), minutes(5, 15), minutes(20, 20), minutes(30, 25)})
+	if result != 20*time.Minute {
+		t.Errorf("total duration %s (expected 20m)", result)
+	}
+}
+
+func TestPeakOverlap(t *testing.T) {
+	type peakTest struct {
+		intervals []Interval
+		output    int
+	}
+	testCases := []peakTest{
+		{[]Interval{}, 0},
+		{[]Interval{minutes(0, 10)}, 1},
+		{[]Interval{minutes(0, 10), minutes(5, 15), minutes(8, 9)}, 3},
+		{[]Interval{minutes(0, 10), minutes(10, 20), minutes(20, 30)}, 1},
+		{[]Interval{minutes(0, 10), minutes(20, 30), minutes(25, 26), minutes(5, 5)}, 2},
+	}
+	for i, testCase := range testCases {
+		result := PeakOverlap(testCase.intervals)
+		if result != testCase.output {
+			t.Errorf("peak overlap of case %d => %d (expected %d)", i, result, testCase.output)
+		}
+	}
+}
+
+func TestAverageOverlap(t *testing.T) {
+	window := minutes(0, 10)
+	type averageTest struct {
+		intervals []Interval
+		output    float64
+	}
+	testCases := []averageTest{
+		{[]Interval{}, 0},
+		{[]Interval{minutes(0, 10)}, 1},
+		{[]Interval{minutes(0, 10), minutes(0, 5)}, 1.5},
+		{[]Interval{minutes(-10, 5), minutes(8, 20)}, 0.7},
+	}
+	for i, testCase := range testCases {
+		result := AverageOverlap(testCase.intervals, window.Start, window.End)
+		if math.Abs(result-testCase.output) > 1e-9 {
+			t.Errorf("average overlap of case %d => %f (expected %f)", i, result, testCase.output)
+		}
+	}
+	if result := AverageOverlap([]Interval{minutes(0, 10)}, window.End, window.Start); result != 0 {
+		t.Errorf("average overlap of an empty window => %f (expected 0)", result)
+	}
+}
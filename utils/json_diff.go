@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// JsonChange is a single field that differs between two JSON documents
+type JsonChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// JsonDiff compares the JSON encodings of two values, and lists the fields that differ. Nested objects
+// are compared field by field, with their paths joined by dots, and anything else (including arrays) is
+// compared as a whole. Either value may be nil, in which case every field of the other one is a change
+func JsonDiff(before, after interface{}) ([]JsonChange, error) {
+
+	// Decode both values into generic JSON
+	beforeJson, err := toGenericJson(before)
+	if err != nil {
+		return nil, err
+	}
+	afterJson, err := toGenericJson(after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare them
+	changes := []JsonChange{}
+	diffJsonValues("", beforeJson, afterJson, &changes)
+	return changes, nil
+
+}
+
+// toGenericJson round-trips a value through JSON, so it can be compared with another value of a
+// different type
+func toGenericJson(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// diffJsonValues appends the differences between two generic JSON values at a path
+func diffJsonValues(path string, before, after interface{}, changes *[]JsonChange) {
+
+	// Objects are compared field by field. A missing object counts as an empty one, so that creating or
+	// deleting something lists each of its fields
+	beforeObj, beforeIsObj := before.(map[string]interface{})
+	afterObj, afterIsObj := after.(map[string]interface{})
+	if (beforeIsObj || before == nil) && (afterIsObj || after == nil) && (beforeIsObj || afterIsObj) {
+
+		// Get the union of the keys, sorted so the changes are in a stable order
+		keys := make([]string, 0, len(beforeObj)+len(afterObj))
+		for key := range beforeObj {
+			keys = append(keys, key)
+		}
+		for key := range afterObj {
+			if _, ok := beforeObj[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		// Compare each of the fields
+		for _, key := range keys {
+			childPath := key
+			if len(path) > 0 {
+				childPath = path + "." + key
+			}
+			diffJsonValues(childPath, beforeObj[key], afterObj[key], changes)
+		}
+		return
+
+	}
+
+	// Anything else is compared as a whole
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, JsonChange{
+			Path:   path,
+			Before: before,
+			After:  after,
+		})
+	}
+
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJsonDiff(t *testing.T) {
+
+	type profile struct {
+		Name  string            `json:"name"`
+		Bio   string            `json:"bio"`
+		Links []string          `json:"links"`
+		Meta  map[string]string `json:"meta"`
+	}
+
+	// Changed fields are listed in order, with nested objects flattened into paths
+	before := profile{
+		Name:  "Alice",
+		Bio:   "Hello",
+		Links: []string{"https://a.example"},
+		Meta:  map[string]string{"color": "red", "size": "large"},
+	}
+	after := profile{
+		Name:  "Alice",
+		Bio:   "Hi there",
+		Links: []string{"https://a.example", "https://b.example"},
+		Meta:  map[string]string{"color": "blue", "size": "large"},
+	}
+	changes, err := JsonDiff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []JsonChange{
+		{Path: "bio", Before: "Hello", After: "Hi there"},
+		{Path: "links", Before: []interface{}{"https://a.example"}, After: []interface{}{"https://a.example", "https://b.example"}},
+		{Path: "meta.color", Before: "red", After: "blue"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("incorrect changes: %#v", changes)
+	}
+
+	// Identical values have no changes
+	changes, err = JsonDiff(before, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %#v", changes)
+	}
+
+	// Creating something lists every field as a change from nothing
+	changes, err = JsonDiff(nil, map[string]interface{}{"id": 5, "role": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []JsonChange{
+		{Path: "id", Before: nil, After: float64(5)},
+		{Path: "role", Before: nil, After: "admin"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("incorrect changes for a created value: %#v", changes)
+	}
+
+	// Deleting something lists every field as a change to nothing
+	changes, err = JsonDiff(map[string]interface{}{"id": 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []JsonChange{
+		{Path: "id", Before: float64(5), After: nil},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("incorrect changes for a deleted value: %#v", changes)
+	}
+
+	// Values that aren't objects are compared as a whole
+	changes, err = JsonDiff("live", "ended")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []JsonChange{
+		{Path: "", Before: "live", After: "ended"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("incorrect changes for scalar values: %#v", changes)
+	}
+
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// The parameters used for new argon2id password hashes. Changing any of these causes existing hashes
+// to be upgraded the next time their owner logs in
+const (
+	argon2idVersion = argon2.Version
+	argon2idMemory  = 64 * 1024
+	argon2idTime    = 1
+	argon2idThreads = 4
+	argon2idSaltLen = 16
+	argon2idKeyLen  = 32
+)
+
+// The parameters used for shared password hashes, such as stream passwords. These are checked on
+// every request from every viewer, and only protect a single stream, so they're cheaper to derive
+const (
+	sharedArgon2idMemory  = 8 * 1024
+	sharedArgon2idTime    = 1
+	sharedArgon2idThreads = 1
+)
+
+// argon2idPrefix is the prefix of every argon2id password hash
+const argon2idPrefix = "$argon2id$"
+
+// HashPassword hashes a password with argon2id, and returns it encoded in the PHC string format. The
+// encoding includes the algorithm and its parameters, so that hashes made with different algorithms or
+// parameters can coexist
+func HashPassword(password string) (string, error) {
+	return hashArgon2id(password, argon2idMemory, argon2idTime, argon2idThreads)
+}
+
+// HashSharedPassword hashes a password that is shared with many people, such as a stream password, with
+// cheaper argon2id parameters. It's verified with VerifyPasswordHash the same as any other hash
+func HashSharedPassword(password string) (string, error) {
+	return hashArgon2id(password, sharedArgon2idMemory, sharedArgon2idTime, sharedArgon2idThreads)
+}
+
+// hashArgon2id hashes a password with argon2id using the given parameters
+func hashArgon2id(password string, memory, time uint32, threads uint8) (string, error) {
+
+	// Generate a random salt
+	salt := make([]byte, argon2idSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	// Derive the key from the password
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, argon2idKeyLen)
+
+	// Encode the hash with its parameters
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2idVersion,
+		memory,
+		time,
+		threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+
+}
+
+// VerifyPasswordHash checks a password against a hash created by HashPassword, in constant time
+func VerifyPasswordHash(password, encoded string) (bool, error) {
+
+	// Decode the hash and its parameters
+	params, salt, key, err := decodeArgon2idHash(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	// Derive the key from the password using the same parameters
+	candidate := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+
+	// Compare the keys in constant time
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+
+}
+
+// IsLegacyPasswordHash checks if a password hash is a legacy salted SHA-256 hash, which has no prefix
+func IsLegacyPasswordHash(encoded string) bool {
+	return len(encoded) > 0 && !strings.HasPrefix(encoded, "$")
+}
+
+// VerifyLegacyPasswordHash checks a password against a legacy salted SHA-256 hash, in constant time
+func VerifyLegacyPasswordHash(password, salt, encoded string) bool {
+	candidate := Sha256Hex(salt + password)
+	return subtle.ConstantTimeCompare([]byte(candidate), []byte(encoded)) == 1
+}
+
+// PasswordHashNeedsUpgrade checks if a password hash was created with an old algorithm or old
+// parameters, and should be replaced with a new hash the next time the password is known
+func PasswordHashNeedsUpgrade(encoded string) bool {
+	params, _, key, err := decodeArgon2idHash(encoded)
+	if err != nil {
+		return true
+	}
+	return params.version != argon2idVersion ||
+		params.memory != argon2idMemory ||
+		params.time != argon2idTime ||
+		params.threads != argon2idThreads ||
+		len(key) != argon2idKeyLen
+}
+
+type argon2idParams struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// decodeArgon2idHash decodes an argon2id hash in the PHC string format
+func decodeArgon2idHash(encoded string) (*argon2idParams, []byte, []byte, error) {
+
+	// Split up the parts of the hash. The leading "$" produces an empty first part
+	if !strings.HasPrefix(encoded, argon2idPrefix) {
+		return nil, nil, nil, errors.New("password hash is not argon2id")
+	}
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errors.New("malformed argon2id password hash")
+	}
+
+	// Parse the version and the parameters
+	var params argon2idParams
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &params.version); err != nil {
+		return nil, nil, nil, errors.New("malformed argon2id password hash version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, nil, nil, errors.New("malformed argon2id password hash parameters")
+	}
+
+	// Decode the salt and the key
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errors.New("malformed argon2id password hash salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("malformed argon2id password hash key")
+	}
+	return &params, salt, key, nil
+
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+
+	// Hash a password
+	hash, err := HashPassword("hunter2")
+	if err != nil {
+		t.Fatalf("error hashing password: %s", err.Error())
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$") {
+		t.Errorf("unexpected password hash format: %s", hash)
+	}
+
+	// Hashing the same password again should use a different salt
+	other, _ := HashPassword("hunter2")
+	if hash == other {
+		t.Errorf("password hashes should not repeat")
+	}
+
+	// The correct password should verify, and an incorrect one should not
+	if ok, err := VerifyPasswordHash("hunter2", hash); err != nil || !ok {
+		t.Errorf("correct password failed to verify (err: %v)", err)
+	}
+	if ok, _ := VerifyPasswordHash("hunter3", hash); ok {
+		t.Errorf("incorrect password verified")
+	}
+
+	// A fresh hash never needs an upgrade
+	if PasswordHashNeedsUpgrade(hash) {
+		t.Errorf("fresh password hash should not need an upgrade")
+	}
+
+}
+
+func TestHashSharedPassword(t *testing.T) {
+
+	// Hash a shared password with the cheaper parameters
+	hash, err := HashSharedPassword("letmein")
+	if err != nil {
+		t.Fatalf("error hashing password: %s", err.Error())
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=8192,t=1,p=1$") {
+		t.Errorf("unexpected shared password hash format: %s", hash)
+	}
+
+	// It verifies the same as any other hash
+	if ok, err := VerifyPasswordHash("letmein", hash); err != nil || !ok {
+		t.Errorf("correct password failed to verify (err: %v)", err)
+	}
+	if ok, _ := VerifyPasswordHash("letmeout", hash); ok {
+		t.Errorf("incorrect password verified")
+	}
+
+}
+
+func TestLegacyPasswordHash(t *testing.T) {
+
+	// Create a hash the way accounts used to
+	salt := "0123456789abcdef"
+	hash := Sha256Hex(salt + "hunter2")
+
+	// It should be recognized as legacy and upgraded
+	if !IsLegacyPasswordHash(hash) {
+		t.Errorf("salted SHA-256 hash should be legacy")
+	}
+	if !PasswordHashNeedsUpgrade(hash) {
+		t.Errorf("legacy password hash should need an upgrade")
+	}
+	if !VerifyLegacyPasswordHash("hunter2", salt, hash) {
+		t.Errorf("correct password failed to verify against legacy hash")
+	}
+	if VerifyLegacyPasswordHash("hunter3", salt, hash) {
+		t.Errorf("incorrect password verified against legacy hash")
+	}
+
+	// Malformed hashes should never verify
+	for _, bad := range []string{"", "$argon2id$", "$argon2id$v=19$m=1,t=1,p=1$$"} {
+		if ok, _ := VerifyPasswordHash("hunter2", bad); ok {
+			t.Errorf("malformed hash verified: %q", bad)
+		}
+	}
+
+}
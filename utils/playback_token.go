@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlaybackToken grants a viewer access to play a stream until it expires. Tokens are formatted as
+// "<stream_id>.<expires>.<viewer_id>.<signature>", where expires is in Unix seconds and the signature is
+// the hex-encoded HMAC-SHA256 of "<stream_id>.<expires>.<viewer_id>". Media servers and CDN edges can
+// verify them with the shared secret, without calling the API
+type PlaybackToken struct {
+	StreamID string
+	ViewerID string
+	Expires  time.Time
+}
+
+// playbackTokenPayload builds the part of a playback token that is signed
+func playbackTokenPayload(token *PlaybackToken) string {
+	return fmt.Sprintf("%s.%d.%s", token.StreamID, token.Expires.Unix(), token.ViewerID)
+}
+
+// SignPlaybackToken signs a playback token with the secret, and returns it as a string
+func SignPlaybackToken(token *PlaybackToken, secret string) string {
+	payload := playbackTokenPayload(token)
+	return payload + "." + HmacSha256(payload, secret)
+}
+
+// VerifyPlaybackToken checks that a playback token was signed with the secret and hasn't expired
+func VerifyPlaybackToken(str, secret string, now time.Time) (*PlaybackToken, error) {
+
+	// Split up the token
+	parts := strings.Split(str, ".")
+	if len(parts) != 4 {
+		return nil, errors.New("malformed playback token")
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errors.New("malformed playback token")
+	}
+	token := PlaybackToken{
+		StreamID: parts[0],
+		ViewerID: parts[2],
+		Expires:  time.Unix(expires, 0),
+	}
+
+	// Check the signature in constant time
+	expected := HmacSha256(playbackTokenPayload(&token), secret)
+	if !hmac.Equal([]byte(expected), []byte(parts[3])) {
+		return nil, errors.New("invalid playback token signature")
+	}
+
+	// Make sure it hasn't expired
+	if !now.Before(token.Expires) {
+		return nil, errors.New("playback token has expired")
+	}
+	return &token, nil
+
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignPlaybackToken(t *testing.T) {
+	token := SignPlaybackToken(&PlaybackToken{
+		StreamID: "0123456789abcdef",
+		ViewerID: "viewer-1234",
+		Expires:  time.Unix(1700000000, 0),
+	}, "secret")
+	expected := "0123456789abcdef.1700000000.viewer-1234." + HmacSha256("0123456789abcdef.1700000000.viewer-1234", "secret")
+	if token != expected {
+		t.Errorf("incorrect playback token '%s' (expected '%s')", token, expected)
+	}
+}
+
+func TestVerifyPlaybackToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token := SignPlaybackToken(&PlaybackToken{
+		StreamID: "0123456789abcdef",
+		ViewerID: "viewer-1234",
+		Expires:  now.Add(time.Hour),
+	}, "secret")
+
+	// A valid token
+	parsed, err := VerifyPlaybackToken(token, "secret", now)
+	if err != nil {
+		t.Fatalf("valid playback token failed to verify: %s", err.Error())
+	}
+	if parsed.StreamID != "0123456789abcdef" || parsed.ViewerID != "viewer-1234" || !parsed.Expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("playback token parsed incorrectly: %+v", parsed)
+	}
+
+	// Tokens that must be rejected
+	parts := strings.Split(token, ".")
+	invalid := map[string]string{
+		"expired":          token,
+		"wrong secret":     token,
+		"other stream":     "fedcba9876543210." + strings.Join(parts[1:], "."),
+		"extended expiry":  parts[0] + ".1800000000." + strings.Join(parts[2:], "."),
+		"other viewer":     strings.Join(parts[:2], ".") + ".viewer-5678." + parts[3],
+		"missing part":     strings.Join(parts[:3], "."),
+		"malformed expiry": parts[0] + ".soon." + strings.Join(parts[2:], "."),
+		"empty":            "",
+	}
+	for name, str := range invalid {
+		secret, at := "secret", now
+		switch name {
+		case "expired":
+			at = now.Add(time.Hour)
+		case "wrong secret":
+			secret = "other"
+		}
+		if _, err := VerifyPlaybackToken(str, secret, at); err == nil {
+			t.Errorf("%s playback token was accepted", name)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -31,3 +33,14 @@ func RandHexStrInt64() string {
 	}
 	return str
 }
+
+// SecureRandHexStr generates a hexadecimal string of the given length using a cryptographically secure random
+// source. This should be used for anything secret, such as tokens and keys
+func SecureRandHexStr(length uint) (string, error) {
+	buf := make([]byte, (length+1)/2)
+	if _, err := crand.Read(buf); err != nil {
+		return "", err
+	}
+	str := hex.EncodeToString(buf)
+	return str[:length], nil
+}
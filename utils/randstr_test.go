@@ -23,3 +23,25 @@ func TestRandHexStr(t *testing.T) {
 	}
 
 }
+
+func TestSecureRandHexStr(t *testing.T) {
+
+	// Generate strings of many different lengths
+	for i := 0; i < 100; i++ {
+		result, err := SecureRandHexStr(uint(i))
+		if err != nil {
+			t.Fatalf("secure random hex string error: %s", err.Error())
+		}
+		if len(result) != i {
+			t.Errorf("secure random hex string of length: %d expected %d", len(result), i)
+		}
+	}
+
+	// Make sure two long strings are not identical
+	a, _ := SecureRandHexStr(64)
+	b, _ := SecureRandHexStr(64)
+	if a == b {
+		t.Errorf("secure random hex strings should not repeat")
+	}
+
+}
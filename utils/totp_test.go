@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTotpCode(t *testing.T) {
+
+	// The SHA-1 test vectors from RFC 6238, truncated to 6 digits
+	type totpTest struct {
+		time int64
+		code string
+	}
+	testCases := []totpTest{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	// The RFC secret is the ASCII string "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for _, testCase := range testCases {
+		result, err := TotpCode(secret, TotpStep(time.Unix(testCase.time, 0)))
+		if err != nil {
+			t.Fatalf("error calculating TOTP code: %s", err.Error())
+		}
+		if result != testCase.code {
+			t.Errorf("incorrect TOTP code at %d => '%s' (expected %s)", testCase.time, result, testCase.code)
+		}
+	}
+
+}
+
+func TestVerifyTotp(t *testing.T) {
+
+	// Generate a secret and the current code for it
+	secret, err := GenerateTotpSecret()
+	if err != nil {
+		t.Fatalf("error generating TOTP secret: %s", err.Error())
+	}
+	now := time.Now()
+	code, _ := TotpCode(secret, TotpStep(now))
+
+	// The code should verify now, and one step later with skew
+	if step, ok := VerifyTotp(secret, code, now, 1); !ok || step != TotpStep(now) {
+		t.Errorf("current TOTP code failed to verify")
+	}
+	if _, ok := VerifyTotp(secret, code, now.Add(time.Second*30), 1); !ok {
+		t.Errorf("TOTP code from the previous step failed to verify with skew")
+	}
+
+	// The code should not verify much later
+	if _, ok := VerifyTotp(secret, code, now.Add(time.Minute*5), 1); ok {
+		t.Errorf("stale TOTP code verified")
+	}
+
+	// The provisioning URI should include the secret
+	uri := TotpProvisioningUri("Livestream", "a@b.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Livestream:a@b.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected provisioning URI: %s", uri)
+	}
+
+}
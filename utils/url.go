@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"net/url"
+)
+
+// AbsoluteUrl resolves a possibly relative URL against a base URL. The reference is returned unchanged
+// if it is already absolute, or if either URL cannot be parsed.
+func AbsoluteUrl(base, ref string) string {
+
+	// Parse the reference, leaving it alone if it's already absolute
+	refUrl, err := url.Parse(ref)
+	if err != nil || refUrl.IsAbs() {
+		return ref
+	}
+
+	// Parse the base URL, which must be absolute to resolve against
+	baseUrl, err := url.Parse(base)
+	if err != nil || !baseUrl.IsAbs() {
+		return ref
+	}
+
+	// Resolve the reference against the base
+	return baseUrl.ResolveReference(refUrl).String()
+
+}
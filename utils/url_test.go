@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestAbsoluteUrl(t *testing.T) {
+	type urlTest struct {
+		base   string
+		ref    string
+		output string
+	}
+	testCases := []urlTest{
+		{"https://api.example.com", "/uploads/a.png", "https://api.example.com/uploads/a.png"},
+		{"https://api.example.com/", "/uploads/a.png", "https://api.example.com/uploads/a.png"},
+		{"https://example.com/api/", "/uploads/a.png", "https://example.com/uploads/a.png"},
+		{"https://api.example.com", "https://media.example.com/a.png", "https://media.example.com/a.png"},
+		{"", "/uploads/a.png", "/uploads/a.png"},
+		{"/relative", "/uploads/a.png", "/uploads/a.png"},
+	}
+	for _, tc := range testCases {
+		if output := AbsoluteUrl(tc.base, tc.ref); output != tc.output {
+			t.Errorf("AbsoluteUrl(%q, %q) = %q, expected %q", tc.base, tc.ref, output, tc.output)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// NormalizeEmail trims and lowercases an email address so that it can be compared and stored consistently
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail checks if an email address is valid to be used for an account
+func ValidateEmail(email string) error {
+
+	// If the email is too long to be stored or delivered
+	if len(email) > 254 {
+		return errors.New("email address must not be longer than 254 characters")
+	}
+
+	// Parse the address, which must be a bare address without a display name
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("email address is not valid")
+	}
+
+	// Return no error otherwise
+	return nil
+
+}
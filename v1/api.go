@@ -9,26 +9,46 @@ import (
 
 // Server is the API server instance
 type Server struct {
-	PlatformTitle       string
-	MainCreatorUsername string
-	SiteConfigService   *services.SiteConfigService
-	AccountsService     *services.AccountsService
-	AuthTokensService   *services.AuthTokensService
-	MembershipService   *services.MembershipService
-	CreatorsService     *services.CreatorsService
-	RtmpAuthService     *services.RtmpAuthService
-	StreamsService      *services.StreamsService
-	TelegramService     *services.TelegramService
-	BrowserNotifier     *services.BrowserNotifier
-	TelegramNotifier    *services.TelegramNotifier
-	Notifier            services.Notifier
+	PlatformTitle            string
+	MainCreatorUsername      string
+	PublicUrl                string
+	SiteConfigService        *services.SiteConfigService
+	AccountsService          *services.AccountsService
+	EmailVerificationService *services.EmailVerificationService
+	PasswordResetService     *services.PasswordResetService
+	AuthTokensService        *services.AuthTokensService
+	SessionsService          *services.SessionsService
+	LoginThrottleService     *services.LoginThrottleService
+	OidcService              *services.OidcService
+	TotpService              *services.TotpService
+	MembershipService        *services.MembershipService
+	InvitesService           *services.InvitesService
+	ImagesService            *services.ImagesService
+	CreatorsService          *services.CreatorsService
+	ApiKeysService           *services.ApiKeysService
+	RtmpAuthService          *services.RtmpAuthService
+	IngestNodesService       *services.IngestNodesService
+	IngestSettingsService    *services.IngestSettingsService
+	ViewerCountsService      *services.ViewerCountsService
+	ViewerAnalyticsService   *services.ViewerAnalyticsService
+	PlaybackService          *services.PlaybackService
+	StreamsService           *services.StreamsService
+	TelegramService          *services.TelegramService
+	BrowserNotifier          *services.BrowserNotifier
+	TelegramNotifier         *services.TelegramNotifier
+	AuditService             *services.AuditService
+	Notifier                 services.Notifier
 }
 
 // Setup mounts the API server to the given group
 func (s *Server) Setup(g *gin.RouterGroup) {
 
 	// Register middleware for all routes
-	g.Use(middleware.CheckAuth(s.AuthTokensService))
+	g.Use(middleware.CheckAuth(
+		s.AuthTokensService,
+		s.SessionsService,
+		s.ApiKeysService,
+	))
 
 	// Register all of the public hooks that require no authentication
 	s.setupPublicHooks(g)
@@ -36,6 +56,12 @@ func (s *Server) Setup(g *gin.RouterGroup) {
 	// Register RTMP hooks (called by the privileged RTMP server with a passcode)
 	s.setupRtmpHooks(g.Group("rtmp"))
 
+	// Register studio hooks (called by accounts or with API keys)
+	s.setupStudioHooks(g.Group("studio"))
+
+	// Register platform admin hooks
+	s.setupAdminHooks(g.Group("admin"))
+
 	// Register authenticated hooks
 	s.setupAuthenticatedHooks(g)
 
@@ -53,15 +79,78 @@ func (s *Server) setupPublicHooks(g *gin.RouterGroup) {
 	))
 	g.POST("/auth/login", hooks.AuthLogin(
 		s.AccountsService,
+		s.LoginThrottleService,
+		s.AuthTokensService,
+		s.SessionsService,
+		s.MembershipService,
+	))
+	g.POST("/auth/login/totp", hooks.AuthLoginTotp(
+		s.LoginThrottleService,
+		s.AuthTokensService,
+		s.SessionsService,
+		s.TotpService,
+		s.MembershipService,
+	))
+	g.POST("/auth/oidc/start", hooks.AuthOidcStart(
+		s.OidcService,
+	))
+	g.POST("/auth/oidc/callback", hooks.AuthOidcCallback(
+		s.OidcService,
+		s.LoginThrottleService,
 		s.AuthTokensService,
+		s.SessionsService,
 		s.MembershipService,
 	))
+	g.POST("/auth/refresh", hooks.AuthRefresh(
+		s.SessionsService,
+		s.MembershipService,
+	))
+	g.POST("/auth/register", hooks.AuthRegister(
+		s.EmailVerificationService,
+	))
+	g.POST("/auth/verify-email", hooks.AuthVerifyEmail(
+		s.EmailVerificationService,
+		s.AuthTokensService,
+		s.SessionsService,
+		s.MembershipService,
+	))
+	g.POST("/auth/verify-email/resend", hooks.AuthResendVerification(
+		s.AccountsService,
+		s.EmailVerificationService,
+	))
+	g.POST("/auth/password/forgot", hooks.AuthForgotPassword(
+		s.PasswordResetService,
+	))
+	g.POST("/auth/password/reset", hooks.AuthResetPassword(
+		s.PasswordResetService,
+	))
 	g.POST("/creator/get-meta", hooks.GetCreatorMeta(
 		s.CreatorsService,
 		s.StreamsService,
 	))
 	g.POST("/stream/get-meta", hooks.GetStreamMeta(
 		s.StreamsService,
+		s.MembershipService,
+		s.LoginThrottleService,
+		s.PlaybackService,
+	))
+	g.POST("/stream/playback", hooks.GetStreamPlayback(
+		s.StreamsService,
+		s.MembershipService,
+		s.LoginThrottleService,
+		s.PlaybackService,
+	))
+	g.POST("/stream/viewer/join", hooks.StreamViewerJoin(
+		s.StreamsService,
+		s.ViewerAnalyticsService,
+	))
+	g.POST("/stream/viewer/heartbeat", hooks.StreamViewerHeartbeat(
+		s.StreamsService,
+		s.ViewerAnalyticsService,
+	))
+	g.POST("/stream/viewer/leave", hooks.StreamViewerLeave(
+		s.StreamsService,
+		s.ViewerAnalyticsService,
 	))
 	g.POST("/notifications/browser/state", hooks.BrowserNotificationsState(
 		s.BrowserNotifier,
@@ -90,9 +179,78 @@ func (s *Server) setupRtmpHooks(g *gin.RouterGroup) {
 	// Register RTMP-only hooks here
 	g.POST("/stream/get-config", hooks.RtmpGetStreamConfig(
 		s.StreamsService,
+		s.IngestSettingsService,
+	))
+	g.POST("/node/heartbeat", hooks.RtmpNodeHeartbeat(
+		s.IngestNodesService,
+	))
+	g.POST("/viewers/report", hooks.RtmpReportViewers(
+		s.ViewerCountsService,
 	))
 	g.POST("/stream/set-streaming", hooks.RtmpSetStreaming(
 		s.StreamsService,
+		s.Notifier,
+		s.AuditService,
+		s.PublicUrl,
+	))
+
+}
+
+// setupAdminHooks mounts API hooks for operating the platform, which only platform admins may call
+func (s *Server) setupAdminHooks(g *gin.RouterGroup) {
+
+	// Require an admin account for these hooks
+	g.Use(middleware.RequireLogin())
+	g.Use(middleware.RequireAdmin())
+
+	// Register admin API routes
+	g.POST("/accounts/list", hooks.AdminListAccounts(
+		s.AccountsService,
+	))
+	g.POST("/accounts/set-disabled", hooks.AdminSetAccountDisabled(
+		s.AccountsService,
+		s.SessionsService,
+		s.AuditService,
+	))
+	g.POST("/accounts/impersonate", hooks.AdminImpersonateAccount(
+		s.AccountsService,
+		s.SessionsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/creators/list", hooks.AdminListCreators(
+		s.CreatorsService,
+	))
+	g.POST("/creators/create", hooks.AdminCreateCreator(
+		s.AccountsService,
+		s.CreatorsService,
+		s.AuditService,
+	))
+	g.POST("/streams/force-end", hooks.AdminForceEndStream(
+		s.StreamsService,
+		s.AuditService,
+	))
+	g.POST("/site/rotate-vapid-keys", hooks.AdminRotateVapidKeys(
+		s.BrowserNotifier,
+		s.AuditService,
+	))
+	g.POST("/ingest-nodes/list", hooks.AdminListIngestNodes(
+		s.RtmpAuthService,
+	))
+	g.POST("/ingest-nodes/create", hooks.AdminCreateIngestNode(
+		s.RtmpAuthService,
+		s.AuditService,
+	))
+	g.POST("/ingest-nodes/set-playback-url", hooks.AdminSetIngestNodePlaybackUrl(
+		s.RtmpAuthService,
+		s.AuditService,
+	))
+	g.POST("/ingest-nodes/revoke", hooks.AdminRevokeIngestNode(
+		s.RtmpAuthService,
+		s.AuditService,
+	))
+	g.POST("/audit/list", hooks.AdminListAuditEvents(
+		s.AuditService,
 	))
 
 }
@@ -103,44 +261,248 @@ func (s *Server) setupAuthenticatedHooks(g *gin.RouterGroup) {
 	// Require login for everything after this
 	g.Use(middleware.RequireLogin())
 
-	// Register authenticated API routes
+	// Register authenticated API routes. Admins impersonating an account can't change its security
 	g.POST("/auth/whoami", hooks.AuthWhoAmI(
-		s.AuthTokensService,
 		s.MembershipService,
 	))
-	g.POST("/studio/members/add", hooks.StudioAddMember(
-		s.AccountsService,
+	g.POST("/auth/invites/accept", hooks.AuthAcceptInvite(
+		s.InvitesService,
+		s.AuditService,
+	))
+	g.POST("/auth/logout", hooks.AuthLogout(
+		s.SessionsService,
+	))
+	g.POST("/auth/sessions/list", hooks.AuthListSessions(
+		s.SessionsService,
+	))
+	g.POST("/auth/sessions/revoke", middleware.RequireOwnSession(), hooks.AuthRevokeSession(
+		s.SessionsService,
+		s.AuditService,
+	))
+	g.POST("/auth/totp/enroll", middleware.RequireOwnSession(), hooks.AuthTotpEnroll(
+		s.TotpService,
+	))
+	g.POST("/auth/totp/confirm", middleware.RequireOwnSession(), hooks.AuthTotpConfirm(
+		s.TotpService,
+		s.AuditService,
+	))
+	g.POST("/auth/totp/disable", middleware.RequireOwnSession(), hooks.AuthTotpDisable(
+		s.TotpService,
+		s.AuditService,
+	))
+	g.POST("/auth/totp/recovery-codes/regenerate", middleware.RequireOwnSession(), hooks.AuthTotpRegenerateRecoveryCodes(
+		s.TotpService,
+		s.AuditService,
+	))
+
+}
+
+// setupStudioHooks mounts API hooks for managing creator profiles. These accept either account
+// authentication or an API key for the profile
+func (s *Server) setupStudioHooks(g *gin.RouterGroup) {
+
+	// Require login or an API key for these hooks
+	g.Use(middleware.RequireStudioAuth())
+
+	// Register studio API routes
+	g.POST("/creator/create", middleware.RequireLogin(), hooks.StudioCreateCreator(
+		s.CreatorsService,
+		s.AuditService,
+	))
+	g.POST("/creator/get", hooks.StudioGetCreator(
+		s.CreatorsService,
+		s.MembershipService,
+	))
+	g.POST("/creator/update", hooks.StudioUpdateCreator(
+		s.CreatorsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/creator/upload-image", hooks.StudioUploadCreatorImage(
+		s.CreatorsService,
+		s.MembershipService,
+		s.ImagesService,
+		s.AuditService,
+	))
+	g.POST("/creator/analytics", hooks.StudioGetCreatorAnalytics(
 		s.CreatorsService,
 		s.MembershipService,
+		s.ViewerAnalyticsService,
 	))
-	g.POST("/studio/members/list", hooks.StudioListMembers(
+	g.POST("/creator/ingest-settings/get", hooks.StudioGetCreatorIngestSettings(
 		s.CreatorsService,
 		s.MembershipService,
+		s.IngestSettingsService,
 	))
-	g.POST("/studio/stream/set-status", hooks.StudioSetStreamStatus(
+	g.POST("/creator/ingest-settings/set", hooks.StudioSetCreatorIngestSettings(
+		s.CreatorsService,
+		s.MembershipService,
+		s.IngestSettingsService,
+		s.AuditService,
+	))
+	g.POST("/creator/stream-key/reveal", hooks.StudioRevealCreatorStreamKey(
+		s.CreatorsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/creator/stream-key/rotate", hooks.StudioRotateCreatorStreamKey(
+		s.CreatorsService,
+		s.MembershipService,
+		s.StreamsService,
+		s.AuditService,
+	))
+	g.POST("/creator/stream-key/revoke", hooks.StudioRevokeCreatorStreamKey(
+		s.CreatorsService,
+		s.MembershipService,
+		s.StreamsService,
+		s.AuditService,
+	))
+	g.POST("/creator/delete", middleware.RequireLogin(), middleware.RequireOwnSession(), hooks.StudioDeleteCreator(
+		s.CreatorsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/members/invite", hooks.StudioInviteMember(
+		s.CreatorsService,
+		s.MembershipService,
+		s.InvitesService,
+		s.AuditService,
+	))
+	g.POST("/members/invites/list", hooks.StudioListInvites(
+		s.CreatorsService,
+		s.MembershipService,
+		s.InvitesService,
+	))
+	g.POST("/members/invites/revoke", hooks.StudioRevokeInvite(
+		s.CreatorsService,
+		s.MembershipService,
+		s.InvitesService,
+		s.AuditService,
+	))
+	g.POST("/members/list", hooks.StudioListMembers(
+		s.CreatorsService,
+		s.MembershipService,
+	))
+	g.POST("/members/remove", hooks.StudioRemoveMember(
+		s.CreatorsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/members/set-role", hooks.StudioSetMemberRole(
+		s.CreatorsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/members/transfer-ownership", middleware.RequireLogin(), middleware.RequireOwnSession(), hooks.StudioTransferOwnership(
+		s.CreatorsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/audit/list", middleware.RequireLogin(), hooks.StudioListAuditEvents(
+		s.CreatorsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/security/login-attempts", middleware.RequireLogin(), hooks.StudioListLoginAttempts(
+		s.CreatorsService,
+		s.MembershipService,
+		s.LoginThrottleService,
+	))
+	g.POST("/stream/set-status", hooks.StudioSetStreamStatus(
 		s.StreamsService,
 		s.MembershipService,
 		s.Notifier,
+		s.AuditService,
+		s.PublicUrl,
 	))
-	g.POST("/studio/stream/get", hooks.StudioGetStream(
+	g.POST("/stream/analytics", hooks.StudioGetStreamAnalytics(
+		s.StreamsService,
+		s.MembershipService,
+		s.ViewerAnalyticsService,
+		s.ViewerCountsService,
+	))
+	g.POST("/stream/status-history", hooks.StudioGetStreamStatusHistory(
+		s.StreamsService,
+		s.MembershipService,
+	))
+	g.POST("/stream/get", hooks.StudioGetStream(
 		s.CreatorsService,
 		s.StreamsService,
 		s.MembershipService,
+		s.IngestNodesService,
 	))
-	g.POST("/studio/stream/create", hooks.StudioCreateStream(
+	g.POST("/stream/create", hooks.StudioCreateStream(
 		s.CreatorsService,
 		s.StreamsService,
 		s.MembershipService,
+		s.IngestNodesService,
+		s.AuditService,
 	))
-	g.POST("/studio/stream/update", hooks.StudioUpdateStream(
+	g.POST("/stream/update", hooks.StudioUpdateStream(
 		s.CreatorsService,
 		s.StreamsService,
 		s.MembershipService,
+		s.IngestNodesService,
+		s.AuditService,
+	))
+	g.POST("/stream/ingest-settings/get", hooks.StudioGetStreamIngestSettings(
+		s.StreamsService,
+		s.MembershipService,
+		s.IngestSettingsService,
+	))
+	g.POST("/stream/ingest-settings/set", hooks.StudioSetStreamIngestSettings(
+		s.StreamsService,
+		s.MembershipService,
+		s.IngestSettingsService,
+		s.AuditService,
+	))
+	g.POST("/stream/reveal-key", hooks.StudioRevealStreamKey(
+		s.StreamsService,
+		s.MembershipService,
+		s.AuditService,
 	))
-	g.POST("/studio/streams/list", hooks.StudioListStreams(
+	g.POST("/stream/rotate-key", hooks.StudioRotateStreamKey(
+		s.StreamsService,
+		s.MembershipService,
+		s.AuditService,
+	))
+	g.POST("/stream/upload-thumbnail", hooks.StudioUploadStreamThumbnail(
+		s.StreamsService,
+		s.MembershipService,
+		s.ImagesService,
+		s.AuditService,
+	))
+	g.POST("/streams/list", hooks.StudioListStreams(
 		s.CreatorsService,
 		s.StreamsService,
 		s.MembershipService,
+		s.IngestNodesService,
+	))
+	g.POST("/notifications/send", hooks.StudioSendNotification(
+		s.CreatorsService,
+		s.MembershipService,
+		s.Notifier,
+		s.AuditService,
+	))
+
+	// Managing API keys requires logging in, so that a key can't be used to mint other keys, and can't be
+	// done while impersonating, since keys outlast the impersonation
+	g.POST("/api-keys/create", middleware.RequireLogin(), middleware.RequireOwnSession(), hooks.StudioCreateApiKey(
+		s.CreatorsService,
+		s.MembershipService,
+		s.ApiKeysService,
+		s.AuditService,
+	))
+	g.POST("/api-keys/list", middleware.RequireLogin(), middleware.RequireOwnSession(), hooks.StudioListApiKeys(
+		s.CreatorsService,
+		s.MembershipService,
+		s.ApiKeysService,
+	))
+	g.POST("/api-keys/revoke", middleware.RequireLogin(), middleware.RequireOwnSession(), hooks.StudioRevokeApiKey(
+		s.CreatorsService,
+		s.MembershipService,
+		s.ApiKeysService,
+		s.AuditService,
 	))
 
 }
@@ -0,0 +1,223 @@
+package hooks
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	v1utils "github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminListAccountsReq struct {
+	Query  string `json:"query"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+func AdminListAccounts(
+	accountsService *services.AccountsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminListAccountsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Search for the accounts
+		accounts, total, err := accountsService.SearchAccounts(req.Query, req.Offset, req.Limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the accounts
+		accountsSer := make([]map[string]interface{}, len(accounts))
+		for i, account := range accounts {
+			accountsSer[i] = serializeAccountForAdmin(account)
+		}
+
+		// Respond with the accounts
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"accounts": accountsSer,
+				"total":    total,
+			},
+		})
+
+	}
+}
+
+type AdminSetAccountDisabledReq struct {
+	AccountID uint64 `json:"account_id"`
+	Disabled  bool   `json:"disabled"`
+	Reason    string `json:"reason"`
+}
+
+func AdminSetAccountDisabled(
+	accountsService *services.AccountsService,
+	sessionsService *services.SessionsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminSetAccountDisabledReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Admins can't lock themselves out
+		admin := v1utils.CtxGetAccount(c)
+		if req.AccountID == admin.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot disable your own account"})
+			return
+		}
+
+		// Get the account
+		account, err := accountsService.GetByID(req.AccountID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if account == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "account not found"})
+			return
+		}
+
+		// Update the account
+		if err := accountsService.SetDisabled(account, req.Disabled); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Log the account out everywhere it's disabled
+		if req.Disabled {
+			if err := sessionsService.RevokeAllSessions(account.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		// Record the change in the audit log
+		action := models.AuditAction_AdminAccountEnable
+		if req.Disabled {
+			action = models.AuditAction_AdminAccountDisable
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     action,
+			TargetType: "account",
+			TargetID:   auditTargetID(account.ID),
+			Details: map[string]interface{}{
+				"reason": strings.TrimSpace(req.Reason),
+			},
+		})
+
+		// Return the updated account
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeAccountForAdmin(account),
+		})
+
+	}
+}
+
+type AdminImpersonateAccountReq struct {
+	AccountID uint64 `json:"account_id"`
+	Reason    string `json:"reason"`
+	Device    string `json:"device"`
+}
+
+func AdminImpersonateAccount(
+	accountsService *services.AccountsService,
+	sessionsService *services.SessionsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminImpersonateAccountReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Every impersonation needs a reason, for the audit log
+		reason := strings.TrimSpace(req.Reason)
+		if len(reason) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a reason is required to impersonate an account"})
+			return
+		}
+
+		// Get the account to impersonate
+		admin := v1utils.CtxGetAccount(c)
+		if req.AccountID == admin.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot impersonate your own account"})
+			return
+		}
+		account, err := accountsService.GetByID(req.AccountID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if account == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "account not found"})
+			return
+		}
+
+		// Start a short-lived session on the account, on behalf of the admin
+		tokens, err := sessionsService.CreateImpersonationSession(account, admin, sessionClient(c, req.Device))
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Record the impersonation in the audit log. If it can't be recorded, the session is revoked
+		// so that nothing happens without a trace
+		err = auditService.Record(auditActor(c), &services.AuditEntry{
+			Action:     models.AuditAction_AdminImpersonate,
+			TargetType: "account",
+			TargetID:   auditTargetID(account.ID),
+			Details: map[string]interface{}{
+				"reason":     reason,
+				"session_id": tokens.Session.ID,
+			},
+		})
+		if err != nil {
+			sessionsService.RevokeSession(account.ID, tokens.Session.ID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize the whoami info for the impersonated account, with the session's tokens
+		whoami, err := serializeWhoAmI(account, tokens, membershipService)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the whoami info for the account
+		c.JSON(http.StatusOK, gin.H{
+			"data": whoami,
+		})
+
+	}
+}
+
+func serializeAccountForAdmin(account *models.Account) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             account.ID,
+		"email":          account.Email,
+		"is_admin":       account.IsAdmin,
+		"email_verified": account.IsEmailVerified(),
+		"totp_enabled":   account.IsTotpEnabled(),
+		"disabled_date":  utils.FlattenNullTimeSec(account.DisabledDate),
+		"created_date":   account.CreatedDate.Unix(),
+	}
+}
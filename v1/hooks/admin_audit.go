@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminListAuditEventsReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	AccountID uint64 `json:"account_id"`
+	Action    string `json:"action"`
+	BeforeID  uint64 `json:"before_id"`
+	Limit     int    `json:"limit"`
+}
+
+func AdminListAuditEvents(
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminListAuditEventsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the matching events
+		events, nextBeforeID, err := auditService.GetEvents(&services.AuditFilter{
+			CreatorProfileID: req.CreatorID,
+			ActorAccountID:   req.AccountID,
+			Action:           req.Action,
+			BeforeID:         req.BeforeID,
+			Limit:            req.Limit,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the events
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeAuditEvents(events, nextBeforeID),
+		})
+
+	}
+}
@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminListCreatorsReq struct {
+	Query  string `json:"query"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+func AdminListCreators(
+	creatorsService *services.CreatorsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminListCreatorsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Search for the creators
+		creators, total, err := creatorsService.SearchCreators(req.Query, req.Offset, req.Limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the creators
+		creatorsSer := make([]map[string]interface{}, len(creators))
+		for i, creator := range creators {
+			creatorsSer[i] = serializeCreatorForStudio(creator)
+		}
+
+		// Respond with the creators
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"creators": creatorsSer,
+				"total":    total,
+			},
+		})
+
+	}
+}
+
+type AdminCreateCreatorReq struct {
+	OwnerAccountID uint64                        `json:"owner_account_id"`
+	Options        services.CreateCreatorOptions `json:"options"`
+}
+
+func AdminCreateCreator(
+	accountsService *services.AccountsService,
+	creatorsService *services.CreatorsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminCreateCreatorReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the account that will own the profile
+		owner, err := accountsService.GetByID(req.OwnerAccountID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if owner == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "owner account not found"})
+			return
+		}
+
+		// Create the profile
+		creator, err := creatorsService.CreateCreator(owner.ID, &req.Options)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Record the new profile in the audit log
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_AdminCreatorCreate,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			Details: map[string]interface{}{
+				"username":         creator.Username,
+				"owner_account_id": owner.ID,
+			},
+		})
+
+		// Return the profile
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeCreatorForStudio(creator),
+		})
+
+	}
+}
@@ -0,0 +1,185 @@
+package hooks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func AdminListIngestNodes(
+	rtmpAuthService *services.RtmpAuthService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the nodes
+		nodes, err := rtmpAuthService.GetNodes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the nodes
+		nodesSer := make([]map[string]interface{}, len(nodes))
+		for i, node := range nodes {
+			nodesSer[i] = serializeIngestNode(node)
+		}
+
+		// Respond with the nodes
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"nodes": nodesSer,
+			},
+		})
+
+	}
+}
+
+type AdminCreateIngestNodeReq struct {
+	Name                string   `json:"name"`
+	IngestUrl           string   `json:"ingest_url"`
+	PlaybackUrlTemplate string   `json:"playback_url_template"`
+	AllowedCidrs        []string `json:"allowed_cidrs"`
+}
+
+func AdminCreateIngestNode(
+	rtmpAuthService *services.RtmpAuthService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminCreateIngestNodeReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Register the node
+		node, secret, err := rtmpAuthService.CreateNode(
+			req.Name,
+			req.IngestUrl,
+			req.PlaybackUrlTemplate,
+			req.AllowedCidrs,
+		)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AdminIngestNodeCreate,
+			TargetType: "ingest_node",
+			TargetID:   auditTargetID(node.ID),
+			After:      serializeIngestNode(node),
+		})
+
+		// Respond with the node, and the secret it signs requests with. This is the only time the
+		// secret is shown
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"node":   serializeIngestNode(node),
+				"secret": secret,
+			},
+		})
+
+	}
+}
+
+type AdminSetIngestNodePlaybackUrlReq struct {
+	NodeID              uint64 `json:"node_id"`
+	PlaybackUrlTemplate string `json:"playback_url_template"`
+}
+
+func AdminSetIngestNodePlaybackUrl(
+	rtmpAuthService *services.RtmpAuthService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminSetIngestNodePlaybackUrlReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Update the node's template
+		node, err := rtmpAuthService.SetNodePlaybackUrlTemplate(req.NodeID, req.PlaybackUrlTemplate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AdminIngestNodeSetPlaybackUrl,
+			TargetType: "ingest_node",
+			TargetID:   auditTargetID(node.ID),
+			After:      serializeIngestNode(node),
+		})
+
+		// Respond with the updated node
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"node": serializeIngestNode(node),
+			},
+		})
+
+	}
+}
+
+type AdminRevokeIngestNodeReq struct {
+	NodeID uint64 `json:"node_id"`
+}
+
+func AdminRevokeIngestNode(
+	rtmpAuthService *services.RtmpAuthService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminRevokeIngestNodeReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Revoke the node
+		node, err := rtmpAuthService.RevokeNode(req.NodeID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AdminIngestNodeRevoke,
+			TargetType: "ingest_node",
+			TargetID:   auditTargetID(node.ID),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+// serializeIngestNode serializes an ingest node for admins. The secret is never included
+func serializeIngestNode(node *models.IngestNode) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                    node.ID,
+		"name":                  node.Name,
+		"identifier":            node.Identifier,
+		"ingest_url":            node.IngestUrl,
+		"playback_url_template": node.PlaybackUrlTemplate,
+		"allowed_cidrs":         node.GetAllowedCidrs(),
+		"online":                node.IsOnline(time.Now()),
+		"capacity":              node.Capacity,
+		"active_streams":        node.ActiveStreams,
+		"last_heartbeat_date":   utils.FlattenNullTimeSec(node.LastHeartbeatDate),
+		"last_seen_date":        utils.FlattenNullTimeSec(node.LastSeenDate),
+		"created_date":          node.CreatedDate.Unix(),
+	}
+}
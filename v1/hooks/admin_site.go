@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRotateVapidKeys(
+	browserNotifier *services.BrowserNotifier,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Rotate the keys, which drops every browser subscription
+		removed, err := browserNotifier.RotateVapidKeys()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Record it in the audit log
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AdminVapidKeysRotate,
+			TargetType: "site",
+			Details: map[string]interface{}{
+				"removed_targets": removed,
+			},
+		})
+
+		// Get the new public key
+		keypair, err := browserNotifier.GetVapidKeyPair()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the new public key
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"vapid_public_key": keypair.PublicKey,
+				"removed_targets":  removed,
+			},
+		})
+
+	}
+}
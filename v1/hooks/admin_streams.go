@@ -0,0 +1,66 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminForceEndStreamReq struct {
+	StreamID uint64 `json:"stream_id"`
+	Reason   string `json:"reason"`
+}
+
+func AdminForceEndStream(
+	streamsService *services.StreamsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AdminForceEndStreamReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream
+		stream, err := streamsService.GetStreamByID(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// End the stream
+		previousStatus := stream.Status
+		trigger := streamStatusTrigger(c, models.StreamStatusSource_Admin)
+		if err := streamsService.ForceEndStream(stream, trigger); err != nil {
+			c.JSON(streamStatusErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Record it in the audit log
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_AdminStreamForceEnd,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+			Details: map[string]interface{}{
+				"previous_status": previousStatus,
+				"reason":          req.Reason,
+			},
+		})
+
+		// Return the stream
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeStreamForStudio(stream),
+		})
+
+	}
+}
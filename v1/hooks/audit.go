@@ -0,0 +1,102 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	v1utils "github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// auditActor describes who is sending a request, for recording in the audit log
+func auditActor(c *gin.Context) *services.AuditActor {
+	actor := services.AuditActor{
+		IPAddress: c.ClientIP(),
+	}
+	if account := v1utils.CtxGetAccount(c); account != nil {
+		actor.AccountID = account.ID
+	}
+	if session := v1utils.CtxGetSession(c); session != nil && session.IsImpersonation() {
+		actor.ImpersonatorAccountID = uint64(session.ImpersonatorAccountID.Int64)
+	}
+	if apiKey := v1utils.CtxGetApiKey(c); apiKey != nil {
+		actor.ApiKeyID = apiKey.ID
+	}
+	if node := v1utils.CtxGetIngestNode(c); node != nil {
+		actor.IngestNodeID = node.ID
+	}
+	return &actor
+}
+
+// recordAudit records an action that already happened in the audit log. The action can't be undone at
+// this point, so failures are only logged
+func recordAudit(c *gin.Context, auditService *services.AuditService, entry *services.AuditEntry) {
+	if err := auditService.Record(auditActor(c), entry); err != nil {
+		fmt.Println("Error recording audit event: ", err.Error())
+	}
+}
+
+// auditTargetID formats the identifier of an audited target
+func auditTargetID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+// serializeMemberForAudit snapshots a member of a creator profile for the audit log
+func serializeMemberForAudit(member *models.CreatorProfileMember) map[string]interface{} {
+	if member == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"account_id": member.AccountID,
+		"role":       member.Role,
+	}
+}
+
+// serializeAuditEvents serializes a page of audit events, with the cursor for the next page
+func serializeAuditEvents(events []*models.AuditEvent, nextBeforeID uint64) map[string]interface{} {
+	eventsSer := make([]map[string]interface{}, len(events))
+	for i, event := range events {
+		eventsSer[i] = serializeAuditEvent(event)
+	}
+	var nextBeforeIDSer *uint64
+	if nextBeforeID > 0 {
+		nextBeforeIDSer = &nextBeforeID
+	}
+	return map[string]interface{}{
+		"events":         eventsSer,
+		"next_before_id": nextBeforeIDSer,
+	}
+}
+
+func serializeAuditEvent(event *models.AuditEvent) map[string]interface{} {
+
+	// Decode the changes and details, which are stored as JSON
+	var changes, details interface{}
+	if len(event.Changes) > 0 {
+		json.Unmarshal([]byte(event.Changes), &changes)
+	}
+	if len(event.Details) > 0 {
+		json.Unmarshal([]byte(event.Details), &details)
+	}
+
+	return map[string]interface{}{
+		"id":                      event.ID,
+		"actor_account_id":        utils.FlattenNullInt64(event.ActorAccountID),
+		"impersonator_account_id": utils.FlattenNullInt64(event.ImpersonatorAccountID),
+		"api_key_id":              utils.FlattenNullInt64(event.ApiKeyID),
+		"ingest_node_id":          utils.FlattenNullInt64(event.IngestNodeID),
+		"creator_id":              utils.FlattenNullInt64(event.CreatorProfileID),
+		"action":                  event.Action,
+		"target_type":             event.TargetType,
+		"target_id":               event.TargetID,
+		"changes":                 changes,
+		"details":                 details,
+		"ip_address":              event.IPAddress,
+		"created_date":            event.CreatedDate.Unix(),
+	}
+
+}
@@ -0,0 +1,60 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthAcceptInviteReq struct {
+	Token string `json:"token"`
+}
+
+func AuthAcceptInvite(
+	invitesService *services.InvitesService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthAcceptInviteReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Accept the invite for the logged in account
+		account := utils.CtxGetAccount(c)
+		invite, err := invitesService.AcceptInvite(req.Token, account)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: invite.CreatorProfileID,
+			Action:           models.AuditAction_MemberInviteAccept,
+			TargetType:       "invite",
+			TargetID:         auditTargetID(invite.ID),
+			After: map[string]interface{}{
+				"account_id": account.ID,
+				"role":       invite.Role,
+			},
+		})
+
+		// Return the profile the account joined
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"creator": gin.H{
+					"id":       invite.CreatorProfile.ID,
+					"username": invite.CreatorProfile.Username,
+					"name":     invite.CreatorProfile.Name,
+				},
+				"role": invite.Role,
+			},
+		})
+
+	}
+}
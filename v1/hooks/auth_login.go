@@ -1,8 +1,12 @@
 package hooks
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
 	"github.com/gin-gonic/gin"
 )
@@ -10,11 +14,14 @@ import (
 type AuthLoginReq struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Device   string `json:"device"`
 }
 
 func AuthLogin(
 	accountsService *services.AccountsService,
+	loginThrottleService *services.LoginThrottleService,
 	authTokensService *services.AuthTokensService,
+	sessionsService *services.SessionsService,
 	membershipService *services.MembershipService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -26,6 +33,11 @@ func AuthLogin(
 			return
 		}
 
+		// Make sure the client isn't being throttled for too many failed attempts
+		if !checkLoginThrottle(c, loginThrottleService, req.Email) {
+			return
+		}
+
 		// Find the account with the provided email and password
 		account, err := accountsService.FindByLogin(
 			req.Email,
@@ -36,18 +48,54 @@ func AuthLogin(
 			return
 		}
 		if account == nil {
+
+			// Record the failure against the account, if there is one with the email
+			target, err := accountsService.GetByEmail(req.Email)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			result := models.LoginAttemptResult_BadPassword
+			if target == nil {
+				result = models.LoginAttemptResult_UnknownEmail
+			}
+			recordLoginAttempt(c, loginThrottleService, target, req.Email, result)
+
 			c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect email or password"})
 			return
+
 		}
 
-		// Serialize the whoami info
-		whoami, err := serializeWhoAmI(
+		// Unverified accounts cannot log in
+		if !account.IsEmailVerified() {
+			recordLoginAttempt(c, loginThrottleService, account, req.Email, models.LoginAttemptResult_Unverified)
+			c.JSON(http.StatusForbidden, gin.H{"error": "email address has not been verified"})
+			return
+		}
+
+		// Disabled accounts cannot log in either
+		if account.IsDisabled() {
+			recordLoginAttempt(c, loginThrottleService, account, req.Email, models.LoginAttemptResult_Disabled)
+			c.JSON(http.StatusForbidden, gin.H{"error": services.ErrAccountDisabled.Error()})
+			return
+		}
+
+		// The login only counts as a success once a session is issued, which may still require TOTP
+		if !account.IsTotpEnabled() {
+			recordLoginAttempt(c, loginThrottleService, account, req.Email, models.LoginAttemptResult_Success)
+		}
+
+		// Start a session, or issue a challenge for the second factor
+		whoami, err := completeLogin(
+			c,
 			account,
+			req.Device,
 			authTokensService,
+			sessionsService,
 			membershipService,
 		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
@@ -58,3 +106,92 @@ func AuthLogin(
 
 	}
 }
+
+// totpChallengeLifetime is how long the user has to enter their TOTP code after their password
+const totpChallengeLifetime = time.Minute * 5
+
+// completeLogin finishes logging in an account whose credentials were already checked. Accounts with
+// two-factor authentication get a challenge token to exchange for a session at /auth/login/totp, and
+// all other accounts get a session right away
+func completeLogin(
+	c *gin.Context,
+	account *models.Account,
+	device string,
+	authTokensService *services.AuthTokensService,
+	sessionsService *services.SessionsService,
+	membershipService *services.MembershipService,
+) (map[string]interface{}, error) {
+
+	// Disabled accounts don't get a session or a challenge
+	if account.IsDisabled() {
+		return nil, services.ErrAccountDisabled
+	}
+
+	// If TOTP is not enabled, start the session now
+	if !account.IsTotpEnabled() {
+		return startSession(c, account, device, sessionsService, membershipService)
+	}
+
+	// Issue a challenge token for the second step
+	expires := time.Now().Add(totpChallengeLifetime)
+	token, err := authTokensService.CreateChallengeToken(account, expires)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"totp_required":     true,
+		"challenge_token":   token,
+		"challenge_expires": expires.Unix(),
+	}, nil
+
+}
+
+// checkLoginThrottle checks if the client may attempt to log in to the account with the email address.
+// If not, the attempt is recorded and a 429 response is sent
+func checkLoginThrottle(
+	c *gin.Context,
+	loginThrottleService *services.LoginThrottleService,
+	email string,
+) bool {
+
+	// Check how long the client must wait
+	wait, err := loginThrottleService.CheckAllowed(email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	// Reject the attempt
+	recordLoginAttempt(c, loginThrottleService, nil, email, models.LoginAttemptResult_Throttled)
+	seconds := int64(wait.Seconds()) + 1
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "too many failed login attempts, try again later",
+		"retry_after": seconds,
+	})
+	return false
+
+}
+
+// recordLoginAttempt records the result of a login attempt from the client
+func recordLoginAttempt(
+	c *gin.Context,
+	loginThrottleService *services.LoginThrottleService,
+	account *models.Account,
+	email string,
+	result string,
+) {
+	err := loginThrottleService.RecordAttempt(
+		account,
+		email,
+		c.ClientIP(),
+		c.GetHeader("User-Agent"),
+		result,
+	)
+	if err != nil {
+		fmt.Println("Error recording login attempt: ", err.Error())
+	}
+}
@@ -0,0 +1,83 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthLoginTotpReq struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+	Device         string `json:"device"`
+}
+
+func AuthLoginTotp(
+	loginThrottleService *services.LoginThrottleService,
+	authTokensService *services.AuthTokensService,
+	sessionsService *services.SessionsService,
+	totpService *services.TotpService,
+	membershipService *services.MembershipService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthLoginTotpReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the account that passed the first step
+		account, err := authTokensService.GetAccountForChallengeToken(req.ChallengeToken)
+		if err != nil || account == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "login challenge is invalid or has expired"})
+			return
+		}
+
+		// Make sure the client isn't being throttled for too many failed attempts
+		if !checkLoginThrottle(c, loginThrottleService, account.Email) {
+			return
+		}
+
+		// Check the TOTP code, or a recovery code in its place
+		var ok bool
+		if len(req.RecoveryCode) > 0 {
+			ok, err = totpService.VerifyRecoveryCode(account, req.RecoveryCode)
+		} else {
+			ok, err = totpService.Verify(account, req.Code)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			recordLoginAttempt(c, loginThrottleService, account, account.Email, models.LoginAttemptResult_BadTotp)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect two-factor authentication code"})
+			return
+		}
+		recordLoginAttempt(c, loginThrottleService, account, account.Email, models.LoginAttemptResult_Success)
+
+		// Start a session and serialize the whoami info
+		whoami, err := startSession(
+			c,
+			account,
+			req.Device,
+			sessionsService,
+			membershipService,
+		)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the whoami info for this account
+		c.JSON(http.StatusOK, gin.H{
+			"data": whoami,
+		})
+
+	}
+}
@@ -0,0 +1,104 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthOidcCallbackReq struct {
+	Code   string `json:"code"`
+	State  string `json:"state"`
+	Device string `json:"device"`
+}
+
+func AuthOidcStart(
+	oidcService *services.OidcService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// If no identity provider is configured
+		if !oidcService.Enabled() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "external login is not configured"})
+			return
+		}
+
+		// Start the login
+		authorizationUrl, err := oidcService.BeginLogin()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the URL to send the user to
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"authorization_url": authorizationUrl,
+			},
+		})
+
+	}
+}
+
+func AuthOidcCallback(
+	oidcService *services.OidcService,
+	loginThrottleService *services.LoginThrottleService,
+	authTokensService *services.AuthTokensService,
+	sessionsService *services.SessionsService,
+	membershipService *services.MembershipService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// If no identity provider is configured
+		if !oidcService.Enabled() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "external login is not configured"})
+			return
+		}
+
+		// Get the request body
+		var req AuthOidcCallbackReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Finish the login with the identity provider
+		account, err := oidcService.CompleteLogin(req.Code, req.State)
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if account.IsDisabled() {
+			recordLoginAttempt(c, loginThrottleService, account, account.Email, models.LoginAttemptResult_Disabled)
+			c.JSON(http.StatusForbidden, gin.H{"error": services.ErrAccountDisabled.Error()})
+			return
+		}
+
+		// The login only counts as a success once a session is issued, which may still require TOTP
+		if !account.IsTotpEnabled() {
+			recordLoginAttempt(c, loginThrottleService, account, account.Email, models.LoginAttemptResult_Success)
+		}
+
+		// Start a session, or ask for the second factor
+		whoami, err := completeLogin(
+			c,
+			account,
+			req.Device,
+			authTokensService,
+			sessionsService,
+			membershipService,
+		)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the whoami info for this account
+		c.JSON(http.StatusOK, gin.H{
+			"data": whoami,
+		})
+
+	}
+}
@@ -0,0 +1,70 @@
+package hooks
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthForgotPasswordReq struct {
+	Email string `json:"email"`
+}
+
+func AuthForgotPassword(
+	passwordResetService *services.PasswordResetService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthForgotPasswordReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Send the reset email. The response is the same whether or not an account exists, so this
+		// can't be used to discover which email addresses have accounts
+		if err := passwordResetService.RequestReset(req.Email); err != nil {
+			fmt.Println("Error requesting password reset: ", err.Error())
+		}
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+type AuthResetPasswordReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func AuthResetPassword(
+	passwordResetService *services.PasswordResetService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthResetPasswordReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Reset the password
+		if _, err := passwordResetService.ResetPassword(req.Token, req.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return an empty response. The user must log in again with the new password
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
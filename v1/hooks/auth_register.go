@@ -0,0 +1,44 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthRegisterReq struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func AuthRegister(
+	emailVerificationService *services.EmailVerificationService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthRegisterReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Create the account, and send it the verification email
+		account, err := emailVerificationService.RegisterAccount(req.Email, req.Password)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the new account
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"id":             account.ID,
+				"email":          account.Email,
+				"email_verified": account.IsEmailVerified(),
+			},
+		})
+
+	}
+}
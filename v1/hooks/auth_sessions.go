@@ -0,0 +1,195 @@
+package hooks
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionClient describes the client sending a request, for recording on its session
+func sessionClient(c *gin.Context, device string) *services.SessionClient {
+	return &services.SessionClient{
+		Device:    device,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+}
+
+// sessionErrorStatus gets the response status for an error starting a session. Disabled accounts are
+// forbidden, and anything else is unexpected
+func sessionErrorStatus(err error) int {
+	if errors.Is(err, services.ErrAccountDisabled) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// startSession creates a new session for an account that just logged in, and serializes the whoami
+// info along with the session's tokens
+func startSession(
+	c *gin.Context,
+	account *models.Account,
+	device string,
+	sessionsService *services.SessionsService,
+	membershipService *services.MembershipService,
+) (map[string]interface{}, error) {
+
+	// Create the session
+	tokens, err := sessionsService.CreateSession(account, sessionClient(c, device))
+	if err != nil {
+		return nil, err
+	}
+
+	// Serialize the whoami info with the tokens
+	return serializeWhoAmI(account, tokens, membershipService)
+
+}
+
+type AuthRefreshReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func AuthRefresh(
+	sessionsService *services.SessionsService,
+	membershipService *services.MembershipService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthRefreshReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Refresh the session
+		tokens, err := sessionsService.RefreshSession(req.RefreshToken, sessionClient(c, ""))
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize the whoami info with the new tokens
+		whoami, err := serializeWhoAmI(
+			tokens.Session.Account,
+			tokens,
+			membershipService,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the whoami info for this account
+		c.JSON(http.StatusOK, gin.H{
+			"data": whoami,
+		})
+
+	}
+}
+
+func AuthLogout(
+	sessionsService *services.SessionsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the account and session sending the request
+		account := utils.CtxGetAccount(c)
+		session := utils.CtxGetSession(c)
+
+		// Revoke the current session
+		if err := sessionsService.RevokeSession(account.ID, session.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+func AuthListSessions(
+	sessionsService *services.SessionsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the account and session sending the request
+		account := utils.CtxGetAccount(c)
+		current := utils.CtxGetSession(c)
+
+		// Get the active sessions on the account
+		sessions, err := sessionsService.GetActiveSessions(account.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the sessions
+		sessionsSer := make([]map[string]interface{}, len(sessions))
+		for i, session := range sessions {
+			sessionsSer[i] = map[string]interface{}{
+				"id":           session.ID,
+				"device":       session.Device,
+				"ip_address":   session.IPAddress,
+				"user_agent":   session.UserAgent,
+				"last_seen":    session.LastSeenDate.Unix(),
+				"created_date": session.CreatedDate.Unix(),
+				"current":      session.ID == current.ID,
+			}
+		}
+
+		// Respond with the sessions
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"sessions": sessionsSer,
+			},
+		})
+
+	}
+}
+
+type AuthRevokeSessionReq struct {
+	SessionID uint64 `json:"session_id"`
+}
+
+func AuthRevokeSession(
+	sessionsService *services.SessionsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthRevokeSessionReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the account sending the request
+		account := utils.CtxGetAccount(c)
+
+		// Revoke the session, which must belong to the account
+		if err := sessionsService.RevokeSession(account.ID, req.SessionID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AccountSessionRevoke,
+			TargetType: "session",
+			TargetID:   auditTargetID(req.SessionID),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
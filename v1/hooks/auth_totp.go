@@ -0,0 +1,196 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func AuthTotpEnroll(
+	totpService *services.TotpService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the account sending the request
+		account := utils.CtxGetAccount(c)
+
+		// Generate a new pending secret
+		enrollment, err := totpService.BeginEnrollment(account)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the secret, to be added to an authenticator app
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"secret":           enrollment.Secret,
+				"provisioning_uri": enrollment.ProvisioningUri,
+			},
+		})
+
+	}
+}
+
+type AuthTotpConfirmReq struct {
+	Code string `json:"code"`
+}
+
+func AuthTotpConfirm(
+	totpService *services.TotpService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthTotpConfirmReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the account sending the request
+		account := utils.CtxGetAccount(c)
+
+		// Enable TOTP with the pending secret
+		recoveryCodes, err := totpService.ConfirmEnrollment(account, req.Code)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AccountTotpEnable,
+			TargetType: "account",
+			TargetID:   auditTargetID(account.ID),
+		})
+
+		// Return the recovery codes. This is the only time they are shown
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"recovery_codes": recoveryCodes,
+			},
+		})
+
+	}
+}
+
+type AuthTotpDisableReq struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func AuthTotpDisable(
+	totpService *services.TotpService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthTotpDisableReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the account sending the request
+		account := utils.CtxGetAccount(c)
+
+		// Require the password as well as a current code. Accounts without a password, such as those
+		// created through external login, may use a recovery code instead
+		var ok bool
+		var err error
+		if account.HasPassword() {
+			if !account.VerifyPassword(req.Password) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect password"})
+				return
+			}
+			ok, err = totpService.Verify(account, req.Code)
+		} else if len(req.RecoveryCode) > 0 {
+			ok, err = totpService.VerifyRecoveryCode(account, req.RecoveryCode)
+		} else {
+			ok, err = totpService.Verify(account, req.Code)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect two-factor authentication code"})
+			return
+		}
+
+		// Disable TOTP
+		if err := totpService.Disable(account); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AccountTotpDisable,
+			TargetType: "account",
+			TargetID:   auditTargetID(account.ID),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+type AuthTotpRegenerateRecoveryCodesReq struct {
+	Code string `json:"code"`
+}
+
+func AuthTotpRegenerateRecoveryCodes(
+	totpService *services.TotpService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthTotpRegenerateRecoveryCodesReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the account sending the request
+		account := utils.CtxGetAccount(c)
+
+		// Require a current code
+		ok, err := totpService.Verify(account, req.Code)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "incorrect two-factor authentication code"})
+			return
+		}
+
+		// Replace the recovery codes
+		recoveryCodes, err := totpService.RegenerateRecoveryCodes(account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			Action:     models.AuditAction_AccountRecoveryCodesRegenerate,
+			TargetType: "account",
+			TargetID:   auditTargetID(account.ID),
+		})
+
+		// Return the new recovery codes
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"recovery_codes": recoveryCodes,
+			},
+		})
+
+	}
+}
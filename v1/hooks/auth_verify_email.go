@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthVerifyEmailReq struct {
+	Token  string `json:"token"`
+	Device string `json:"device"`
+}
+
+func AuthVerifyEmail(
+	emailVerificationService *services.EmailVerificationService,
+	authTokensService *services.AuthTokensService,
+	sessionsService *services.SessionsService,
+	membershipService *services.MembershipService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthVerifyEmailReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Redeem the verification token
+		account, err := emailVerificationService.Verify(req.Token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Log the user in right away. Accounts with TOTP get a challenge instead of a session
+		whoami, err := completeLogin(
+			c,
+			account,
+			req.Device,
+			authTokensService,
+			sessionsService,
+			membershipService,
+		)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return the whoami info for this account, or the challenge
+		c.JSON(http.StatusOK, gin.H{
+			"data": whoami,
+		})
+
+	}
+}
+
+type AuthResendVerificationReq struct {
+	Email string `json:"email"`
+}
+
+func AuthResendVerification(
+	accountsService *services.AccountsService,
+	emailVerificationService *services.EmailVerificationService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req AuthResendVerificationReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Find the account with the email
+		account, err := accountsService.GetByEmail(req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Only send if the account exists and still needs verification. The response is the same
+		// either way, so this can't be used to discover which email addresses have accounts
+		if account != nil && !account.IsEmailVerified() {
+			if err := emailVerificationService.SendVerification(account); err != nil {
+				fmt.Println("Error sending verification email: ", err.Error())
+			}
+		}
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
@@ -3,7 +3,6 @@ package hooks
 import (
 	"errors"
 	"net/http"
-	"time"
 
 	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
@@ -12,7 +11,6 @@ import (
 )
 
 func AuthWhoAmI(
-	authTokensService *services.AuthTokensService,
 	membershipService *services.MembershipService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -20,10 +18,11 @@ func AuthWhoAmI(
 		// Get the account from the request
 		account := utils.CtxGetAccount(c)
 
-		// Serialize the whoami info
+		// Serialize the whoami info. No new tokens are issued here, since the client
+		// refreshes its access token with its refresh token instead
 		whoami, err := serializeWhoAmI(
 			account,
-			authTokensService,
+			nil,
 			membershipService,
 		)
 		if err != nil {
@@ -31,6 +30,11 @@ func AuthWhoAmI(
 			return
 		}
 
+		// Flag sessions where an admin is impersonating the account
+		if session := utils.CtxGetSession(c); session != nil && session.IsImpersonation() {
+			whoami["impersonator_account_id"] = session.ImpersonatorAccountID.Int64
+		}
+
 		// Return the whoami info for this account
 		c.JSON(http.StatusOK, gin.H{
 			"data": whoami,
@@ -39,9 +43,11 @@ func AuthWhoAmI(
 	}
 }
 
+// serializeWhoAmI serializes the info about an account. If tokens were just issued for a session, they
+// are included as well
 func serializeWhoAmI(
 	account *models.Account,
-	authTokensService *services.AuthTokensService,
+	tokens *services.SessionTokens,
 	membershipService *services.MembershipService,
 ) (map[string]interface{}, error) {
 
@@ -50,37 +56,41 @@ func serializeWhoAmI(
 		return nil, errors.New("something went wrong")
 	}
 
-	// Create an authentication token for the account
-	token, err := authTokensService.CreateToken(
-		account,
-		time.Now(),
-		time.Now().Add(time.Hour*24*30),
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get all of the creator profiles on this account
-	creators, err := membershipService.GetCreatorProfiles(account.ID)
+	// Get all of the creator profile memberships on this account
+	members, err := membershipService.GetMembershipsForAccount(account.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Serialize all of the creators
-	creatorsSer := make([]map[string]interface{}, len(creators))
-	for i, creator := range creators {
+	// Serialize all of the creators, with the account's role on each
+	creatorsSer := make([]map[string]interface{}, len(members))
+	for i, member := range members {
 		creatorsSer[i] = map[string]interface{}{
-			"id":       creator.ID,
-			"username": creator.Username,
-			"name":     creator.Name,
+			"id":          member.CreatorProfile.ID,
+			"username":    member.CreatorProfile.Username,
+			"name":        member.CreatorProfile.Name,
+			"role":        member.Role,
+			"permissions": models.GetRolePermissions(member.Role),
 		}
 	}
 
-	// Return the map of whoami info
-	return map[string]interface{}{
+	// Create the map of whoami info
+	whoami := map[string]interface{}{
 		"id":       account.ID,
 		"email":    account.Email,
-		"token":    token,
+		"is_admin": account.IsAdmin,
 		"creators": creatorsSer,
-	}, nil
+	}
+
+	// Add the tokens, if there are any
+	if tokens != nil {
+		whoami["session_id"] = tokens.Session.ID
+		whoami["token"] = tokens.AccessToken
+		whoami["token_expires"] = tokens.AccessTokenExpires.Unix()
+		whoami["refresh_token"] = tokens.RefreshToken
+		if tokens.Session.IsImpersonation() {
+			whoami["impersonator_account_id"] = tokens.Session.ImpersonatorAccountID.Int64
+		}
+	}
+	return whoami, nil
 }
@@ -38,14 +38,14 @@ func GetCreatorMeta(
 			return
 		}
 
-		// Get the currently-live stream
-		liveStream, err := streamsService.GetLiveStreamForCreator(creator)
+		// Get the currently-live stream. Only public streams are shown on the creator's page
+		liveStream, err := streamsService.GetPublicLiveStreamForCreator(creator)
 		if err != nil {
 			fmt.Println("Error fetching live stream: ", err.Error())
 		}
 
 		// Get the next upcoming stream (not yet live)
-		nextStream, err := streamsService.GetNextStreamForCreator(creator)
+		nextStream, err := streamsService.GetNextPublicStreamForCreator(creator)
 		if err != nil {
 			fmt.Println("Error fetching next upcoming stream: ", err.Error())
 		}
@@ -56,7 +56,9 @@ func GetCreatorMeta(
 				"id":          creator.ID,
 				"username":    creator.Username,
 				"name":        creator.Name,
+				"bio":         creator.Bio,
 				"image":       creator.Image,
+				"links":       serializeCreatorLinks(creator.Links),
 				"live_stream": serializeStream(liveStream),
 				"next_stream": serializeStream(nextStream),
 			},
@@ -65,6 +67,17 @@ func GetCreatorMeta(
 	}
 }
 
+func serializeCreatorLinks(links []*models.CreatorProfileLink) []map[string]interface{} {
+	linksSer := make([]map[string]interface{}, len(links))
+	for i, link := range links {
+		linksSer[i] = map[string]interface{}{
+			"label": link.Label,
+			"url":   link.Url,
+		}
+	}
+	return linksSer
+}
+
 func serializeStream(stream *models.Stream) map[string]interface{} {
 	if stream == nil {
 		return nil
@@ -74,8 +87,10 @@ func serializeStream(stream *models.Stream) map[string]interface{} {
 		"identifier":           stream.Identifier,
 		"title":                stream.Title,
 		"status":               stream.Status,
+		"visibility":           stream.Visibility,
 		"scheduled_start_date": stream.ScheduledStartDate.Unix(),
 		"current_viewers":      stream.CurrentViewers,
 		"chatroom_url":         utils.FlattenNullString(stream.ChatRoomUrl),
+		"thumbnail_url":        utils.FlattenNullString(stream.ThumbnailUrl),
 	}
 }
@@ -13,6 +13,7 @@ type RtmpGetStreamConfigReq struct {
 
 func RtmpGetStreamConfig(
 	streamsService *services.StreamsService,
+	ingestSettingsService *services.IngestSettingsService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -24,7 +25,7 @@ func RtmpGetStreamConfig(
 		}
 
 		// Get the details for the stream
-		stream, err := streamsService.GetStreamByStreamKey(req.StreamKey)
+		stream, err := streamsService.ResolveStreamKey(req.StreamKey)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -36,10 +37,26 @@ func RtmpGetStreamConfig(
 			return
 		}
 
+		// Finished streams can't be streamed to again
+		if stream.IsFinished() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Stream has already finished",
+			})
+			return
+		}
+
+		// Get the ingest config for the stream, which the RTMP server enforces
+		config, err := ingestSettingsService.ResolveConfig(stream)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Return a response of data for the stream
 		c.JSON(http.StatusOK, gin.H{
 			"data": gin.H{
 				"stream_id": stream.Identifier,
+				"ingest":    config,
 			},
 		})
 
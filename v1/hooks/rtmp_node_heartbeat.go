@@ -0,0 +1,45 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func RtmpNodeHeartbeat(
+	ingestNodesService *services.IngestNodesService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Only registered nodes can heartbeat
+		node := utils.CtxGetIngestNode(c)
+		if node == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Heartbeats require a registered ingest node"})
+			return
+		}
+
+		// Get the request body
+		var req services.IngestHeartbeat
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Record the heartbeat
+		disconnected, err := ingestNodesService.Heartbeat(node, &req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return a response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"disconnected_streams": disconnected,
+			},
+		})
+
+	}
+}
@@ -0,0 +1,56 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type RtmpReportViewersReq struct {
+	Reports []struct {
+		StreamID string `json:"stream_id"`
+		Viewers  int    `json:"viewers"`
+	} `json:"reports"`
+}
+
+func RtmpReportViewers(
+	viewerCountsService *services.ViewerCountsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Only registered nodes can report viewers, so their reports can be told apart
+		node := utils.CtxGetIngestNode(c)
+		if node == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Viewer reports require a registered ingest node"})
+			return
+		}
+
+		// Get the request body
+		var req RtmpReportViewersReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Record the reports
+		counts := make(map[string]int, len(req.Reports))
+		for _, report := range req.Reports {
+			counts[report.StreamID] = report.Viewers
+		}
+		updated, err := viewerCountsService.ReportViewers(node.ID, counts)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return a response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"updated_streams": updated,
+			},
+		})
+
+	}
+}
@@ -1,8 +1,10 @@
 package hooks
 
 import (
+	"fmt"
 	"net/http"
 
+	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +16,9 @@ type RtmpSetStreamingReq struct {
 
 func RtmpSetStreaming(
 	streamsService *services.StreamsService,
+	notifier services.Notifier,
+	auditService *services.AuditService,
+	publicUrl string,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -36,10 +41,28 @@ func RtmpSetStreaming(
 		}
 
 		// Update the stream status
-		if err := streamsService.UpdateStreaming(stream, req.Streaming); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		before := serializeStreamForStudio(stream)
+		trigger := streamStatusTrigger(c, models.StreamStatusSource_Rtmp)
+		wentLive, err := streamsService.UpdateStreaming(stream, req.Streaming, trigger)
+		if err != nil {
+			c.JSON(streamStatusErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_StreamSetStreaming,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+			Before:           before,
+			After:            serializeStreamForStudio(stream),
+		})
+
+		// If the stream went live, let the subscribers know
+		if wentLive {
+			if err := services.NotifyStreamLive(notifier, stream, publicUrl); err != nil {
+				fmt.Println("Error sending notifications: ", err)
+			}
+		}
 
 		// Return a response of data for the stream
 		c.JSON(http.StatusOK, gin.H{
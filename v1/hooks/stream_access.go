@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// authorizeStreamViewer checks if the request may watch the stream, given its visibility. Members of
+// the stream's creator profile may watch any of its streams. Members-only streams are reported as not
+// found to everyone else, and password-protected streams need the password. Repeated incorrect passwords
+// from the same IP address are throttled. If access is denied, an error response is sent and false is returned
+func authorizeStreamViewer(
+	c *gin.Context,
+	membershipService *services.MembershipService,
+	loginThrottleService *services.LoginThrottleService,
+	stream *models.Stream,
+	password string,
+) bool {
+
+	// Public and unlisted streams can be watched by anyone
+	if !stream.MembersOnly() && !stream.RequiresPassword() {
+		return true
+	}
+
+	// Members of the creator profile can watch all of its streams
+	if account := utils.CtxGetAccount(c); account != nil {
+		member, err := membershipService.IsMember(stream.CreatorProfileID, account.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		if member {
+			return true
+		}
+	}
+
+	// Hide members-only streams from everyone else
+	if stream.MembersOnly() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return false
+	}
+
+	// Check the password of password-protected streams
+	if len(password) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Stream password required"})
+		return false
+	}
+	wait, err := loginThrottleService.CheckStreamPasswordAllowed(stream, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if wait > 0 {
+		seconds := int64(wait.Seconds()) + 1
+		c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "too many incorrect stream passwords, try again later",
+			"retry_after": seconds,
+		})
+		return false
+	}
+	if !stream.VerifyPassword(password) {
+		err := loginThrottleService.RecordStreamPasswordFailure(stream, c.ClientIP(), c.GetHeader("User-Agent"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Incorrect stream password"})
+		return false
+	}
+	return true
+
+}
@@ -0,0 +1,87 @@
+package hooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthorizeStreamViewer(t *testing.T) {
+	db := openTestDB(t, &models.CreatorProfileMember{}, &models.LoginAttempt{})
+	membershipService := &services.MembershipService{DB: db}
+	loginThrottleService := &services.LoginThrottleService{DB: db}
+	member := &models.Account{ID: 1}
+	outsider := &models.Account{ID: 2}
+	db.Create(&models.CreatorProfileMember{AccountID: member.ID, CreatorProfileID: 1})
+	hash, err := utils.HashSharedPassword("letmein")
+	if err != nil {
+		t.Fatalf("error hashing password: %s", err.Error())
+	}
+
+	// authorize checks if a viewer may watch a stream, and gets the response status if they can't
+	authorize := func(stream *models.Stream, account *models.Account, password, ip string) (bool, int) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/stream/playback", nil)
+		c.Request.RemoteAddr = ip + ":1234"
+		if account != nil {
+			c.Set("account", account)
+		}
+		ok := authorizeStreamViewer(c, membershipService, loginThrottleService, stream, password)
+		return ok, w.Code
+	}
+
+	tests := []struct {
+		name       string
+		visibility string
+		account    *models.Account
+		password   string
+		expected   int
+	}{
+		{"public", models.StreamVisibility_Public, nil, "", http.StatusOK},
+		{"unlisted", models.StreamVisibility_Unlisted, nil, "", http.StatusOK},
+		{"members only, as a member", models.StreamVisibility_Members, member, "", http.StatusOK},
+		{"members only, as someone else", models.StreamVisibility_Members, outsider, "", http.StatusNotFound},
+		{"members only, logged out", models.StreamVisibility_Members, nil, "letmein", http.StatusNotFound},
+		{"password, as a member", models.StreamVisibility_Password, member, "", http.StatusOK},
+		{"password, correct", models.StreamVisibility_Password, outsider, "letmein", http.StatusOK},
+		{"password, missing", models.StreamVisibility_Password, nil, "", http.StatusForbidden},
+		{"password, incorrect", models.StreamVisibility_Password, nil, "letmeout", http.StatusForbidden},
+	}
+	for i, test := range tests {
+		stream := &models.Stream{ID: uint64(i + 1), CreatorProfileID: 1, Visibility: test.visibility, PasswordHash: hash}
+		ok, status := authorize(stream, test.account, test.password, "192.0.2.1")
+		if ok != (test.expected == http.StatusOK) || status != test.expected {
+			t.Errorf("%s: expected status %d, got %v with %d", test.name, test.expected, ok, status)
+		}
+	}
+
+	// Repeated incorrect passwords from an IP address are throttled, even once it has the right one
+	stream := &models.Stream{ID: 100, CreatorProfileID: 1, Visibility: models.StreamVisibility_Password, PasswordHash: hash}
+	for i := 0; i < 6; i++ {
+		if _, status := authorize(stream, nil, "letmeout", "192.0.2.2"); status != http.StatusForbidden {
+			t.Fatalf("expected incorrect password %d to be rejected, got %d", i+1, status)
+		}
+	}
+	if _, status := authorize(stream, nil, "letmein", "192.0.2.2"); status != http.StatusTooManyRequests {
+		t.Errorf("expected guesses to be throttled, got %d", status)
+	}
+
+	// Other IP addresses, other streams, and members aren't affected
+	if ok, status := authorize(stream, nil, "letmein", "192.0.2.3"); !ok {
+		t.Errorf("expected another IP address to be allowed, got %d", status)
+	}
+	other := *stream
+	other.ID = 101
+	if ok, status := authorize(&other, nil, "letmein", "192.0.2.2"); !ok {
+		t.Errorf("expected another stream to be allowed, got %d", status)
+	}
+	if ok, status := authorize(stream, member, "", "192.0.2.2"); !ok {
+		t.Errorf("expected a member to be allowed, got %d", status)
+	}
+}
@@ -1,19 +1,24 @@
 package hooks
 
 import (
-	"fmt"
 	"net/http"
 
+	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
 	"github.com/gin-gonic/gin"
 )
 
 type GetStreamMetaReq struct {
 	StreamID string `json:"stream_id"`
+	Password string `json:"password"`
 }
 
 func GetStreamMeta(
 	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	loginThrottleService *services.LoginThrottleService,
+	playbackService *services.PlaybackService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -24,8 +29,75 @@ func GetStreamMeta(
 			return
 		}
 
-		// Log the body
-		fmt.Println("req: ", req)
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Cancelled streams, and streams whose creator profile was deleted, are hidden from the public
+		if stream == nil ||
+			stream.Status == models.StreamStatus_Cancelled ||
+			stream.CreatorProfile == nil ||
+			stream.CreatorProfile.DeletedDate.Valid {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+			return
+		}
+
+		// Make sure the viewer may see the stream
+		if !authorizeStreamViewer(c, membershipService, loginThrottleService, stream, req.Password) {
+			return
+		}
+
+		// Get the URLs the stream can be played from
+		playbackUrls, err := serializePlaybackUrls(playbackService, stream)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the public view of the stream
+		streamSer := serializeStream(stream)
+		streamSer["started_date"] = utils.FlattenNullTimeSec(stream.StartedDate)
+		streamSer["ended_date"] = utils.FlattenNullTimeSec(stream.EndedDate)
+		streamSer["playback_urls"] = playbackUrls
+		streamSer["playback_token_required"] = playbackService.TokensRequired()
+		streamSer["creator"] = serializeCreatorSummary(stream.CreatorProfile)
+		c.JSON(http.StatusOK, gin.H{
+			"data": streamSer,
+		})
+
+	}
+}
+
+// serializePlaybackUrls serializes the URLs a stream can be played from, which are null while it can't be.
+// They're also null when playback requires a token, since each viewer needs their own signed URL
+func serializePlaybackUrls(
+	playbackService *services.PlaybackService,
+	stream *models.Stream,
+) (map[string]interface{}, error) {
+	var hls interface{}
+	if !playbackService.TokensRequired() {
+		url, err := playbackService.GetHlsUrl(stream)
+		if err != nil {
+			return nil, err
+		}
+		if len(url) > 0 {
+			hls = url
+		}
+	}
+	return map[string]interface{}{
+		"hls": hls,
+	}, nil
+}
 
+// serializeCreatorSummary serializes the public details of a creator profile shown alongside its streams
+func serializeCreatorSummary(creator *models.CreatorProfile) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       creator.ID,
+		"username": creator.Username,
+		"name":     creator.Name,
+		"image":    creator.Image,
 	}
 }
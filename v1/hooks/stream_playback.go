@@ -0,0 +1,71 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type GetStreamPlaybackReq struct {
+	StreamID string `json:"stream_id"`
+	ViewerID string `json:"viewer_id"`
+	Password string `json:"password"`
+}
+
+func GetStreamPlayback(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	loginThrottleService *services.LoginThrottleService,
+	playbackService *services.PlaybackService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req GetStreamPlaybackReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream being watched
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil || stream.CreatorProfile == nil || stream.CreatorProfile.DeletedDate.Valid {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+			return
+		}
+
+		// Make sure the viewer may watch the stream
+		if !authorizeStreamViewer(c, membershipService, loginThrottleService, stream, req.Password) {
+			return
+		}
+
+		// Issue the viewer a playback URL
+		playback, err := playbackService.IssuePlayback(stream, req.ViewerID)
+		if err != nil {
+			c.JSON(viewerErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the URL, and the token it's signed with if there is one
+		var token, expires interface{}
+		if len(playback.Token) > 0 {
+			token = playback.Token
+			expires = playback.Expires.Unix()
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"playback_urls": gin.H{
+					"hls": playback.HlsUrl,
+				},
+				"token":         token,
+				"token_expires": expires,
+			},
+		})
+
+	}
+}
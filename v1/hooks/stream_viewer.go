@@ -0,0 +1,134 @@
+package hooks
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StreamViewerReq struct {
+	StreamID string `json:"stream_id"`
+	ViewerID string `json:"viewer_id"`
+}
+
+func StreamViewerJoin(
+	streamsService *services.StreamsService,
+	viewerAnalyticsService *services.ViewerAnalyticsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the stream being watched
+		stream, viewerID, ok := getStreamForViewer(c, streamsService)
+		if !ok {
+			return
+		}
+
+		// Start the viewer's session
+		if err := viewerAnalyticsService.JoinStream(stream, viewerID); err != nil {
+			c.JSON(viewerErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Tell the viewer how often to heartbeat
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"heartbeat_interval": int(services.ViewerHeartbeatInterval.Seconds()),
+			},
+		})
+
+	}
+}
+
+func StreamViewerHeartbeat(
+	streamsService *services.StreamsService,
+	viewerAnalyticsService *services.ViewerAnalyticsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the stream being watched
+		stream, viewerID, ok := getStreamForViewer(c, streamsService)
+		if !ok {
+			return
+		}
+
+		// Extend the viewer's session
+		if err := viewerAnalyticsService.HeartbeatStream(stream, viewerID); err != nil {
+			c.JSON(viewerErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		// Tell the viewer how often to heartbeat
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"heartbeat_interval": int(services.ViewerHeartbeatInterval.Seconds()),
+			},
+		})
+
+	}
+}
+
+func StreamViewerLeave(
+	streamsService *services.StreamsService,
+	viewerAnalyticsService *services.ViewerAnalyticsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the stream being watched
+		stream, viewerID, ok := getStreamForViewer(c, streamsService)
+		if !ok {
+			return
+		}
+
+		// End the viewer's session
+		if err := viewerAnalyticsService.LeaveStream(stream, viewerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+// getStreamForViewer gets the stream and viewer in a viewer session request. If the stream isn't found, an
+// error response is sent and false is returned
+func getStreamForViewer(
+	c *gin.Context,
+	streamsService *services.StreamsService,
+) (*models.Stream, string, bool) {
+
+	// Get the request body
+	var req StreamViewerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+
+	// Get the stream with the identifier
+	stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	if stream == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stream not found"})
+		return nil, "", false
+	}
+	return stream, req.ViewerID, true
+
+}
+
+// viewerErrorStatus gets the response status for an error tracking a viewer. Streams that aren't live
+// conflict with the request, and anything else is a bad request
+func viewerErrorStatus(err error) int {
+	if errors.Is(err, services.ErrStreamNotLive) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// authorizeStudio checks if the request has the permission on the creator profile. Accounts must be
+// members of the profile with a role that grants the permission, and API keys must belong to the
+// profile and have been granted it as a scope. The account that created a key must also still hold
+// the permission. If access is denied, an error response is sent and false is returned
+func authorizeStudio(
+	c *gin.Context,
+	membershipService *services.MembershipService,
+	creatorID uint64,
+	permission string,
+) bool {
+	access, err := studioHasPermission(c, membershipService, creatorID, permission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !access {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return false
+	}
+	return true
+}
+
+// studioHasPermission checks if the request has the permission on the creator profile, without sending
+// a response
+func studioHasPermission(
+	c *gin.Context,
+	membershipService *services.MembershipService,
+	creatorID uint64,
+	permission string,
+) (bool, error) {
+
+	// If the request was made with an API key, it's limited to what its creator may still do
+	if apiKey := utils.CtxGetApiKey(c); apiKey != nil {
+		if apiKey.CreatorProfileID != creatorID || !apiKey.HasScope(permission) {
+			return false, nil
+		}
+		return membershipService.HasPermission(creatorID, apiKey.CreatedByAccountID, permission)
+	}
+
+	// Get the account from the context
+	account := utils.CtxGetAccount(c)
+	if account == nil {
+		return false, nil
+	}
+
+	// Check if the account's role grants the permission
+	return membershipService.HasPermission(creatorID, account.ID, permission)
+
+}
+
+// studioRole gets the role the request acts with on the creator profile, for deciding which members it
+// may manage. API keys act with the current role of the account that created them, but never as the
+// owner, since ownership can't be automated. An empty string is returned if the request has no role on
+// the profile
+func studioRole(
+	c *gin.Context,
+	membershipService *services.MembershipService,
+	creatorID uint64,
+) (string, error) {
+
+	// If the request was made with an API key, act as the account that created it
+	accountID := uint64(0)
+	apiKey := utils.CtxGetApiKey(c)
+	if apiKey != nil {
+		if apiKey.CreatorProfileID != creatorID {
+			return "", nil
+		}
+		accountID = apiKey.CreatedByAccountID
+	} else if account := utils.CtxGetAccount(c); account != nil {
+		accountID = account.ID
+	} else {
+		return "", nil
+	}
+
+	// Get the account's membership
+	member, err := membershipService.GetMember(creatorID, accountID)
+	if err != nil || member == nil {
+		return "", err
+	}
+	if apiKey != nil && member.Role == models.MemberRole_Owner {
+		return models.MemberRole_Admin, nil
+	}
+	return member.Role, nil
+
+}
+
+// studioActorAccountID gets the account responsible for the request. Requests made with an API key are
+// attributed to the account that created the key
+func studioActorAccountID(c *gin.Context) uint64 {
+	if apiKey := utils.CtxGetApiKey(c); apiKey != nil {
+		return apiKey.CreatedByAccountID
+	}
+	if account := utils.CtxGetAccount(c); account != nil {
+		return account.ID
+	}
+	return 0
+}
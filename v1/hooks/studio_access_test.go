@@ -0,0 +1,52 @@
+package hooks
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestStudioApiKeyFollowsCreatorMembership(t *testing.T) {
+	db := openTestDB(t, &models.CreatorProfileMember{})
+	membershipService := &services.MembershipService{DB: db}
+	owner := models.CreatorProfileMember{AccountID: 1, CreatorProfileID: 1, Role: models.MemberRole_Owner}
+	admin := models.CreatorProfileMember{AccountID: 2, CreatorProfileID: 1, Role: models.MemberRole_Admin}
+	db.Create(&owner)
+	db.Create(&admin)
+
+	// withKey creates a request context made with a key created by the account
+	withKey := func(accountID uint64, scopes string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set("api_key", &models.ApiKey{CreatorProfileID: 1, CreatedByAccountID: accountID, Scopes: scopes})
+		return c
+	}
+	check := func(name string, c *gin.Context, permission string, expectedAccess bool, expectedRole string) {
+		access, err := studioHasPermission(c, membershipService, 1, permission)
+		if err != nil || access != expectedAccess {
+			t.Errorf("%s: expected access %v, got %v (err: %v)", name, expectedAccess, access, err)
+		}
+		role, err := studioRole(c, membershipService, 1)
+		if err != nil || role != expectedRole {
+			t.Errorf("%s: expected role %q, got %q (err: %v)", name, expectedRole, role, err)
+		}
+	}
+
+	// Keys are limited to their scopes, and never act as the owner
+	check("owner key", withKey(1, "members:manage"), models.Permission_MembersManage, true, models.MemberRole_Admin)
+	check("unscoped key", withKey(1, "streams:read"), models.Permission_MembersManage, false, models.MemberRole_Admin)
+	check("admin key", withKey(2, "members:manage"), models.Permission_MembersManage, true, models.MemberRole_Admin)
+
+	// A key stops granting what its creator's role no longer does
+	db.Model(&admin).Update("role", models.MemberRole_Moderator)
+	check("downgraded key", withKey(2, "members:manage"), models.Permission_MembersManage, false, models.MemberRole_Moderator)
+	check("downgraded key", withKey(2, "notify:send"), models.Permission_NotifySend, true, models.MemberRole_Moderator)
+
+	// And grants nothing once its creator is removed
+	db.Model(&admin).Update("deleted_date", sql.NullTime{Valid: true, Time: time.Now()})
+	check("removed key", withKey(2, "notify:send"), models.Permission_NotifySend, false, "")
+}
@@ -0,0 +1,223 @@
+package hooks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	v1utils "github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioCreateApiKeyReq struct {
+	CreatorID   uint64   `json:"creator_id"`
+	Name        string   `json:"name"`
+	Scopes      []string `json:"scopes"`
+	ExpiresDate *int64   `json:"expires_date"`
+}
+
+func StudioCreateApiKey(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	apiKeysService *services.ApiKeysService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioCreateApiKeyReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the account has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_ApiKeysManage) {
+			return
+		}
+
+		// A key can't be granted a permission the account doesn't have itself
+		for _, scope := range req.Scopes {
+			if !authorizeStudio(c, membershipService, creator.ID, scope) {
+				return
+			}
+		}
+
+		// Get the expiration date, if there is one
+		var expires *time.Time
+		if req.ExpiresDate != nil {
+			t := time.Unix(*req.ExpiresDate, 0)
+			expires = &t
+		}
+
+		// Create the key
+		account := v1utils.CtxGetAccount(c)
+		apiKey, key, err := apiKeysService.CreateKey(
+			creator.ID,
+			account.ID,
+			req.Name,
+			req.Scopes,
+			expires,
+		)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_ApiKeyCreate,
+			TargetType:       "api_key",
+			TargetID:         auditTargetID(apiKey.ID),
+			After:            serializeApiKey(apiKey),
+		})
+
+		// Return the key. This is the only time the full key is shown
+		keySer := serializeApiKey(apiKey)
+		keySer["key"] = key
+		c.JSON(http.StatusOK, gin.H{
+			"data": keySer,
+		})
+
+	}
+}
+
+type StudioListApiKeysReq struct {
+	CreatorID uint64 `json:"creator_id"`
+}
+
+func StudioListApiKeys(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	apiKeysService *services.ApiKeysService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioListApiKeysReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the account has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_ApiKeysManage) {
+			return
+		}
+
+		// Get all of the keys on the profile
+		keys, err := apiKeysService.GetKeysForCreator(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the keys
+		keysSer := make([]map[string]interface{}, len(keys))
+		for i := range keys {
+			keysSer[i] = serializeApiKey(keys[i])
+		}
+
+		// Respond with the keys
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"api_keys": keysSer,
+			},
+		})
+
+	}
+}
+
+type StudioRevokeApiKeyReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	ApiKeyID  uint64 `json:"api_key_id"`
+}
+
+func StudioRevokeApiKey(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	apiKeysService *services.ApiKeysService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioRevokeApiKeyReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the account has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_ApiKeysManage) {
+			return
+		}
+
+		// Revoke the key
+		if err := apiKeysService.RevokeKey(creator.ID, req.ApiKeyID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_ApiKeyRevoke,
+			TargetType:       "api_key",
+			TargetID:         auditTargetID(req.ApiKeyID),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+func serializeApiKey(apiKey *models.ApiKey) map[string]interface{} {
+	if apiKey == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"id":           apiKey.ID,
+		"name":         apiKey.Name,
+		"prefix":       services.ApiKeyPrefix + apiKey.Prefix,
+		"scopes":       apiKey.GetScopes(),
+		"last_used":    utils.FlattenNullTimeSec(apiKey.LastUsedDate),
+		"expires_date": utils.FlattenNullTimeSec(apiKey.ExpiresDate),
+		"created_date": apiKey.CreatedDate.Unix(),
+	}
+}
@@ -0,0 +1,68 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioListAuditEventsReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	AccountID uint64 `json:"account_id"`
+	Action    string `json:"action"`
+	BeforeID  uint64 `json:"before_id"`
+	Limit     int    `json:"limit"`
+}
+
+func StudioListAuditEvents(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioListAuditEventsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile's audit log
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_AuditRead) {
+			return
+		}
+
+		// Get the matching events on the profile
+		events, nextBeforeID, err := auditService.GetEvents(&services.AuditFilter{
+			CreatorProfileID: creator.ID,
+			ActorAccountID:   req.AccountID,
+			Action:           req.Action,
+			BeforeID:         req.BeforeID,
+			Limit:            req.Limit,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the events
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeAuditEvents(events, nextBeforeID),
+		})
+
+	}
+}
@@ -0,0 +1,224 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioCreateCreatorReq struct {
+	Options services.CreateCreatorOptions `json:"options"`
+}
+
+func StudioCreateCreator(
+	creatorsService *services.CreatorsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioCreateCreatorReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Create the profile, owned by the account
+		account := utils.CtxGetAccount(c)
+		creator, err := creatorsService.CreateCreator(account.ID, &req.Options)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_CreatorCreate,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			After:            serializeCreatorForStudio(creator),
+		})
+
+		// Return the profile
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeCreatorForStudio(creator),
+		})
+
+	}
+}
+
+type StudioGetCreatorReq struct {
+	CreatorID uint64 `json:"creator_id"`
+}
+
+func StudioGetCreator(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioGetCreatorReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Every member can see the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_StreamsRead) {
+			return
+		}
+
+		// Return the profile
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeCreatorForStudio(creator),
+		})
+
+	}
+}
+
+type StudioUpdateCreatorReq struct {
+	CreatorID uint64                  `json:"creator_id"`
+	Updates   services.CreatorUpdates `json:"updates"`
+}
+
+func StudioUpdateCreator(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioUpdateCreatorReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_ProfileManage) {
+			return
+		}
+
+		// Update the profile
+		before := serializeCreatorForStudio(creator)
+		if err := creatorsService.UpdateCreator(creator, &req.Updates); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the updated profile
+		creator, err = creatorsService.GetCreatorByID(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_CreatorUpdate,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			Before:           before,
+			After:            serializeCreatorForStudio(creator),
+		})
+
+		// Return the profile
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeCreatorForStudio(creator),
+		})
+
+	}
+}
+
+type StudioDeleteCreatorReq struct {
+	CreatorID uint64 `json:"creator_id"`
+}
+
+func StudioDeleteCreator(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioDeleteCreatorReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Only the owner can delete the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_ProfileDelete) {
+			return
+		}
+
+		// Delete the profile
+		if err := creatorsService.DeleteCreator(creator); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_CreatorDelete,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			Before:           serializeCreatorForStudio(creator),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+func serializeCreatorForStudio(creator *models.CreatorProfile) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             creator.ID,
+		"username":       creator.Username,
+		"name":           creator.Name,
+		"bio":            creator.Bio,
+		"image":          creator.Image,
+		"links":          serializeCreatorLinks(creator.Links),
+		"has_stream_key": creator.StreamKey.Valid,
+		"created_date":   creator.CreatedDate.Unix(),
+	}
+}
@@ -0,0 +1,102 @@
+package hooks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// creatorAnalyticsDefaultRange is the date range of a creator analytics rollup when none is given
+	creatorAnalyticsDefaultRange = 30 * 24 * time.Hour
+
+	// creatorAnalyticsMaxRange is the longest date range of a creator analytics rollup
+	creatorAnalyticsMaxRange = 366 * 24 * time.Hour
+)
+
+type StudioGetCreatorAnalyticsReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	StartDate int64  `json:"start_date"`
+	EndDate   int64  `json:"end_date"`
+}
+
+func StudioGetCreatorAnalytics(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	viewerAnalyticsService *services.ViewerAnalyticsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioGetCreatorAnalyticsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the date range, which defaults to the last 30 days
+		end := time.Now()
+		if req.EndDate > 0 {
+			end = time.Unix(req.EndDate, 0)
+		}
+		start := end.Add(-creatorAnalyticsDefaultRange)
+		if req.StartDate > 0 {
+			start = time.Unix(req.StartDate, 0)
+		}
+		if !end.After(start) || end.Sub(start) > creatorAnalyticsMaxRange {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date range must be between 1 second and 366 days"})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request may see the profile's analytics
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_AnalyticsRead) {
+			return
+		}
+
+		// Get the rollup
+		rollup, err := viewerAnalyticsService.GetCreatorRollup(creator.ID, start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize the analytics of each stream
+		streamsSer := make([]map[string]interface{}, len(rollup.Streams))
+		for i, result := range rollup.Streams {
+			streamsSer[i] = serializeStreamAnalytics(result.Analytics)
+			streamsSer[i]["stream_id"] = result.Stream.Identifier
+			streamsSer[i]["title"] = result.Stream.Title
+			streamsSer[i]["started_date"] = result.Stream.StartedDate.Time.Unix()
+			streamsSer[i]["final"] = result.Final
+		}
+
+		// Respond with the rollup
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"start_date":      start.Unix(),
+				"end_date":        end.Unix(),
+				"stream_count":    rollup.StreamCount,
+				"peak_viewers":    rollup.PeakViewers,
+				"average_viewers": rollup.AverageViewers,
+				"unique_viewers":  rollup.UniqueViewers,
+				"watch_minutes":   rollup.WatchMinutes,
+				"streams":         streamsSer,
+			},
+		})
+
+	}
+}
@@ -0,0 +1,149 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioCreatorStreamKeyReq struct {
+	CreatorID uint64 `json:"creator_id"`
+}
+
+func StudioRevealCreatorStreamKey(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the creator profile from the request
+		creator, ok := getCreatorForStreamKey(c, creatorsService, membershipService)
+		if !ok {
+			return
+		}
+		if creator.StreamKey.Valid {
+			recordAudit(c, auditService, &services.AuditEntry{
+				CreatorProfileID: creator.ID,
+				Action:           models.AuditAction_CreatorStreamKeyReveal,
+				TargetType:       "creator",
+				TargetID:         auditTargetID(creator.ID),
+			})
+		}
+
+		// Return the key, if the profile has one
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"stream_key": utils.FlattenNullString(creator.StreamKey),
+			},
+		})
+
+	}
+}
+
+func StudioRotateCreatorStreamKey(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	streamsService *services.StreamsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the creator profile from the request
+		creator, ok := getCreatorForStreamKey(c, creatorsService, membershipService)
+		if !ok {
+			return
+		}
+
+		// Create or replace the key
+		if err := streamsService.RotateCreatorStreamKey(creator); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_CreatorStreamKeyRotate,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+		})
+
+		// Return the new key
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"stream_key": utils.FlattenNullString(creator.StreamKey),
+			},
+		})
+
+	}
+}
+
+func StudioRevokeCreatorStreamKey(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	streamsService *services.StreamsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the creator profile from the request
+		creator, ok := getCreatorForStreamKey(c, creatorsService, membershipService)
+		if !ok {
+			return
+		}
+
+		// Remove the key
+		if err := streamsService.RevokeCreatorStreamKey(creator); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_CreatorStreamKeyRevoke,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+// getCreatorForStreamKey gets the creator profile in a request to manage its persistent stream key, and
+// checks that the request may do so. If not, an error response is sent and false is returned
+func getCreatorForStreamKey(
+	c *gin.Context,
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+) (*models.CreatorProfile, bool) {
+
+	// Get the request body
+	var req StudioCreatorStreamKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// Get the creator profile with the identifier
+	creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if creator == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+		return nil, false
+	}
+
+	// Check if the request may manage the profile's stream keys
+	if !authorizeStudio(c, membershipService, creator.ID, models.Permission_StreamsKeys) {
+		return nil, false
+	}
+	return creator, true
+
+}
@@ -0,0 +1,208 @@
+package hooks
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioUploadCreatorImageReq struct {
+	CreatorID uint64 `form:"creator_id"`
+}
+
+func StudioUploadCreatorImage(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	imagesService *services.ImagesService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		limitUploadSize(c)
+		var req StudioUploadCreatorImageReq
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_ProfileManage) {
+			return
+		}
+
+		// Read the uploaded file
+		data, ok := readUploadedImage(c)
+		if !ok {
+			return
+		}
+
+		// Process and store the image
+		before := serializeCreatorForStudio(creator)
+		image, err := imagesService.UploadCreatorImage(creator, studioActorAccountID(c), data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_CreatorUploadImage,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			Before:           before,
+			After:            serializeCreatorForStudio(creator),
+			Details: map[string]interface{}{
+				"image_id": image.ID,
+			},
+		})
+
+		// Return the image
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeImage(image),
+		})
+
+	}
+}
+
+type StudioUploadStreamThumbnailReq struct {
+	StreamID string `form:"stream_id"`
+}
+
+func StudioUploadStreamThumbnail(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	imagesService *services.ImagesService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		limitUploadSize(c)
+		var req StudioUploadStreamThumbnailReq
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// Check if the request has access to the stream's profile
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsWrite) {
+			return
+		}
+
+		// Read the uploaded file
+		data, ok := readUploadedImage(c)
+		if !ok {
+			return
+		}
+
+		// Process and store the image
+		before := serializeStreamForStudio(stream)
+		image, err := imagesService.UploadStreamThumbnail(stream, studioActorAccountID(c), data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_StreamUploadThumbnail,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+			Before:           before,
+			After:            serializeStreamForStudio(stream),
+			Details: map[string]interface{}{
+				"image_id": image.ID,
+			},
+		})
+
+		// Return the image
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeImage(image),
+		})
+
+	}
+}
+
+// limitUploadSize caps the size of the request body, so an oversized upload is cut off instead of
+// being read into memory or onto disk
+func limitUploadSize(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.ImageMaxUploadSize+(1<<20))
+}
+
+// readUploadedImage reads the file uploaded in the "image" form field. If it can't be read, an error
+// response is sent and false is returned
+func readUploadedImage(c *gin.Context) ([]byte, bool) {
+
+	// Open the uploaded file
+	header, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "an image file is required"})
+		return nil, false
+	}
+	if header.Size > services.ImageMaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image file is too large"})
+		return nil, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	defer file.Close()
+
+	// Read the file
+	data, err := io.ReadAll(io.LimitReader(file, services.ImageMaxUploadSize+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(data) > services.ImageMaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "image file is too large"})
+		return nil, false
+	}
+	return data, true
+
+}
+
+func serializeImage(image *models.Image) map[string]interface{} {
+	variantsSer := map[string]interface{}{}
+	for _, variant := range image.Variants {
+		variantsSer[variant.Name] = map[string]interface{}{
+			"url":    variant.Url,
+			"width":  variant.Width,
+			"height": variant.Height,
+		}
+	}
+	imageSer := map[string]interface{}{
+		"id":       image.ID,
+		"variants": variantsSer,
+	}
+	if len(image.Variants) > 0 {
+		imageSer["url"] = image.Variants[0].Url
+	}
+	return imageSer
+}
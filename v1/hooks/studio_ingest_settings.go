@@ -0,0 +1,269 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioGetCreatorIngestSettingsReq struct {
+	CreatorID uint64 `json:"creator_id"`
+}
+
+func StudioGetCreatorIngestSettings(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	ingestSettingsService *services.IngestSettingsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioGetCreatorIngestSettingsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_StreamsRead) {
+			return
+		}
+
+		// Get the settings, and the config they result in
+		settings, err := ingestSettingsService.GetCreatorSettings(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		effective, err := ingestSettingsService.ResolveCreatorConfig(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the settings
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"settings":  settings,
+				"effective": effective,
+			},
+		})
+
+	}
+}
+
+type StudioSetCreatorIngestSettingsReq struct {
+	CreatorID uint64                        `json:"creator_id"`
+	Settings  services.IngestSettingsValues `json:"settings"`
+}
+
+func StudioSetCreatorIngestSettings(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	ingestSettingsService *services.IngestSettingsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioSetCreatorIngestSettingsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request may manage the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_ProfileManage) {
+			return
+		}
+
+		// Replace the settings
+		before, err := ingestSettingsService.GetCreatorSettings(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ingestSettingsService.SetCreatorSettings(creator.ID, &req.Settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_CreatorIngestSettingsSet,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			Before:           before,
+			After:            &req.Settings,
+		})
+
+		// Get the config the settings result in
+		effective, err := ingestSettingsService.ResolveCreatorConfig(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the settings
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"settings":  &req.Settings,
+				"effective": effective,
+			},
+		})
+
+	}
+}
+
+type StudioGetStreamIngestSettingsReq struct {
+	StreamID string `json:"stream_id"`
+}
+
+func StudioGetStreamIngestSettings(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	ingestSettingsService *services.IngestSettingsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioGetStreamIngestSettingsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// Check if the request has access to the stream's profile
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsRead) {
+			return
+		}
+
+		// Get the settings, and the config they result in
+		settings, err := ingestSettingsService.GetStreamSettings(stream.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		effective, err := ingestSettingsService.ResolveConfig(stream)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the settings
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"settings":  settings,
+				"effective": effective,
+			},
+		})
+
+	}
+}
+
+type StudioSetStreamIngestSettingsReq struct {
+	StreamID string                        `json:"stream_id"`
+	Settings services.IngestSettingsValues `json:"settings"`
+}
+
+func StudioSetStreamIngestSettings(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	ingestSettingsService *services.IngestSettingsService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioSetStreamIngestSettingsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// Check if the request may change the stream
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsWrite) {
+			return
+		}
+
+		// Replace the settings
+		before, err := ingestSettingsService.GetStreamSettings(stream.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ingestSettingsService.SetStreamSettings(stream.ID, &req.Settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_StreamIngestSettingsSet,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+			Before:           before,
+			After:            &req.Settings,
+		})
+
+		// Get the config the settings result in
+		effective, err := ingestSettingsService.ResolveConfig(stream)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Respond with the settings
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"settings":  &req.Settings,
+				"effective": effective,
+			},
+		})
+
+	}
+}
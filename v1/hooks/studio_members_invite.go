@@ -0,0 +1,218 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioInviteMemberReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+}
+
+func StudioInviteMember(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	invitesService *services.InvitesService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioInviteMemberReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_MembersManage) {
+			return
+		}
+
+		// New members are producers unless another role is given
+		if len(req.Role) == 0 {
+			req.Role = models.MemberRole_Producer
+		}
+		if !models.IsMemberRole(req.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid member role"})
+			return
+		}
+
+		// Members can only invite others with a lesser role than their own
+		role, err := studioRole(c, membershipService, creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !models.MemberRoleOutranks(role, req.Role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you can't give a member that role"})
+			return
+		}
+
+		// Create and send the invite
+		invite, err := invitesService.CreateInvite(creator, studioActorAccountID(c), req.Email, req.Role)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_MemberInvite,
+			TargetType:       "invite",
+			TargetID:         auditTargetID(invite.ID),
+			After:            serializeInvite(invite),
+		})
+
+		// Return the invite
+		c.JSON(http.StatusOK, gin.H{
+			"data": serializeInvite(invite),
+		})
+
+	}
+}
+
+type StudioListInvitesReq struct {
+	CreatorID uint64 `json:"creator_id"`
+}
+
+func StudioListInvites(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	invitesService *services.InvitesService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioListInvitesReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_MembersManage) {
+			return
+		}
+
+		// Get the pending invites
+		invites, err := invitesService.GetPendingInvites(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the invites
+		invitesSer := make([]map[string]interface{}, len(invites))
+		for i := range invites {
+			invitesSer[i] = serializeInvite(invites[i])
+		}
+
+		// Respond with the invites
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"invites": invitesSer,
+			},
+		})
+
+	}
+}
+
+type StudioRevokeInviteReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	InviteID  uint64 `json:"invite_id"`
+}
+
+func StudioRevokeInvite(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	invitesService *services.InvitesService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioRevokeInviteReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_MembersManage) {
+			return
+		}
+
+		// Revoke the invite
+		if err := invitesService.RevokeInvite(creator.ID, req.InviteID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_MemberInviteRevoke,
+			TargetType:       "invite",
+			TargetID:         auditTargetID(req.InviteID),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+func serializeInvite(invite *models.CreatorProfileInvite) map[string]interface{} {
+	inviteSer := map[string]interface{}{
+		"id":           invite.ID,
+		"email":        invite.Email,
+		"role":         invite.Role,
+		"expires_date": invite.ExpiresDate.Unix(),
+		"created_date": invite.CreatedDate.Unix(),
+	}
+	if invite.InvitedByAccount != nil {
+		inviteSer["invited_by"] = map[string]interface{}{
+			"id":    invite.InvitedByAccount.ID,
+			"email": invite.InvitedByAccount.Email,
+		}
+	}
+	return inviteSer
+}
@@ -3,8 +3,8 @@ package hooks
 import (
 	"net/http"
 
+	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
-	"github.com/connerdouglass/livestream-api/v1/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -36,17 +36,8 @@ func StudioListMembers(
 			return
 		}
 
-		// Get the account from the context
-		account := utils.CtxGetAccount(c)
-
-		// Check if the account has access
-		access, err := membershipService.IsMember(creator.ID, account.ID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if !access {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_MembersManage) {
 			return
 		}
 
@@ -61,7 +52,9 @@ func StudioListMembers(
 		membersSer := make([]map[string]interface{}, len(members))
 		for i, m := range members {
 			membersSer[i] = map[string]interface{}{
-				"id": m.ID,
+				"id":           m.ID,
+				"role":         m.Role,
+				"created_date": m.CreatedDate.Unix(),
 				"account": map[string]interface{}{
 					"id":    m.Account.ID,
 					"email": m.Account.Email,
@@ -69,9 +62,11 @@ func StudioListMembers(
 			}
 		}
 
-		// Return an empty response
+		// Respond with the members
 		c.JSON(http.StatusOK, gin.H{
-			"data": gin.H{},
+			"data": gin.H{
+				"members": membersSer,
+			},
 		})
 
 	}
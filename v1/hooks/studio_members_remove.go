@@ -0,0 +1,116 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioRemoveMemberReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	AccountID uint64 `json:"account_id"`
+}
+
+func StudioRemoveMember(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioRemoveMemberReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Any member may leave the profile. Removing someone else requires managing members, and a
+		// role above theirs
+		account := utils.CtxGetAccount(c)
+		leaving := utils.CtxGetApiKey(c) == nil && account != nil && account.ID == req.AccountID
+		if !leaving {
+			if !authorizeStudio(c, membershipService, creator.ID, models.Permission_MembersManage) {
+				return
+			}
+			if !authorizeMemberChange(c, membershipService, creator.ID, req.AccountID, "") {
+				return
+			}
+		}
+
+		// Remove the member
+		member, err := membershipService.GetMember(creator.ID, req.AccountID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := membershipService.RemoveMember(creator.ID, req.AccountID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_MemberRemove,
+			TargetType:       "account",
+			TargetID:         auditTargetID(req.AccountID),
+			Before:           serializeMemberForAudit(member),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
+
+// authorizeMemberChange checks if the request may change another member of the creator profile, which
+// requires a role above both the member's current role and the role they would be given. If not, an
+// error response is sent and false is returned
+func authorizeMemberChange(
+	c *gin.Context,
+	membershipService *services.MembershipService,
+	creatorID uint64,
+	accountID uint64,
+	newRole string,
+) bool {
+
+	// Get the member being changed
+	member, err := membershipService.GetMember(creatorID, accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if member == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "member not found"})
+		return false
+	}
+
+	// Compare the roles
+	role, err := studioRole(c, membershipService, creatorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !models.MemberRoleOutranks(role, member.Role) ||
+		(len(newRole) > 0 && !models.MemberRoleOutranks(role, newRole)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you can't change a member with that role"})
+		return false
+	}
+	return true
+
+}
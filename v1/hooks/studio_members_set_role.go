@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioSetMemberRoleReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	AccountID uint64 `json:"account_id"`
+	Role      string `json:"role"`
+}
+
+func StudioSetMemberRole(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioSetMemberRoleReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !models.IsMemberRole(req.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid member role"})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request may change the member to the role
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_MembersManage) {
+			return
+		}
+		if !authorizeMemberChange(c, membershipService, creator.ID, req.AccountID, req.Role) {
+			return
+		}
+
+		// Change the role
+		member, err := membershipService.GetMember(creator.ID, req.AccountID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		before := serializeMemberForAudit(member)
+		if err := membershipService.SetRole(creator.ID, req.AccountID, req.Role); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		member.Role = req.Role
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_MemberSetRole,
+			TargetType:       "account",
+			TargetID:         auditTargetID(req.AccountID),
+			Before:           before,
+			After:            serializeMemberForAudit(member),
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
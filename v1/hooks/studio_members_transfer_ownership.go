@@ -0,0 +1,70 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioTransferOwnershipReq struct {
+	CreatorID uint64 `json:"creator_id"`
+	AccountID uint64 `json:"account_id"`
+}
+
+func StudioTransferOwnership(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioTransferOwnershipReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Only the owner can give away ownership
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_OwnershipTransfer) {
+			return
+		}
+
+		// Transfer ownership to the other member
+		if err := membershipService.TransferOwnership(creator.ID, req.AccountID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_MemberTransferOwnership,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			Before: map[string]interface{}{
+				"owner_account_id": studioActorAccountID(c),
+			},
+			After: map[string]interface{}{
+				"owner_account_id": req.AccountID,
+			},
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
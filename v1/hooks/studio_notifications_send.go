@@ -0,0 +1,97 @@
+package hooks
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioSendNotificationReq struct {
+	CreatorID uint64  `json:"creator_id"`
+	Title     string  `json:"title"`
+	Body      string  `json:"body"`
+	Link      *string `json:"link"`
+}
+
+func StudioSendNotification(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	notifier services.Notifier,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioSendNotificationReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_NotifySend) {
+			return
+		}
+
+		// The body is required, and the title defaults to the creator's name
+		body := strings.TrimSpace(req.Body)
+		if len(body) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "notification body is required"})
+			return
+		}
+		title := strings.TrimSpace(req.Title)
+		if len(title) == 0 {
+			title = creator.Name
+		}
+
+		// Send the notification to all of the subscribers
+		var image *string
+		if len(creator.Image) > 0 {
+			image = &creator.Image
+		}
+		err = notifier.NotifySubscribers(
+			creator.ID,
+			&services.Notification{
+				Title: title,
+				Body:  body,
+				Link:  req.Link,
+				Image: image,
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_NotificationSend,
+			TargetType:       "creator",
+			TargetID:         auditTargetID(creator.ID),
+			Details: map[string]interface{}{
+				"title": title,
+				"body":  body,
+				"link":  req.Link,
+			},
+		})
+
+		// Return an empty response
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{},
+		})
+
+	}
+}
@@ -0,0 +1,86 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioListLoginAttemptsReq struct {
+	CreatorID uint64 `json:"creator_id"`
+}
+
+func StudioListLoginAttempts(
+	creatorsService *services.CreatorsService,
+	membershipService *services.MembershipService,
+	loginThrottleService *services.LoginThrottleService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioListLoginAttemptsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the creator profile with the identifier
+		creator, err := creatorsService.GetCreatorByID(req.CreatorID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if creator == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "creator not found"})
+			return
+		}
+
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_SecurityRead) {
+			return
+		}
+
+		// Get all of the members of the profile
+		members, err := membershipService.GetMembers(creator.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		accountIDs := make([]uint64, len(members))
+		for i, m := range members {
+			accountIDs[i] = m.AccountID
+		}
+
+		// Get the recent failed attempts on the members' accounts
+		attempts, err := loginThrottleService.GetFailedAttemptsForAccounts(accountIDs, 100)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the attempts
+		attemptsSer := make([]map[string]interface{}, len(attempts))
+		for i, attempt := range attempts {
+			attemptsSer[i] = map[string]interface{}{
+				"id":           attempt.ID,
+				"account_id":   utils.FlattenNullInt64(attempt.AccountID),
+				"email":        attempt.Email,
+				"ip_address":   attempt.IPAddress,
+				"user_agent":   attempt.UserAgent,
+				"result":       attempt.Result,
+				"created_date": attempt.CreatedDate.Unix(),
+			}
+		}
+
+		// Respond with the attempts
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"attempts": attemptsSer,
+			},
+		})
+
+	}
+}
@@ -0,0 +1,88 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioGetStreamAnalyticsReq struct {
+	StreamID string `json:"stream_id"`
+}
+
+func StudioGetStreamAnalytics(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	viewerAnalyticsService *services.ViewerAnalyticsService,
+	viewerCountsService *services.ViewerCountsService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioGetStreamAnalyticsReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// Check if the request may see the stream's analytics
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_AnalyticsRead) {
+			return
+		}
+
+		// Get the analytics
+		analytics, final, err := viewerAnalyticsService.GetStreamAnalytics(stream)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the viewer counts over time
+		samples, err := viewerCountsService.GetViewerSamples(stream.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		samplesSer := make([]map[string]interface{}, len(samples))
+		for i, sample := range samples {
+			samplesSer[i] = map[string]interface{}{
+				"date":    sample.CreatedDate.Unix(),
+				"viewers": sample.Viewers,
+			}
+		}
+
+		// Respond with the analytics
+		analyticsSer := serializeStreamAnalytics(analytics)
+		analyticsSer["stream_id"] = stream.Identifier
+		analyticsSer["final"] = final
+		analyticsSer["viewer_samples"] = samplesSer
+		c.JSON(http.StatusOK, gin.H{
+			"data": analyticsSer,
+		})
+
+	}
+}
+
+// serializeStreamAnalytics serializes the analytics of a stream
+func serializeStreamAnalytics(analytics *models.StreamAnalytics) map[string]interface{} {
+	return map[string]interface{}{
+		"peak_viewers":    analytics.PeakViewers,
+		"average_viewers": analytics.AverageViewers,
+		"unique_viewers":  analytics.UniqueViewers,
+		"watch_minutes":   analytics.WatchMinutes,
+		"computed_date":   analytics.ComputedDate.Unix(),
+	}
+}
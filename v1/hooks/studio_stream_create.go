@@ -4,8 +4,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
-	"github.com/connerdouglass/livestream-api/v1/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,6 +21,8 @@ func StudioCreateStream(
 	creatorsService *services.CreatorsService,
 	streamsService *services.StreamsService,
 	membershipService *services.MembershipService,
+	ingestNodesService *services.IngestNodesService,
+	auditService *services.AuditService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -42,17 +44,8 @@ func StudioCreateStream(
 			return
 		}
 
-		// Get the account from the context
-		account := utils.CtxGetAccount(c)
-
-		// Check if the account has access
-		access, err := membershipService.IsMember(creator.ID, account.ID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if !access {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_StreamsWrite) {
 			return
 		}
 
@@ -65,10 +58,24 @@ func StudioCreateStream(
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: creator.ID,
+			Action:           models.AuditAction_StreamCreate,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+			After:            serializeStreamForStudio(stream),
+		})
+
+		// Serialize the stream, with the URL its encoder should connect to
+		streamsSer, err := serializeStreamsWithIngestUrl([]*models.Stream{stream}, ingestNodesService)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
 		// Return an empty response
 		c.JSON(http.StatusOK, gin.H{
-			"data": serializeStreamForStudio(stream),
+			"data": streamsSer[0],
 		})
 
 	}
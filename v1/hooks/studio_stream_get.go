@@ -5,7 +5,7 @@ import (
 
 	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
-	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/connerdouglass/livestream-api/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,6 +17,7 @@ func StudioGetStream(
 	creatorsService *services.CreatorsService,
 	streamsService *services.StreamsService,
 	membershipService *services.MembershipService,
+	ingestNodesService *services.IngestNodesService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -27,9 +28,6 @@ func StudioGetStream(
 			return
 		}
 
-		// Get the account sending the request
-		account := utils.CtxGetAccount(c)
-
 		// Get the stream with the identifier
 		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
 		if err != nil {
@@ -41,37 +39,73 @@ func StudioGetStream(
 			return
 		}
 
-		// Check if the account owns the stream
-		isMember, err := membershipService.IsMember(stream.CreatorProfileID, account.ID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// Check if the request has access to the stream's profile
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsRead) {
 			return
 		}
-		if !isMember {
-			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+
+		// Serialize the stream, with the URL its encoder should connect to
+		streamsSer, err := serializeStreamsWithIngestUrl([]*models.Stream{stream}, ingestNodesService)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Return an empty response
+		// Return the stream
 		c.JSON(http.StatusOK, gin.H{
-			"data": serializeStreamForStudio(stream),
+			"data": streamsSer[0],
 		})
 
 	}
 }
 
+// serializeStreamForStudio serializes a stream for the studio. The stream key is never included, and
+// has to be revealed on its own
 func serializeStreamForStudio(stream *models.Stream) map[string]interface{} {
 	if stream == nil {
 		return nil
 	}
 	return map[string]interface{}{
-		"id":                   stream.ID,
-		"identifier":           stream.Identifier,
-		"title":                stream.Title,
-		"stream_key":           stream.StreamKey,
-		"status":               stream.Status,
-		"streaming":            stream.Streaming,
-		"scheduled_start_date": stream.ScheduledStartDate.Unix(),
-		"current_viewers":      stream.CurrentViewers,
+		"id":                       stream.ID,
+		"identifier":               stream.Identifier,
+		"title":                    stream.Title,
+		"status":                   stream.Status,
+		"streaming":                stream.Streaming,
+		"ingest_policy":            stream.IngestPolicy,
+		"disconnect_grace_seconds": stream.DisconnectGraceSeconds,
+		"disconnected_date":        utils.FlattenNullTimeSec(stream.DisconnectedDate),
+		"visibility":               stream.Visibility,
+		"has_password":             len(stream.PasswordHash) > 0,
+		"scheduled_start_date":     stream.ScheduledStartDate.Unix(),
+		"current_viewers":          stream.CurrentViewers,
+		"thumbnail_url":            utils.FlattenNullString(stream.ThumbnailUrl),
+		"started_date":             utils.FlattenNullTimeSec(stream.StartedDate),
+		"ended_date":               utils.FlattenNullTimeSec(stream.EndedDate),
+	}
+}
+
+// serializeStreamsWithIngestUrl serializes streams for the studio, along with the URL each one's encoder
+// should connect to
+func serializeStreamsWithIngestUrl(
+	streams []*models.Stream,
+	ingestNodesService *services.IngestNodesService,
+) ([]map[string]interface{}, error) {
+
+	// Pick the ingest URL for each stream
+	urls, err := ingestNodesService.GetIngestUrls(streams)
+	if err != nil {
+		return nil, err
 	}
+
+	// Serialize the streams
+	streamsSer := make([]map[string]interface{}, len(streams))
+	for i, stream := range streams {
+		streamsSer[i] = serializeStreamForStudio(stream)
+		streamsSer[i]["ingest_url"] = nil
+		if len(urls[i]) > 0 {
+			streamsSer[i]["ingest_url"] = urls[i]
+		}
+	}
+	return streamsSer, nil
+
 }
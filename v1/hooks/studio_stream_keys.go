@@ -0,0 +1,115 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioRevealStreamKeyReq struct {
+	StreamID string `json:"stream_id"`
+}
+
+func StudioRevealStreamKey(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioRevealStreamKeyReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// Check if the request may see the stream's key
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsKeys) {
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_StreamKeyReveal,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+		})
+
+		// Return the key
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"stream_key": stream.StreamKey,
+			},
+		})
+
+	}
+}
+
+type StudioRotateStreamKeyReq struct {
+	StreamID string `json:"stream_id"`
+}
+
+func StudioRotateStreamKey(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+	auditService *services.AuditService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioRotateStreamKeyReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// Check if the request may manage the stream's key
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsKeys) {
+			return
+		}
+
+		// Replace the key
+		if err := streamsService.RotateStreamKey(stream); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_StreamKeyRotate,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+		})
+
+		// Return the new key
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"stream_key": stream.StreamKey,
+			},
+		})
+
+	}
+}
@@ -1,9 +1,9 @@
 package hooks
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
@@ -20,6 +20,8 @@ func StudioSetStreamStatus(
 	streamsService *services.StreamsService,
 	membershipService *services.MembershipService,
 	notifier services.Notifier,
+	auditService *services.AuditService,
+	publicUrl string,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -30,9 +32,6 @@ func StudioSetStreamStatus(
 			return
 		}
 
-		// Get the account sending the request
-		account := utils.CtxGetAccount(c)
-
 		// Get the stream with the identifier
 		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
 		if err != nil {
@@ -44,40 +43,30 @@ func StudioSetStreamStatus(
 			return
 		}
 
-		// Check if the account owns the stream
-		isMember, err := membershipService.IsMember(stream.CreatorProfileID, account.ID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if !isMember {
-			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		// Check if the request has access to the stream's profile
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsWrite) {
 			return
 		}
 
 		// Update the status of the stream
-		if err := streamsService.UpdateStatus(stream, req.Status); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		before := serializeStreamForStudio(stream)
+		trigger := streamStatusTrigger(c, models.StreamStatusSource_Studio)
+		if err := streamsService.UpdateStatus(stream, req.Status, trigger); err != nil {
+			c.JSON(streamStatusErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_StreamSetStatus,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+			Before:           before,
+			After:            serializeStreamForStudio(stream),
+		})
 
-		// If we're going live
+		// If we're going live, let the subscribers know
 		if req.Status == models.StreamStatus_Live {
-			link := os.Getenv("TEMP_NOTIFY_LINK")
-			var image *string
-			if len(stream.CreatorProfile.Image) > 0 {
-				image = &stream.CreatorProfile.Image
-			}
-			err := notifier.NotifySubscribers(
-				stream.CreatorProfileID,
-				&services.Notification{
-					Title: stream.CreatorProfile.Name,
-					Body:  fmt.Sprintf("%s just went live!", stream.CreatorProfile.Name),
-					Link:  &link,
-					Image: image,
-				},
-			)
-			if err != nil {
+			if err := services.NotifyStreamLive(notifier, stream, publicUrl); err != nil {
 				fmt.Println("Error sending notifications: ", err)
 			}
 		}
@@ -89,3 +78,35 @@ func StudioSetStreamStatus(
 
 	}
 }
+
+// streamStatusTrigger describes the request changing a stream's status, for the stream's history
+func streamStatusTrigger(c *gin.Context, source string) *services.StreamStatusTrigger {
+	trigger := services.StreamStatusTrigger{
+		Source: source,
+	}
+	if account := utils.CtxGetAccount(c); account != nil {
+		trigger.AccountID = account.ID
+	}
+	if apiKey := utils.CtxGetApiKey(c); apiKey != nil {
+		trigger.ApiKeyID = apiKey.ID
+	}
+	if node := utils.CtxGetIngestNode(c); node != nil {
+		trigger.IngestNodeID = node.ID
+	}
+	return &trigger
+}
+
+// streamStatusErrorStatus gets the response status for an error changing a stream's status. Invalid
+// transitions conflict with the stream's current status, invalid statuses are bad requests, and anything
+// else is unexpected
+func streamStatusErrorStatus(err error) int {
+	var transitionErr *services.StreamTransitionError
+	if errors.As(err, &transitionErr) || errors.Is(err, services.ErrStreamFinished) {
+		return http.StatusConflict
+	}
+	var updateErr *services.StreamUpdateError
+	if errors.As(err, &updateErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,34 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+)
+
+func TestStreamStatusErrorStatus(t *testing.T) {
+	transitionErr := &services.StreamTransitionError{
+		From: models.StreamStatus_Ended,
+		To:   models.StreamStatus_Live,
+	}
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"transition", transitionErr, http.StatusConflict},
+		{"wrapped transition", fmt.Errorf("updating stream: %w", transitionErr), http.StatusConflict},
+		{"finished", services.ErrStreamFinished, http.StatusConflict},
+		{"invalid status", &services.StreamUpdateError{Message: "unsupported stream status"}, http.StatusBadRequest},
+		{"database error", errors.New("database is locked"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if status := streamStatusErrorStatus(test.err); status != test.expected {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.expected, status)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package hooks
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/connerdouglass/livestream-api/services"
+	"github.com/connerdouglass/livestream-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type StudioGetStreamStatusHistoryReq struct {
+	StreamID string `json:"stream_id"`
+}
+
+func StudioGetStreamStatusHistory(
+	streamsService *services.StreamsService,
+	membershipService *services.MembershipService,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the request body
+		var req StudioGetStreamStatusHistoryReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Get the stream with the identifier
+		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if stream == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stream not found"})
+			return
+		}
+
+		// Check if the request has access to the stream's profile
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsRead) {
+			return
+		}
+
+		// Get the status changes on the stream
+		changes, err := streamsService.GetStatusChanges(stream.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Serialize all of the changes
+		changesSer := make([]map[string]interface{}, len(changes))
+		for i, change := range changes {
+			changesSer[i] = map[string]interface{}{
+				"id":               change.ID,
+				"from_status":      change.FromStatus,
+				"to_status":        change.ToStatus,
+				"source":           change.Source,
+				"actor_account_id": utils.FlattenNullInt64(change.ActorAccountID),
+				"api_key_id":       utils.FlattenNullInt64(change.ApiKeyID),
+				"ingest_node_id":   utils.FlattenNullInt64(change.IngestNodeID),
+				"created_date":     change.CreatedDate.Unix(),
+			}
+		}
+
+		// Respond with the changes
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"changes": changesSer,
+			},
+		})
+
+	}
+}
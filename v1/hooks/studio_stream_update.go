@@ -1,10 +1,11 @@
 package hooks
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
-	"github.com/connerdouglass/livestream-api/v1/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,6 +18,8 @@ func StudioUpdateStream(
 	creatorsService *services.CreatorsService,
 	streamsService *services.StreamsService,
 	membershipService *services.MembershipService,
+	ingestNodesService *services.IngestNodesService,
+	auditService *services.AuditService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -27,9 +30,6 @@ func StudioUpdateStream(
 			return
 		}
 
-		// Get the account sending the request
-		account := utils.CtxGetAccount(c)
-
 		// Get the stream with the identifier
 		stream, err := streamsService.GetStreamByIdentifier(req.StreamID)
 		if err != nil {
@@ -41,27 +41,47 @@ func StudioUpdateStream(
 			return
 		}
 
-		// Check if the account owns the stream
-		isMember, err := membershipService.IsMember(stream.CreatorProfileID, account.ID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if !isMember {
-			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+		// Check if the request has access to the stream's profile
+		if !authorizeStudio(c, membershipService, stream.CreatorProfileID, models.Permission_StreamsWrite) {
 			return
 		}
 
 		// Update the stream
+		before := serializeStreamForStudio(stream)
 		if err := streamsService.UpdateStream(stream, &req.Updates); err != nil {
+			c.JSON(streamUpdateErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		recordAudit(c, auditService, &services.AuditEntry{
+			CreatorProfileID: stream.CreatorProfileID,
+			Action:           models.AuditAction_StreamUpdate,
+			TargetType:       "stream",
+			TargetID:         auditTargetID(stream.ID),
+			Before:           before,
+			After:            serializeStreamForStudio(stream),
+		})
+
+		// Serialize the stream, with the URL its encoder should connect to
+		streamsSer, err := serializeStreamsWithIngestUrl([]*models.Stream{stream}, ingestNodesService)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		// Return an empty response
 		c.JSON(http.StatusOK, gin.H{
-			"data": serializeStreamForStudio(stream),
+			"data": streamsSer[0],
 		})
 
 	}
 }
+
+// streamUpdateErrorStatus gets the HTTP status for an error updating a stream. Invalid updates are the
+// client's fault, and anything else is the server's
+func streamUpdateErrorStatus(err error) int {
+	var updateErr *services.StreamUpdateError
+	if errors.As(err, &updateErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,25 @@
+package hooks
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/connerdouglass/livestream-api/services"
+)
+
+func TestStreamUpdateErrorStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"invalid update", &services.StreamUpdateError{Message: "unsupported ingest policy"}, http.StatusBadRequest},
+		{"database error", errors.New("database is locked"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if status := streamUpdateErrorStatus(test.err); status != test.expected {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.expected, status)
+		}
+	}
+}
@@ -3,8 +3,8 @@ package hooks
 import (
 	"net/http"
 
+	"github.com/connerdouglass/livestream-api/models"
 	"github.com/connerdouglass/livestream-api/services"
-	"github.com/connerdouglass/livestream-api/v1/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,6 +16,7 @@ func StudioListStreams(
 	creatorsService *services.CreatorsService,
 	streamsService *services.StreamsService,
 	membershipService *services.MembershipService,
+	ingestNodesService *services.IngestNodesService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -37,17 +38,8 @@ func StudioListStreams(
 			return
 		}
 
-		// Get the account from the context
-		account := utils.CtxGetAccount(c)
-
-		// Check if the account has access
-		access, err := membershipService.IsMember(creator.ID, account.ID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if !access {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		// Check if the request has access to the profile
+		if !authorizeStudio(c, membershipService, creator.ID, models.Permission_StreamsRead) {
 			return
 		}
 
@@ -58,10 +50,11 @@ func StudioListStreams(
 			return
 		}
 
-		// Serialize all of the streams
-		streamsSer := make([]map[string]interface{}, len(streams))
-		for i := range streams {
-			streamsSer[i] = serializeStreamForStudio(streams[i])
+		// Serialize all of the streams, with the URL each one's encoder should connect to
+		streamsSer, err := serializeStreamsWithIngestUrl(streams, ingestNodesService)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
 		// Respond with the streams
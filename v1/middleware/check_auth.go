@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/connerdouglass/livestream-api/services"
@@ -8,12 +9,18 @@ import (
 )
 
 // CheckAuth creates a middleware function that parses auth token header and adds the account to the context
-func CheckAuth(authTokensService *services.AuthTokensService) gin.HandlerFunc {
+func CheckAuth(
+	authTokensService *services.AuthTokensService,
+	sessionsService *services.SessionsService,
+	apiKeysService *services.ApiKeysService,
+) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// Initially, store nil in the context
 		c.Set("bearer_token", nil)
 		c.Set("account", nil)
+		c.Set("session", nil)
+		c.Set("api_key", nil)
 
 		// Get the authorization header, trimmed
 		authHeader := strings.TrimSpace(c.GetHeader("Authorization"))
@@ -34,15 +41,31 @@ func CheckAuth(authTokensService *services.AuthTokensService) gin.HandlerFunc {
 		token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
 		c.Set("bearer_token", token)
 
-		// Find the account of the token
-		account, err := authTokensService.GetAccountForToken(token)
+		// If it's an API key, find the key instead of an account
+		if strings.HasPrefix(token, services.ApiKeyPrefix) {
+			apiKey, err := apiKeysService.Authenticate(token)
+			if err == nil && apiKey != nil {
+				c.Set("api_key", apiKey)
+			}
+			c.Next()
+			return
+		}
+
+		// Find the account and session of the token
+		account, session, err := authTokensService.GetAccountForToken(token)
 		if err != nil {
 			// fmt.Println("auth token error: ", err)
 			c.Next()
 			return
 		}
-		if account != nil {
+		if account != nil && session != nil {
 			c.Set("account", account)
+			c.Set("session", session)
+
+			// Record that the session is still in use
+			if err := sessionsService.TouchSession(session); err != nil {
+				fmt.Println("Error updating session last seen date: ", err.Error())
+			}
 		}
 
 		// Move to the next
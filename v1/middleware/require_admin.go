@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin creates a middleware function to require a platform admin on a hook. Sessions started by
+// impersonating an account never count as admin, even if the impersonated account is one
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the account and session from the context
+		account := utils.CtxGetAccount(c)
+		session := utils.CtxGetSession(c)
+		if account == nil || session == nil || !account.IsAdmin || session.IsImpersonation() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Admin access required",
+			})
+			return
+		}
+
+		// Move to the next successfully
+		c.Next()
+
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireOwnSession creates a middleware function that blocks sessions started by impersonating an
+// account. Admins can act as the account, but not change how it's secured, or make changes that can't
+// be undone or that outlast the impersonation
+func RequireOwnSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the session from the context
+		session := utils.CtxGetSession(c)
+		if session == nil || session.IsImpersonation() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Not allowed while impersonating an account",
+			})
+			return
+		}
+
+		// Move to the next successfully
+		c.Next()
+
+	}
+}
@@ -1,28 +1,92 @@
 package middleware
 
 import (
+	"bytes"
+	"io"
 	"net/http"
 
 	"github.com/connerdouglass/livestream-api/services"
 	"github.com/gin-gonic/gin"
 )
 
-// RequireRtmpAuth creates a middleware function to require RTMP auth on a hook
+const (
+	// IngestNodeHeader is the header an ingest node sends its identifier in
+	IngestNodeHeader = "X-Ingest-Node"
+
+	// IngestTimestampHeader is the header an ingest node sends the Unix time of the request in
+	IngestTimestampHeader = "X-Ingest-Timestamp"
+
+	// IngestSignatureHeader is the header an ingest node sends the hex-encoded signature of the request in
+	IngestSignatureHeader = "X-Ingest-Signature"
+
+	// IngestMaxBodySize is the largest request body an ingest node may send, in bytes
+	IngestMaxBodySize = 1 << 20
+)
+
+// RequireRtmpAuth creates a middleware function to require RTMP auth on a hook. Requests are either
+// signed by a registered ingest node, or carry the legacy shared passcode as a bearer token
 func RequireRtmpAuth(
 	rtmpAuthService *services.RtmpAuthService,
 ) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		// Check for the bearer token
-		token := c.GetString("bearer_token")
+		// Initially, store nil in the context
+		c.Set("ingest_node", nil)
+
+		// If the request isn't from a registered node, fall back to the passcode
+		nodeIdentifier := c.GetHeader(IngestNodeHeader)
+		if len(nodeIdentifier) == 0 {
+			if !rtmpAuthService.CheckPasscode(c.GetString("bearer_token")) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"error": "Authentication failed",
+				})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// Find the node, before reading the body, so unauthenticated callers can't make the server
+		// buffer anything
+		req := services.IngestRequest{
+			NodeIdentifier: nodeIdentifier,
+			Timestamp:      c.GetHeader(IngestTimestampHeader),
+			Signature:      c.GetHeader(IngestSignatureHeader),
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			IPAddress:      c.ClientIP(),
+		}
+		node, err := rtmpAuthService.FindRequestingNode(&req)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Authentication failed",
+			})
+			return
+		}
+
+		// Read the body, which is covered by the signature, and put it back for the hook
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, IngestMaxBodySize+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(body) > IngestMaxBodySize {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Request body is too large",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
 
-		// Validate the token
-		if !rtmpAuthService.CheckPasscode(token) {
+		// Validate the signature
+		req.Body = body
+		if err := rtmpAuthService.VerifyNodeSignature(node, &req); err != nil {
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
 				"error": "Authentication failed",
 			})
 			return
 		}
+		c.Set("ingest_node", node)
 
 		// Move to the next successfully
 		c.Next()
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/connerdouglass/livestream-api/v1/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireStudioAuth creates a middleware function to require either account authentication or an API
+// key on a hook. Hooks behind this must check the API key's profile and scopes themselves
+func RequireStudioAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get the account and API key from the context
+		account := utils.CtxGetAccount(c)
+		apiKey := utils.CtxGetApiKey(c)
+		if account == nil && apiKey == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Authentication failed",
+			})
+			return
+		}
+
+		// Move to the next successfully
+		c.Next()
+
+	}
+}
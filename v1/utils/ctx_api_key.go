@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CtxGetApiKey gets the API key (or nil) from a Gin context
+func CtxGetApiKey(c *gin.Context) *models.ApiKey {
+
+	// Get the API key from the context
+	value, exists := c.Get("api_key")
+	if !exists || value == nil {
+		return nil
+	}
+
+	// Perform a typecheck on the API key
+	apiKey, ok := value.(*models.ApiKey)
+	if !ok || apiKey == nil {
+		return nil
+	}
+
+	// Return the API key
+	return apiKey
+
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CtxGetIngestNode gets the ingest node (or nil) from a Gin context
+func CtxGetIngestNode(c *gin.Context) *models.IngestNode {
+
+	// Get the ingest node from the context
+	value, exists := c.Get("ingest_node")
+	if !exists || value == nil {
+		return nil
+	}
+
+	// Perform a typecheck on the ingest node
+	node, ok := value.(*models.IngestNode)
+	if !ok || node == nil {
+		return nil
+	}
+
+	// Return the ingest node
+	return node
+
+}
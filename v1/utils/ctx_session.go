@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"github.com/connerdouglass/livestream-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CtxGetSession gets the session (or nil) from a Gin context
+func CtxGetSession(c *gin.Context) *models.Session {
+
+	// Get the session from the context
+	value, exists := c.Get("session")
+	if !exists || value == nil {
+		return nil
+	}
+
+	// Perform a typecheck on the session
+	session, ok := value.(*models.Session)
+	if !ok || session == nil {
+		return nil
+	}
+
+	// Return the session
+	return session
+
+}